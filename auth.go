@@ -0,0 +1,247 @@
+package qbt
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jfxdev/go-qbt/request"
+)
+
+// ErrCredentialsRejected is returned by an Authenticator when the server
+// refuses the configured credentials. The client counts these towards
+// Config.MaxLoginRetries.
+var ErrCredentialsRejected = errors.New("credentials rejected")
+
+// Authenticator establishes the client's session with qBittorrent.
+type Authenticator interface {
+	// Login authenticates the session. It runs after the accessibility probe
+	// whenever the session is not known to be valid.
+	Login(ctx context.Context, session *AuthSession) error
+	// Logout ends the session when the client is closed.
+	Logout(ctx context.Context, session *AuthSession) error
+	// Headers returns headers sent with every request, including the probe.
+	Headers() map[string]string
+}
+
+// AuthSession gives an Authenticator access to the client's connection.
+type AuthSession struct {
+	BaseURL  string
+	Username string
+	Password string
+	// ProbeStatus is the status code of the /api/v2/app/version probe that
+	// preceded Login. The probe carries Headers() but no session cookie.
+	ProbeStatus int
+
+	bypassed *atomic.Bool // The client's bypass state, kept per client
+	do       func(ctx context.Context, method, path string, body io.Reader, headers map[string]string) (*http.Response, error)
+}
+
+// Do sends a request to path, relative to BaseURL, through the client's
+// transport and cookie jar. Cookies set by the response are kept.
+func (s *AuthSession) Do(ctx context.Context, method, path string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	return s.do(ctx, method, path, body, headers)
+}
+
+// verifyProbe accepts the session when the probe succeeded and maps 401/403
+// to ErrCredentialsRejected. It backs the authenticators that carry their
+// credentials on every request instead of logging in.
+func verifyProbe(session *AuthSession) error {
+	switch session.ProbeStatus {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: status code %d", ErrCredentialsRejected, session.ProbeStatus)
+	default:
+		return classifyHTTPStatusCode(session.ProbeStatus, "")
+	}
+}
+
+// setBypassed records whether the server bypasses authentication for the
+// client behind the session.
+func (s *AuthSession) setBypassed(bypassed bool) {
+	if s.bypassed != nil {
+		s.bypassed.Store(bypassed)
+	}
+}
+
+func (s *AuthSession) isBypassed() bool {
+	return s.bypassed != nil && s.bypassed.Load()
+}
+
+// CookieAuth logs in with the configured username and password and keeps the
+// SID cookie. It is the default. When the probe succeeds without a cookie the
+// instance bypasses authentication for this client and no login is sent. The
+// detected bypass is stored on the client, so one CookieAuth can be shared.
+type CookieAuth struct {
+	DisableBypassDetection bool // Always POST credentials, even when the probe succeeds
+}
+
+func (a *CookieAuth) Login(ctx context.Context, session *AuthSession) error {
+	if !a.DisableBypassDetection && session.ProbeStatus == http.StatusOK {
+		session.setBypassed(true)
+		return nil
+	}
+	session.setBypassed(false)
+
+	data := url.Values{
+		"username": {session.Username},
+		"password": {session.Password},
+	}
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+
+	resp, err := session.Do(ctx, http.MethodPost, "/api/v2/auth/login", strings.NewReader(data.Encode()), headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Read response body to check for "Fails." message
+	body, _ := io.ReadAll(resp.Body)
+	bodyStr := strings.TrimSpace(string(body))
+
+	if resp.StatusCode != http.StatusOK {
+		return classifyHTTPStatusCode(resp.StatusCode, bodyStr)
+	}
+
+	// qBittorrent answers 200 "Fails." for invalid credentials
+	if strings.Contains(bodyStr, "Fails.") {
+		return ErrCredentialsRejected
+	}
+	return nil
+}
+
+func (a *CookieAuth) Logout(ctx context.Context, session *AuthSession) error {
+	if session.isBypassed() {
+		return nil
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+
+	resp, err := session.Do(ctx, http.MethodPost, "/api/v2/auth/logout", nil, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("logout failed. Status: %d, Response: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+func (a *CookieAuth) Headers() map[string]string { return nil }
+
+// NoAuth never logs in, for instances configured to bypass authentication
+// for localhost or whitelisted subnets.
+type NoAuth struct{}
+
+func (NoAuth) Login(ctx context.Context, session *AuthSession) error  { return verifyProbe(session) }
+func (NoAuth) Logout(ctx context.Context, session *AuthSession) error { return nil }
+func (NoAuth) Headers() map[string]string                             { return nil }
+
+// HeaderAuth sends static headers with every request, e.g. the identity
+// headers a reverse proxy such as Authelia or oauth2-proxy expects.
+type HeaderAuth struct {
+	Values map[string]string
+}
+
+func (a HeaderAuth) Login(ctx context.Context, session *AuthSession) error {
+	return verifyProbe(session)
+}
+func (a HeaderAuth) Logout(ctx context.Context, session *AuthSession) error { return nil }
+
+func (a HeaderAuth) Headers() map[string]string {
+	return a.Values
+}
+
+// BasicAuth sends HTTP Basic credentials with every request, for instances
+// behind a proxy that enforces Basic auth.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuth) Login(ctx context.Context, session *AuthSession) error {
+	return verifyProbe(session)
+}
+func (a BasicAuth) Logout(ctx context.Context, session *AuthSession) error { return nil }
+
+func (a BasicAuth) Headers() map[string]string {
+	credentials := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+	return map[string]string{"Authorization": "Basic " + credentials}
+}
+
+// authenticator returns the configured Authenticator.
+func (qb *Client) authenticator() Authenticator {
+	qb.mu.RLock()
+	defer qb.mu.RUnlock()
+	return qb.auth
+}
+
+// AuthBypassed reports whether the last login found that the server
+// bypasses authentication for this client (CookieAuth only).
+func (qb *Client) AuthBypassed() bool {
+	return qb.authBypassed.Load()
+}
+
+// authHeaders returns the headers the authenticator adds to every request.
+func (qb *Client) authHeaders() map[string]string {
+	return qb.authenticator().Headers()
+}
+
+// newAuthSession builds the session handed to the authenticator.
+func (qb *Client) newAuthSession(probeStatus int) *AuthSession {
+	qb.mu.RLock()
+	config := qb.config
+	qb.mu.RUnlock()
+
+	return &AuthSession{
+		BaseURL:     config.BaseURL,
+		Username:    config.Username,
+		Password:    config.Password,
+		ProbeStatus: probeStatus,
+		bypassed:    &qb.authBypassed,
+		do: func(ctx context.Context, method, path string, body io.Reader, headers map[string]string) (*http.Response, error) {
+			resp, err := qb.doAuthRequest(ctx, method, config.BaseURL+path, body, headers)
+			if err != nil {
+				return nil, err
+			}
+			qb.updateCookieCache(resp.Cookies())
+			return resp, nil
+		},
+	}
+}
+
+// newAuthenticator returns config.Authenticator or the default cookie login.
+func newAuthenticator(config Config) Authenticator {
+	if config.Authenticator != nil {
+		return config.Authenticator
+	}
+	return &CookieAuth{}
+}
+
+// doAuthRequest sends a request with the client's transport, cookie jar and
+// authenticator headers.
+func (qb *Client) doAuthRequest(ctx context.Context, method, endpoint string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	return request.Do(method, endpoint,
+		request.WithBody(body),
+		request.WithHeaders(qb.authHeaders()),
+		request.WithHeaders(headers),
+		request.WithCookieJar(qb.config.jar),
+		request.WithTransport(qb.roundTripper()),
+		request.WithUpdateCookies(),
+		request.WithContext(ctx),
+	)
+}
@@ -0,0 +1,165 @@
+package qbt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newAuthServer fakes an instance that requires either a session cookie or,
+// when allow is set, whatever allow accepts.
+func newAuthServer(t *testing.T, allow func(r *http.Request) bool, logins *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	authorized := func(r *http.Request) bool {
+		if cookie, err := r.Cookie("SID"); err == nil && cookie.Value == "test-session" {
+			return true
+		}
+		return allow != nil && allow(r)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		logins.Add(1)
+		r.ParseForm()
+		if r.PostForm.Get("password") != "adminadmin" {
+			w.Write([]byte("Fails."))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "SID", Value: "test-session", Path: "/"})
+		w.Write([]byte("Ok."))
+	})
+	mux.HandleFunc("/api/v2/app/version", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("v5.0.0"))
+	})
+	mux.HandleFunc("/api/v2/transfer/info", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"dl_info_speed":1}`))
+	})
+
+	return newFakeServer(t, mux)
+}
+
+func TestCookieAuthLogin(t *testing.T) {
+	var logins atomic.Int32
+	server := newAuthServer(t, nil, &logins)
+
+	client := newFakeClient(t, server, Config{MaxRetries: 1})
+	if _, err := client.GetTransferInfo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if logins.Load() != 1 {
+		t.Errorf("Expected one login, got %d", logins.Load())
+	}
+
+	rejected := newFakeClient(t, server, Config{Username: "admin", Password: "wrong"})
+	err := rejected.Login(context.Background())
+	if GetErrorCode(err) != ErrorCodeAuthFailure || IsPermanentError(err) {
+		t.Errorf("Expected a retryable auth failure, got %v", err)
+	}
+}
+
+func TestCookieAuthBypassDetection(t *testing.T) {
+	var logins atomic.Int32
+	everyone := func(r *http.Request) bool { return true }
+	server := newAuthServer(t, everyone, &logins)
+
+	client := newFakeClient(t, server, Config{})
+	if _, err := client.GetTransferInfo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if logins.Load() != 0 {
+		t.Errorf("Expected no login with auth bypass, got %d", logins.Load())
+	}
+	if client.GetStatus() != StatusConnected {
+		t.Errorf("Expected connected status, got %s", client.GetStatus())
+	}
+	if !client.AuthBypassed() {
+		t.Error("Expected bypass to be detected")
+	}
+
+	forced := newFakeClient(t, server, Config{Authenticator: &CookieAuth{DisableBypassDetection: true}})
+	if err := forced.Login(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if logins.Load() != 1 {
+		t.Errorf("Expected a login with bypass detection disabled, got %d", logins.Load())
+	}
+}
+
+func TestCookieAuthSharedBetweenClients(t *testing.T) {
+	var logins, logouts atomic.Int32
+	shared := &CookieAuth{}
+
+	// The first server bypasses authentication, the second does not
+	bypassing := newFakeClient(t, newAuthServer(t, func(r *http.Request) bool { return true }, &logins), Config{Authenticator: shared})
+	if _, err := bypassing.GetTransferInfo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	regularServer := newAuthServer(t, nil, &logins)
+	counting := regularServer.Config.Handler
+	regularServer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2/auth/logout" {
+			logouts.Add(1)
+		}
+		counting.ServeHTTP(w, r)
+	})
+	regular := newFakeClient(t, regularServer, Config{Authenticator: shared})
+	if err := regular.Login(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !bypassing.AuthBypassed() || regular.AuthBypassed() {
+		t.Errorf("Expected bypass per client, got %v and %v", bypassing.AuthBypassed(), regular.AuthBypassed())
+	}
+	if err := regular.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if logouts.Load() != 1 {
+		t.Errorf("Expected the regular client to log out, got %d logouts", logouts.Load())
+	}
+}
+
+func TestHeaderAndBasicAuth(t *testing.T) {
+	allow := func(r *http.Request) bool {
+		user, pass, ok := r.BasicAuth()
+		return r.Header.Get("Remote-User") == "alice" || (ok && user == "proxy" && pass == "secret")
+	}
+
+	testCases := []struct {
+		name     string
+		auth     Authenticator
+		expected ErrorCode
+	}{
+		{"header", HeaderAuth{Values: map[string]string{"Remote-User": "alice"}}, ErrorCodeNone},
+		{"wrong header", HeaderAuth{Values: map[string]string{"Remote-User": "mallory"}}, ErrorCodeAuthFailure},
+		{"basic", BasicAuth{Username: "proxy", Password: "secret"}, ErrorCodeNone},
+		{"wrong basic", BasicAuth{Username: "proxy", Password: "guess"}, ErrorCodeAuthFailure},
+		{"none", NoAuth{}, ErrorCodeAuthFailure},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logins atomic.Int32
+			client := newFakeClient(t, newAuthServer(t, allow, &logins), Config{MaxRetries: 1, Authenticator: tc.auth})
+
+			_, err := client.GetTransferInfo()
+			if code := GetErrorCode(err); code != tc.expected {
+				t.Errorf("Expected %q, got %v", tc.expected, err)
+			}
+			if logins.Load() != 0 {
+				t.Errorf("Expected no cookie login, got %d", logins.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+/*
+Package backup snapshots a qBittorrent instance into a single archive and
+restores it onto another instance.
+
+A snapshot holds the preferences, categories, tags, RSS feeds and rules, and
+for every torrent its .torrent file together with its category, tags, save
+path, speed and share limits, file priorities and whether it was stopped.
+
+	out, _ := os.Create("seedbox.tar.gz")
+	snapshot, err := backup.Export(client, out, backup.ExportOptions{})
+
+	in, _ := os.Open("seedbox.tar.gz")
+	report, err := backup.Import(newClient, in, backup.ImportOptions{})
+
+The archive is a gzip-compressed tar file with snapshot.json at its root and
+the torrents under torrents/<hash>.torrent. Credentials found in the
+preferences, such as the proxy and mail notification passwords, are left out
+unless ExportOptions.IncludeSecrets is set. Exporting .torrent files requires
+qBittorrent 4.5 (Web API 2.8.14) or later.
+*/
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+	"github.com/jfxdev/go-qbt/reconcile"
+)
+
+// FormatVersion is the version of the snapshot format written by Export.
+const FormatVersion = 1
+
+const (
+	snapshotName = "snapshot.json"
+	torrentsDir  = "torrents/"
+)
+
+// TorrentSource is the part of *qbt.Client needed to capture torrents.
+type TorrentSource interface {
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error)
+	ExportTorrent(hash string) ([]byte, error)
+}
+
+// TorrentTarget is the part of *qbt.Client needed to add captured torrents.
+type TorrentTarget interface {
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	AddTorrentFile(filename string, torrent []byte, opts qbt.TorrentConfig) error
+	StartTorrents(hash string) error
+	SetTorrentDownloadLimit(hash string, limit int) error
+	SetTorrentUploadLimit(hash string, limit int) error
+	SetTorrentShareLimit(hash string, ratioLimit float64, seedingTimeLimit int, inactiveSeedingTimeLimit int) error
+	SetFilePriority(hash string, fileIDs []int, priority int) error
+}
+
+// API is the part of *qbt.Client used to export and import snapshots.
+type API interface {
+	reconcile.API
+	TorrentSource
+	TorrentTarget
+	GetAppVersion() (string, error)
+}
+
+// secretPreferences hold credentials. They are only exported with
+// ExportOptions.IncludeSecrets; any other key containing "password" is
+// treated the same way.
+var secretPreferences = []string{
+	"web_ui_username",
+	"web_ui_password",
+	"web_ui_api_key",
+	"proxy_username",
+	"proxy_password",
+	"mail_notification_username",
+	"mail_notification_password",
+	"dyndns_username",
+	"dyndns_password",
+}
+
+// ExportOptions control what a snapshot contains.
+type ExportOptions struct {
+	IncludeSecrets bool // Keep credentials in the exported preferences (default: false)
+}
+
+// Snapshot describes an instance at the time of export.
+type Snapshot struct {
+	Format     int             `json:"format"`
+	CreatedAt  time.Time       `json:"created_at"`
+	AppVersion string          `json:"app_version"`
+	Config     reconcile.State `json:"config"`
+	Torrents   []Torrent       `json:"torrents"`
+}
+
+// Torrent is the per-torrent state of a snapshot. The .torrent file itself is
+// stored next to the snapshot in the archive.
+type Torrent struct {
+	Hash                     string   `json:"hash"`
+	Name                     string   `json:"name"`
+	SavePath                 string   `json:"save_path"`
+	Category                 string   `json:"category,omitempty"`
+	Tags                     []string `json:"tags,omitempty"`
+	Stopped                  bool     `json:"stopped"`
+	DownloadLimit            int      `json:"download_limit"`
+	UploadLimit              int      `json:"upload_limit"`
+	RatioLimit               float64  `json:"ratio_limit"`
+	SeedingTimeLimit         int      `json:"seeding_time_limit"`
+	InactiveSeedingTimeLimit int      `json:"inactive_seeding_time_limit"`
+	FilePriorities           []int    `json:"file_priorities,omitempty"` // Indexed like ListTorrentFiles
+}
+
+// Archive is a snapshot together with the .torrent files, keyed by hash.
+type Archive struct {
+	Snapshot *Snapshot
+	Files    map[string][]byte
+}
+
+// Export snapshots the instance and writes the archive to w.
+func Export(api API, w io.Writer, opts ExportOptions) (*Snapshot, error) {
+	archive, err := Create(api, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := archive.Write(w); err != nil {
+		return nil, err
+	}
+	return archive.Snapshot, nil
+}
+
+// Create snapshots the instance in memory.
+func Create(api API, opts ExportOptions) (*Archive, error) {
+	snapshot := &Snapshot{Format: FormatVersion, CreatedAt: time.Now().UTC()}
+	archive := &Archive{Snapshot: snapshot, Files: make(map[string][]byte)}
+
+	var err error
+	if snapshot.AppVersion, err = api.GetAppVersion(); err != nil {
+		return nil, fmt.Errorf("failed to export instance: %w", err)
+	}
+	if err := exportConfig(api, &snapshot.Config, opts); err != nil {
+		return nil, fmt.Errorf("failed to export instance: %w", err)
+	}
+
+	torrents, err := api.ListTorrents(qbt.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export instance: %w", err)
+	}
+
+	for _, t := range torrents {
+		torrent, data, err := CaptureTorrent(api, t)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Torrents = append(snapshot.Torrents, torrent)
+		archive.Files[t.Hash] = data
+	}
+
+	return archive, nil
+}
+
+// CaptureTorrent exports the .torrent file and the settings of t.
+func CaptureTorrent(src TorrentSource, t *qbt.TorrentResponse) (Torrent, []byte, error) {
+	data, err := src.ExportTorrent(t.Hash)
+	if err != nil {
+		return Torrent{}, nil, fmt.Errorf("failed to export torrent %s: %w", t.Hash, err)
+	}
+	files, err := src.ListTorrentFiles(t.Hash)
+	if err != nil {
+		return Torrent{}, nil, fmt.Errorf("failed to export torrent %s: %w", t.Hash, err)
+	}
+
+	torrent := Torrent{
+		Hash:                     t.Hash,
+		Name:                     t.Name,
+		SavePath:                 t.SavePath,
+		Category:                 t.Category,
+		Tags:                     splitTags(t.Tags),
+		Stopped:                  isStopped(t.State),
+		DownloadLimit:            t.DlLimit,
+		UploadLimit:              t.UpLimit,
+		RatioLimit:               t.RatioLimit,
+		SeedingTimeLimit:         t.SeedingTimeLimit,
+		InactiveSeedingTimeLimit: t.InactiveSeedingTimeLimit,
+	}
+	for _, f := range files {
+		torrent.FilePriorities = append(torrent.FilePriorities, f.Priority)
+	}
+	return torrent, data, nil
+}
+
+func exportConfig(api API, state *reconcile.State, opts ExportOptions) error {
+	categories, err := api.GetCategories()
+	if err != nil {
+		return err
+	}
+	state.Categories = make(map[string]reconcile.Category, len(categories))
+	for name, c := range categories {
+		state.Categories[name] = reconcile.Category{SavePath: c.SavePath}
+	}
+
+	if state.Tags, err = api.GetTags(); err != nil {
+		return err
+	}
+
+	// Folders are recreated by reconcile from the paths of the feeds inside them
+	feeds, _, err := api.ListRSSItems()
+	if err != nil {
+		return err
+	}
+	rules, err := api.GetRSSRules()
+	if err != nil {
+		return err
+	}
+	state.RSS = &reconcile.RSS{Feeds: make(map[string]string, len(feeds)), Rules: rules}
+	for path, feed := range feeds {
+		state.RSS.Feeds[path] = feed.URL
+	}
+
+	if state.Preferences, err = api.GetPreferences(); err != nil {
+		return err
+	}
+	if !opts.IncludeSecrets {
+		for key := range state.Preferences {
+			if isSecretPreference(key) {
+				delete(state.Preferences, key)
+			}
+		}
+	}
+	return nil
+}
+
+func isSecretPreference(key string) bool {
+	return slices.Contains(secretPreferences, key) || strings.Contains(key, "password")
+}
+
+// Write encodes the archive as a gzip-compressed tar file.
+func (a *Archive) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	snapshot, err := json.MarshalIndent(a.Snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := writeEntry(tw, snapshotName, snapshot, a.Snapshot.CreatedAt); err != nil {
+		return err
+	}
+
+	for _, t := range a.Snapshot.Torrents {
+		if err := writeEntry(tw, torrentsDir+t.Hash+".torrent", a.Files[t.Hash], a.Snapshot.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// Read decodes an archive written by Write.
+func Read(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+
+	archive := &Archive{Files: make(map[string][]byte)}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		switch {
+		case header.Name == snapshotName:
+			archive.Snapshot = &Snapshot{}
+			if err := json.Unmarshal(data, archive.Snapshot); err != nil {
+				return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+			}
+		case strings.HasPrefix(header.Name, torrentsDir):
+			hash := strings.TrimSuffix(path.Base(header.Name), ".torrent")
+			archive.Files[hash] = data
+		}
+	}
+
+	if archive.Snapshot == nil {
+		return nil, fmt.Errorf("failed to read archive: %s not found", snapshotName)
+	}
+	if archive.Snapshot.Format > FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format %d (latest supported is %d)", archive.Snapshot.Format, FormatVersion)
+	}
+	return archive, nil
+}
+
+// splitTags parses the comma-separated tag list of torrents/info.
+func splitTags(tags string) []string {
+	var out []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// isStopped reports whether a torrent state is stopped (paused before 5.0).
+func isStopped(state string) bool {
+	return strings.HasPrefix(state, "paused") || strings.HasPrefix(state, "stopped")
+}
@@ -0,0 +1,319 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// fakeAPI is an in-memory instance.
+type fakeAPI struct {
+	categories map[string]qbt.Category
+	tags       []string
+	feeds      map[string]qbt.RSSFeed
+	rules      map[string]qbt.RSSRule
+	prefs      map[string]interface{}
+	torrents   []*qbt.TorrentResponse
+	files      map[string][]byte
+	priorities map[string][]int
+	prefWrites int
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{
+		categories: map[string]qbt.Category{},
+		feeds:      map[string]qbt.RSSFeed{},
+		rules:      map[string]qbt.RSSRule{},
+		prefs:      map[string]interface{}{},
+		files:      map[string][]byte{},
+		priorities: map[string][]int{},
+	}
+}
+
+func (f *fakeAPI) GetCategories() (map[string]qbt.Category, error) { return f.categories, nil }
+func (f *fakeAPI) CreateCategory(name, savePath string) error {
+	f.categories[name] = qbt.Category{Name: name, SavePath: savePath}
+	return nil
+}
+func (f *fakeAPI) EditCategory(name, savePath string) error { return f.CreateCategory(name, savePath) }
+func (f *fakeAPI) DeleteCategory(name string) error         { return fmt.Errorf("unexpected delete") }
+func (f *fakeAPI) GetTags() ([]string, error)               { return f.tags, nil }
+func (f *fakeAPI) CreateTags(tags []string) error {
+	f.tags = append(f.tags, tags...)
+	return nil
+}
+func (f *fakeAPI) DeleteTags(tags []string) error { return fmt.Errorf("unexpected delete") }
+func (f *fakeAPI) ListRSSItems() (map[string]qbt.RSSFeed, []string, error) {
+	return f.feeds, nil, nil
+}
+func (f *fakeAPI) AddRSSFolder(path string) error { return nil }
+func (f *fakeAPI) AddRSSFeed(feedURL, path string) error {
+	f.feeds[path] = qbt.RSSFeed{URL: feedURL}
+	return nil
+}
+func (f *fakeAPI) RemoveRSSFeed(path string) error                 { return fmt.Errorf("unexpected delete") }
+func (f *fakeAPI) GetRSSRules() (map[string]qbt.RSSRule, error)    { return f.rules, nil }
+func (f *fakeAPI) SetRSSRule(name string, rule qbt.RSSRule) error  { f.rules[name] = rule; return nil }
+func (f *fakeAPI) RemoveRSSRule(name string) error                 { return fmt.Errorf("unexpected delete") }
+func (f *fakeAPI) GetPreferences() (map[string]interface{}, error) { return f.prefs, nil }
+func (f *fakeAPI) SetPreferences(prefs map[string]interface{}) error {
+	f.prefWrites++
+	for k, v := range prefs {
+		f.prefs[k] = v
+	}
+	return nil
+}
+func (f *fakeAPI) GetAppVersion() (string, error) { return "v5.0.0", nil }
+
+func (f *fakeAPI) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	if len(opts.Hashes) == 0 {
+		return f.torrents, nil
+	}
+	var out []*qbt.TorrentResponse
+	for _, t := range f.torrents {
+		if t.Hash == opts.Hashes[0] {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAPI) ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error) {
+	var files []*qbt.TorrentFile
+	for _, p := range f.priorities[hash] {
+		files = append(files, &qbt.TorrentFile{Priority: p})
+	}
+	return files, nil
+}
+
+func (f *fakeAPI) ExportTorrent(hash string) ([]byte, error) { return f.files[hash], nil }
+
+func (f *fakeAPI) AddTorrentFile(filename string, torrent []byte, opts qbt.TorrentConfig) error {
+	hash := filename[:len(filename)-len(".torrent")]
+	state := "downloading"
+	if opts.Paused {
+		state = "stoppedDL"
+	}
+	tags := ""
+	for i, tag := range opts.Tags {
+		if i > 0 {
+			tags += ", "
+		}
+		tags += tag
+	}
+	f.torrents = append(f.torrents, &qbt.TorrentResponse{
+		Hash: hash, SavePath: opts.Directory, Category: opts.Category, Tags: tags, State: state,
+		RatioLimit: -2, SeedingTimeLimit: -2, InactiveSeedingTimeLimit: -2,
+	})
+	f.files[hash] = torrent
+	f.priorities[hash] = []int{1, 1, 1}
+	return nil
+}
+
+func (f *fakeAPI) torrent(hash string) *qbt.TorrentResponse {
+	for _, t := range f.torrents {
+		if t.Hash == hash {
+			return t
+		}
+	}
+	return nil
+}
+
+func (f *fakeAPI) SetTorrentDownloadLimit(hash string, limit int) error {
+	f.torrent(hash).DlLimit = limit
+	return nil
+}
+
+func (f *fakeAPI) SetTorrentUploadLimit(hash string, limit int) error {
+	f.torrent(hash).UpLimit = limit
+	return nil
+}
+
+func (f *fakeAPI) SetTorrentShareLimit(hash string, ratioLimit float64, seedingTimeLimit int, inactiveSeedingTimeLimit int) error {
+	t := f.torrent(hash)
+	t.RatioLimit, t.SeedingTimeLimit, t.InactiveSeedingTimeLimit = ratioLimit, seedingTimeLimit, inactiveSeedingTimeLimit
+	return nil
+}
+
+func (f *fakeAPI) StartTorrents(hash string) error {
+	f.torrent(hash).State = "downloading"
+	return nil
+}
+
+func (f *fakeAPI) SetFilePriority(hash string, fileIDs []int, priority int) error {
+	if !isStopped(f.torrent(hash).State) {
+		return fmt.Errorf("priorities set on running torrent %s", hash)
+	}
+	for _, id := range fileIDs {
+		f.priorities[hash][id] = priority
+	}
+	return nil
+}
+
+func newSourceAPI() *fakeAPI {
+	source := newFakeAPI()
+	source.categories["movies"] = qbt.Category{Name: "movies", SavePath: "/data/movies"}
+	source.tags = []string{"keep", "private"}
+	source.feeds["showrss"] = qbt.RSSFeed{URL: "https://example.com/feed.rss"}
+	source.rules["tv"] = qbt.RSSRule{Enabled: true, MustContain: "1080p"}
+	source.prefs = map[string]interface{}{
+		"max_active_downloads": float64(5),
+		"max_active_uploads":   float64(3),
+		"web_ui_port":          float64(9090),
+		"proxy_password":       "hunter2",
+	}
+	source.torrents = []*qbt.TorrentResponse{
+		{Hash: "aaa", Name: "Movie", SavePath: "/data/movies", Category: "movies", Tags: "keep, private", State: "stoppedUP",
+			DlLimit: 1000, UpLimit: 2000, RatioLimit: 2, SeedingTimeLimit: 60, InactiveSeedingTimeLimit: -2},
+		{Hash: "bbb", Name: "Other", SavePath: "/data", State: "uploading",
+			RatioLimit: -2, SeedingTimeLimit: -2, InactiveSeedingTimeLimit: -2},
+	}
+	source.files = map[string][]byte{"aaa": []byte("d4:infod4:name5:Movieee"), "bbb": []byte("d4:infod4:name5:Otheree")}
+	source.priorities = map[string][]int{"aaa": {1, 0, 7}, "bbb": {1}}
+	return source
+}
+
+func TestExportImport(t *testing.T) {
+	source := newSourceAPI()
+
+	var buf bytes.Buffer
+	snapshot, err := Export(source, &buf, ExportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(snapshot.Torrents) != 2 || snapshot.AppVersion != "v5.0.0" {
+		t.Fatalf("Unexpected snapshot: %+v", snapshot)
+	}
+	if _, ok := snapshot.Config.Preferences["proxy_password"]; ok {
+		t.Errorf("Expected secrets to be left out, got %v", snapshot.Config.Preferences)
+	}
+
+	target := newFakeAPI()
+	target.prefs = map[string]interface{}{
+		"max_active_downloads": float64(3),
+		"max_active_uploads":   float64(3),
+		"web_ui_port":          float64(8080),
+		"proxy_password":       "",
+	}
+	report, err := Import(target, bytes.NewReader(buf.Bytes()), ImportOptions{PollInterval: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v (report: %+v)", err, report)
+	}
+	if len(report.Restored) != 2 || len(report.Skipped) != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	if !reflect.DeepEqual(target.categories, source.categories) || !reflect.DeepEqual(target.rules, source.rules) {
+		t.Errorf("Categories or rules not restored: %+v %+v", target.categories, target.rules)
+	}
+	if len(target.tags) != 2 || target.feeds["showrss"].URL != "https://example.com/feed.rss" {
+		t.Errorf("Tags or feeds not restored: %v %v", target.tags, target.feeds)
+	}
+	if target.prefs["max_active_downloads"] != float64(5) || target.prefs["web_ui_port"] != float64(8080) {
+		t.Errorf("Expected preferences restored except WebUI ones, got %v", target.prefs)
+	}
+	if target.prefWrites != 1 {
+		t.Errorf("Expected preferences restored in one call, got %d", target.prefWrites)
+	}
+
+	for _, want := range source.torrents {
+		got := target.torrent(want.Hash)
+		if got.SavePath != want.SavePath || got.Category != want.Category || got.Tags != want.Tags ||
+			got.DlLimit != want.DlLimit || got.UpLimit != want.UpLimit || got.RatioLimit != want.RatioLimit ||
+			got.SeedingTimeLimit != want.SeedingTimeLimit || got.InactiveSeedingTimeLimit != want.InactiveSeedingTimeLimit {
+			t.Errorf("Torrent %s not restored:\nexpected %+v\ngot      %+v", want.Hash, want, got)
+		}
+		if !bytes.Equal(target.files[want.Hash], source.files[want.Hash]) {
+			t.Errorf("Torrent %s: file differs", want.Hash)
+		}
+	}
+	if !reflect.DeepEqual(target.priorities["aaa"], []int{1, 0, 7}) {
+		t.Errorf("File priorities not restored: %v", target.priorities["aaa"])
+	}
+
+	// A second import finds everything in place
+	report, err = Import(target, bytes.NewReader(buf.Bytes()), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Skipped) != 2 || !report.Config.Empty() {
+		t.Errorf("Expected nothing to restore, got %+v\n%s", report, report.Config)
+	}
+}
+
+func TestRestoreStoppedState(t *testing.T) {
+	archive, err := Create(newSourceAPI(), ExportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	target := newFakeAPI()
+	if _, err := Restore(target, archive, ImportOptions{SkipConfig: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if target.torrent("aaa").State != "stoppedDL" || target.torrent("bbb").State != "downloading" {
+		t.Errorf("Stopped state not restored: %s %s", target.torrent("aaa").State, target.torrent("bbb").State)
+	}
+	if len(target.categories) != 0 {
+		t.Errorf("SkipConfig should leave categories alone, got %v", target.categories)
+	}
+
+	delete(archive.Files, "bbb")
+	report, err := Restore(newFakeAPI(), archive, ImportOptions{SkipConfig: true})
+	if err == nil || report.Failed["bbb"] == "" || len(report.Restored) != 1 {
+		t.Errorf("Expected bbb to fail without its torrent file, got %v %+v", err, report)
+	}
+}
+
+func TestRestoreFromServerWithoutInactiveSeedingLimit(t *testing.T) {
+	archive, err := Create(newSourceAPI(), ExportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Archived by qBittorrent 4.5, which reports no inactive seeding limit
+	archive.Snapshot.AppVersion = "v4.5.5"
+	for i := range archive.Snapshot.Torrents {
+		archive.Snapshot.Torrents[i].InactiveSeedingTimeLimit = 0
+	}
+
+	target := newFakeAPI()
+	if _, err := Restore(target, archive, ImportOptions{SkipConfig: true, PollInterval: 1}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, hash := range []string{"aaa", "bbb"} {
+		if got := target.torrent(hash).InactiveSeedingTimeLimit; got != -2 {
+			t.Errorf("Torrent %s: expected the global inactive seeding limit, got %d", hash, got)
+		}
+	}
+	if got := target.torrent("aaa").SeedingTimeLimit; got != 60 {
+		t.Errorf("Expected the other share limits restored, got %d", got)
+	}
+}
+
+func TestExportIncludeSecrets(t *testing.T) {
+	archive, err := Create(newSourceAPI(), ExportOptions{IncludeSecrets: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if archive.Snapshot.Config.Preferences["proxy_password"] != "hunter2" {
+		t.Errorf("Expected secrets with IncludeSecrets, got %v", archive.Snapshot.Config.Preferences)
+	}
+}
+
+func TestReadRejectsInvalidArchives(t *testing.T) {
+	if _, err := Read(bytes.NewReader([]byte("not an archive"))); err == nil {
+		t.Error("Expected an error for invalid data")
+	}
+
+	var buf bytes.Buffer
+	archive := &Archive{Snapshot: &Snapshot{Format: FormatVersion + 1}}
+	if err := archive.Write(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := Read(&buf); err == nil {
+		t.Error("Expected an error for a newer snapshot format")
+	}
+}
@@ -0,0 +1,231 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+	"github.com/jfxdev/go-qbt/reconcile"
+)
+
+// Default values for ImportOptions
+const (
+	DefaultAddTimeout   = 30 * time.Second
+	DefaultPollInterval = 500 * time.Millisecond
+)
+
+// excludedPreferences are never restored, so the instance stays reachable
+// with the address and credentials it was set up with.
+var excludedPreferences = []string{"web_ui_", "bypass_"}
+
+// ImportOptions control how a snapshot is restored.
+type ImportOptions struct {
+	SkipConfig      bool          // Restore torrents only (default: false)
+	SkipPreferences bool          // Keep the instance's preferences (default: false)
+	Paused          bool          // Add every torrent stopped, not only those stopped at export (default: false)
+	SkipChecking    bool          // Add torrents without rechecking existing data (default: false)
+	AddTimeout      time.Duration // How long to wait for an added torrent to appear (default: 30s)
+	PollInterval    time.Duration // Interval between checks for an added torrent (default: 500ms)
+}
+
+// Report summarizes an import.
+type Report struct {
+	Config   *reconcile.Plan   `json:"config,omitempty"` // Configuration changes applied
+	Restored []string          `json:"restored"`         // Hashes of added torrents
+	Skipped  []string          `json:"skipped"`          // Hashes already present on the instance
+	Failed   map[string]string `json:"failed"`           // Errors by hash
+}
+
+// Import reads an archive from r and restores it.
+func Import(api API, r io.Reader, opts ImportOptions) (*Report, error) {
+	archive, err := Read(r)
+	if err != nil {
+		return nil, err
+	}
+	return Restore(api, archive, opts)
+}
+
+// Restore applies the archived configuration and adds the torrents that are
+// not on the instance yet. Configuration is merged: nothing is deleted. A
+// failing torrent does not stop the import; failures are listed in the report
+// and summarized in the returned error.
+func Restore(api API, archive *Archive, opts ImportOptions) (*Report, error) {
+	report := &Report{Failed: make(map[string]string)}
+
+	if !opts.SkipConfig {
+		plan, err := reconcile.Reconcile(api, restoredConfig(archive.Snapshot.Config, opts), reconcile.Options{})
+		report.Config = plan
+		if err != nil {
+			return report, fmt.Errorf("failed to restore configuration: %w", err)
+		}
+	}
+
+	existing, err := api.ListTorrents(qbt.ListOptions{})
+	if err != nil {
+		return report, fmt.Errorf("failed to list torrents: %w", err)
+	}
+	present := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		present[t.Hash] = true
+	}
+
+	for _, t := range archive.Snapshot.Torrents {
+		if t.InactiveSeedingTimeLimit == 0 && !hasInactiveSeedingLimit(archive.Snapshot.AppVersion) {
+			// Exported as 0 by a server that has no inactive seeding limit
+			t.InactiveSeedingTimeLimit = -2
+		}
+		if present[t.Hash] {
+			report.Skipped = append(report.Skipped, t.Hash)
+			continue
+		}
+		if err := AddTorrent(api, t, archive.Files[t.Hash], opts); err != nil {
+			report.Failed[t.Hash] = err.Error()
+			continue
+		}
+		report.Restored = append(report.Restored, t.Hash)
+	}
+
+	if len(report.Failed) > 0 {
+		return report, fmt.Errorf("failed to restore %d of %d torrents", len(report.Failed), len(archive.Snapshot.Torrents))
+	}
+	return report, nil
+}
+
+// hasInactiveSeedingLimit reports whether the qBittorrent version an archive
+// was exported from has the inactive seeding limit (4.6, Web API 2.9.2).
+// Unknown versions are assumed to have it.
+func hasInactiveSeedingLimit(appVersion string) bool {
+	version, err := qbt.ParseVersion(appVersion)
+	return err != nil || version.AtLeast(qbt.Version{Major: 4, Minor: 6})
+}
+
+// restoredConfig drops the preferences that must not be restored.
+func restoredConfig(config reconcile.State, opts ImportOptions) *reconcile.State {
+	if opts.SkipPreferences {
+		config.Preferences = nil
+		return &config
+	}
+
+	prefs := make(map[string]interface{}, len(config.Preferences))
+	for key, value := range config.Preferences {
+		if !isExcludedPreference(key) {
+			prefs[key] = value
+		}
+	}
+	config.Preferences = prefs
+	return &config
+}
+
+func isExcludedPreference(key string) bool {
+	for _, prefix := range excludedPreferences {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTorrent adds a captured torrent stopped, waits for it to appear, applies
+// its settings and then starts it unless it was stopped. It uses the
+// AddTimeout, PollInterval, Paused and SkipChecking options.
+func AddTorrent(dst TorrentTarget, t Torrent, data []byte, opts ImportOptions) error {
+	if len(data) == 0 {
+		return fmt.Errorf("torrent file missing from archive")
+	}
+	if opts.AddTimeout <= 0 {
+		opts.AddTimeout = DefaultAddTimeout
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+
+	// Added stopped so nothing is downloaded before the file priorities are
+	// set, then started unless it was stopped in the snapshot
+	err := dst.AddTorrentFile(t.Hash+".torrent", data, qbt.TorrentConfig{
+		Directory:    t.SavePath,
+		Category:     t.Category,
+		Tags:         t.Tags,
+		Paused:       true,
+		SkipChecking: opts.SkipChecking,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Torrents are added asynchronously; settings apply once they exist
+	if err := waitForTorrent(dst, t.Hash, opts); err != nil {
+		return err
+	}
+	if err := ApplySettings(dst, t); err != nil {
+		return err
+	}
+	if t.Stopped || opts.Paused {
+		return nil
+	}
+	return dst.StartTorrents(t.Hash)
+}
+
+// ApplySettings sets the limits and file priorities of a captured torrent on
+// an instance that already has it. Settings left at their defaults are skipped.
+func ApplySettings(dst TorrentTarget, t Torrent) error {
+	if t.DownloadLimit > 0 {
+		if err := dst.SetTorrentDownloadLimit(t.Hash, t.DownloadLimit); err != nil {
+			return err
+		}
+	}
+	if t.UploadLimit > 0 {
+		if err := dst.SetTorrentUploadLimit(t.Hash, t.UploadLimit); err != nil {
+			return err
+		}
+	}
+	if t.RatioLimit != -2 || t.SeedingTimeLimit != -2 || t.InactiveSeedingTimeLimit != -2 {
+		if err := dst.SetTorrentShareLimit(t.Hash, t.RatioLimit, t.SeedingTimeLimit, t.InactiveSeedingTimeLimit); err != nil {
+			return err
+		}
+	}
+
+	return restoreFilePriorities(dst, t)
+}
+
+// restoreFilePriorities sets non-default priorities with one call per level.
+func restoreFilePriorities(dst TorrentTarget, t Torrent) error {
+	byPriority := make(map[int][]int)
+	for id, priority := range t.FilePriorities {
+		if priority != 1 {
+			byPriority[priority] = append(byPriority[priority], id)
+		}
+	}
+
+	priorities := make([]int, 0, len(byPriority))
+	for priority := range byPriority {
+		priorities = append(priorities, priority)
+	}
+	sort.Ints(priorities)
+
+	for _, priority := range priorities {
+		if err := dst.SetFilePriority(t.Hash, byPriority[priority], priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func waitForTorrent(dst TorrentTarget, hash string, opts ImportOptions) error {
+	deadline := time.Now().Add(opts.AddTimeout)
+	for {
+		torrents, err := dst.ListTorrents(qbt.ListOptions{Hashes: []string{hash}})
+		if err != nil {
+			return err
+		}
+		if len(torrents) > 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("torrent did not appear within %s", opts.AddTimeout)
+		}
+		time.Sleep(opts.PollInterval)
+	}
+}
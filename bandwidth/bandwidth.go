@@ -0,0 +1,287 @@
+/*
+Package bandwidth applies speed limits by time of day and day of week.
+
+qBittorrent's own scheduler only switches the alternative limits on a weekly
+grid. A Scheduler takes a list of windows, each with its own timezone and
+days, and applies the first window that contains the current time: global
+limits, the alternative speed mode and per-category torrent limits. Outside
+every window the Default window applies:
+
+	scheduler := bandwidth.New(client, bandwidth.Config{
+		Windows: []bandwidth.Window{{
+			Name:     "weekday evenings",
+			Days:     []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+			Start:    18 * time.Hour,
+			End:      23 * time.Hour,
+			Location: berlin,
+			Download: bandwidth.Limit(2 << 20),
+			Categories: map[string]bandwidth.Limits{
+				"backup": {Download: 256 << 10, Upload: 128 << 10},
+			},
+		}},
+		Default: bandwidth.Window{
+			Download:   bandwidth.Limit(0),
+			Categories: map[string]bandwidth.Limits{"backup": {}}, // Lift the evening limits
+		},
+	})
+	go scheduler.Run(ctx)
+
+qBittorrent's global limit endpoints read and write the limits of whichever
+speed mode is active, so a window's Alternative mode is switched first and
+its Download and Upload then set the limits of that mode.
+
+Applying is idempotent: the current settings are read first and only the
+ones that differ are changed, so reapplying the same window does nothing.
+*/
+package bandwidth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// DefaultInterval is the time between applications in Run.
+const DefaultInterval = time.Minute
+
+// API is the part of *qbt.Client used by the scheduler.
+type API interface {
+	GetGlobalDownloadLimit() (int, error)
+	GetGlobalUploadLimit() (int, error)
+	SetGlobalDownloadSpeedLimit(limit int) error
+	SetGlobalUploadSpeedLimit(limit int) error
+	GetSpeedLimitsMode() (bool, error)
+	SetSpeedLimitsMode(alternative bool) error
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	SetTorrentDownloadLimit(hash string, limit int) error
+	SetTorrentUploadLimit(hash string, limit int) error
+}
+
+// Limits are per-torrent speed limits in bytes/s; 0 means unlimited.
+type Limits struct {
+	Download int
+	Upload   int
+}
+
+// Window is a recurring period and the settings applied during it. Unset
+// settings are left as they are.
+type Window struct {
+	Name        string
+	Days        []time.Weekday    // Days the window starts on (default: every day)
+	Start       time.Duration     // Start as time since midnight
+	End         time.Duration     // End as time since midnight; before Start crosses midnight, equal to Start is all day
+	Location    *time.Location    // Timezone of Days, Start and End (default: Config.Location)
+	Download    *int              // Global download limit of the active mode in bytes/s, 0 for unlimited
+	Upload      *int              // Global upload limit of the active mode in bytes/s, 0 for unlimited
+	Alternative *bool             // Alternative speed limits mode, applied before Download and Upload
+	Categories  map[string]Limits // Limits for every torrent in these categories
+}
+
+// Limit returns a pointer to limit, for Window.Download and Window.Upload.
+func Limit(limit int) *int {
+	return &limit
+}
+
+// Mode returns a pointer to alternative, for Window.Alternative.
+func Mode(alternative bool) *bool {
+	return &alternative
+}
+
+// Config configures a Scheduler.
+type Config struct {
+	Windows  []Window         // Checked in order; the first containing the current time applies
+	Default  Window           // Applied outside every window; its period is ignored
+	Location *time.Location   // Timezone of windows without one (default: time.Local)
+	Interval time.Duration    // Time between applications in Run (default: 1m)
+	Logger   *slog.Logger     // Receives changes and errors (default: discard)
+	Now      func() time.Time // Clock (default: time.Now)
+}
+
+// Result reports one application.
+type Result struct {
+	Window  string   // Name of the applied window, "default" outside every window
+	Changes []string // Settings that were changed; empty when already applied
+}
+
+// Scheduler applies windows to one instance.
+type Scheduler struct {
+	api    API
+	config Config
+	logger *slog.Logger
+}
+
+// New creates a scheduler for the instance behind api.
+func New(api API, config Config) *Scheduler {
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	if config.Default.Name == "" {
+		config.Default.Name = "default"
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Scheduler{api: api, config: config, logger: logger}
+}
+
+// Run applies the current window every Interval until ctx is cancelled.
+// Failed applications are logged and retried on the next tick.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Apply(); err != nil {
+			s.logger.Warn("bandwidth schedule failed", qbt.LogKeyError, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Current returns the window containing the current time, or Default.
+func (s *Scheduler) Current() *Window {
+	now := s.config.Now()
+	for i := range s.config.Windows {
+		if s.contains(&s.config.Windows[i], now) {
+			return &s.config.Windows[i]
+		}
+	}
+	return &s.config.Default
+}
+
+func (s *Scheduler) contains(w *Window, now time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = s.config.Location
+	}
+	local := now.In(loc)
+	day := local.Weekday()
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second
+
+	onDay := func(d time.Weekday) bool { return len(w.Days) == 0 || slices.Contains(w.Days, d) }
+	switch {
+	case w.Start == w.End:
+		return onDay(day)
+	case w.Start < w.End:
+		return onDay(day) && clock >= w.Start && clock < w.End
+	case clock >= w.Start:
+		return onDay(day)
+	case clock < w.End:
+		// After midnight: the window started the day before
+		return onDay((day + 6) % 7)
+	}
+	return false
+}
+
+// Apply brings the instance in line with the current window, changing only
+// the settings that differ. The speed mode is switched before the global
+// limits, which apply to the active mode.
+func (s *Scheduler) Apply() (*Result, error) {
+	w := s.Current()
+	result := &Result{Window: w.Name}
+
+	if w.Alternative != nil {
+		if err := s.alternative(result, *w.Alternative); err != nil {
+			return result, err
+		}
+	}
+	if w.Download != nil {
+		if err := s.global(result, "download", s.api.GetGlobalDownloadLimit, s.api.SetGlobalDownloadSpeedLimit, *w.Download); err != nil {
+			return result, err
+		}
+	}
+	if w.Upload != nil {
+		if err := s.global(result, "upload", s.api.GetGlobalUploadLimit, s.api.SetGlobalUploadSpeedLimit, *w.Upload); err != nil {
+			return result, err
+		}
+	}
+	if len(w.Categories) > 0 {
+		if err := s.categories(result, w.Categories); err != nil {
+			return result, err
+		}
+	}
+
+	if len(result.Changes) > 0 {
+		s.logger.Info("bandwidth schedule applied", "window", result.Window, "changes", result.Changes)
+	}
+	return result, nil
+}
+
+func (s *Scheduler) global(result *Result, name string, get func() (int, error), set func(int) error, limit int) error {
+	current, err := get()
+	if err != nil {
+		return fmt.Errorf("failed to get global %s limit: %w", name, err)
+	}
+	// Global limits are stored in whole KiB/s
+	if qbt.NormalizeSpeedLimit(current) == qbt.NormalizeSpeedLimit(limit) {
+		return nil
+	}
+	if err := set(limit); err != nil {
+		return fmt.Errorf("failed to set global %s limit: %w", name, err)
+	}
+	result.Changes = append(result.Changes, fmt.Sprintf("global %s limit %d -> %d", name, current, limit))
+	return nil
+}
+
+func (s *Scheduler) alternative(result *Result, enabled bool) error {
+	current, err := s.api.GetSpeedLimitsMode()
+	if err != nil {
+		return err
+	}
+	if current == enabled {
+		return nil
+	}
+	if err := s.api.SetSpeedLimitsMode(enabled); err != nil {
+		return err
+	}
+	result.Changes = append(result.Changes, fmt.Sprintf("alternative speed limits %v", enabled))
+	return nil
+}
+
+func (s *Scheduler) categories(result *Result, categories map[string]Limits) error {
+	torrents, err := s.api.ListTorrents(qbt.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list torrents: %w", err)
+	}
+	for _, t := range torrents {
+		limits, ok := categories[t.Category]
+		if !ok {
+			continue
+		}
+		if !sameLimit(t.DlLimit, limits.Download) {
+			if err := s.api.SetTorrentDownloadLimit(t.Hash, limits.Download); err != nil {
+				return fmt.Errorf("failed to set download limit of %s: %w", t.Hash, err)
+			}
+			result.Changes = append(result.Changes, fmt.Sprintf("%s download limit %d -> %d", t.Hash, t.DlLimit, limits.Download))
+		}
+		if !sameLimit(t.UpLimit, limits.Upload) {
+			if err := s.api.SetTorrentUploadLimit(t.Hash, limits.Upload); err != nil {
+				return fmt.Errorf("failed to set upload limit of %s: %w", t.Hash, err)
+			}
+			result.Changes = append(result.Changes, fmt.Sprintf("%s upload limit %d -> %d", t.Hash, t.UpLimit, limits.Upload))
+		}
+	}
+	return nil
+}
+
+// sameLimit compares torrent limits, treating every value below 1 as
+// unlimited. Unlike global limits, torrent limits are kept in bytes/s.
+func sameLimit(current, wanted int) bool {
+	return current == wanted || (current <= 0 && wanted <= 0)
+}
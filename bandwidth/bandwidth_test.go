@@ -0,0 +1,164 @@
+package bandwidth
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// fakeAPI keeps the global limits of the normal [0] and alternative [1]
+// modes; like qBittorrent, the limit methods act on the active mode and
+// store whole KiB/s.
+type fakeAPI struct {
+	download, upload [2]int
+	alternative      bool
+	torrents         []*qbt.TorrentResponse
+	calls            []string
+}
+
+func (f *fakeAPI) mode() int {
+	if f.alternative {
+		return 1
+	}
+	return 0
+}
+
+func (f *fakeAPI) GetGlobalDownloadLimit() (int, error) { return f.download[f.mode()], nil }
+func (f *fakeAPI) GetGlobalUploadLimit() (int, error)   { return f.upload[f.mode()], nil }
+
+func (f *fakeAPI) SetGlobalDownloadSpeedLimit(limit int) error {
+	f.calls = append(f.calls, fmt.Sprintf("download %d", limit))
+	f.download[f.mode()] = qbt.NormalizeSpeedLimit(limit)
+	return nil
+}
+
+func (f *fakeAPI) SetGlobalUploadSpeedLimit(limit int) error {
+	f.calls = append(f.calls, fmt.Sprintf("upload %d", limit))
+	f.upload[f.mode()] = qbt.NormalizeSpeedLimit(limit)
+	return nil
+}
+
+func (f *fakeAPI) GetSpeedLimitsMode() (bool, error) { return f.alternative, nil }
+
+func (f *fakeAPI) SetSpeedLimitsMode(alternative bool) error {
+	f.calls = append(f.calls, fmt.Sprintf("alternative %v", alternative))
+	f.alternative = alternative
+	return nil
+}
+
+func (f *fakeAPI) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	return f.torrents, nil
+}
+
+func (f *fakeAPI) torrent(hash string) *qbt.TorrentResponse {
+	for _, t := range f.torrents {
+		if t.Hash == hash {
+			return t
+		}
+	}
+	return nil
+}
+
+func (f *fakeAPI) SetTorrentDownloadLimit(hash string, limit int) error {
+	f.calls = append(f.calls, fmt.Sprintf("%s download %d", hash, limit))
+	f.torrent(hash).DlLimit = limit
+	return nil
+}
+
+func (f *fakeAPI) SetTorrentUploadLimit(hash string, limit int) error {
+	f.calls = append(f.calls, fmt.Sprintf("%s upload %d", hash, limit))
+	f.torrent(hash).UpLimit = limit
+	return nil
+}
+
+func TestCurrent(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	windows := []Window{
+		{Name: "weekday evening", Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, Start: 18 * time.Hour, End: 23 * time.Hour},
+		{Name: "friday night", Days: []time.Weekday{time.Friday}, Start: 23 * time.Hour, End: 6 * time.Hour},
+		{Name: "tokyo sunday", Days: []time.Weekday{time.Sunday}, Location: tokyo},
+	}
+
+	testCases := []struct {
+		now      string
+		expected string
+	}{
+		{"2026-05-04T18:00:00Z", "weekday evening"}, // Monday
+		{"2026-05-04T23:00:00Z", "default"},
+		{"2026-05-08T23:30:00Z", "friday night"},
+		{"2026-05-09T05:59:00Z", "friday night"}, // Saturday morning, started Friday
+		{"2026-05-09T06:00:00Z", "default"},
+		{"2026-05-07T23:30:00Z", "default"},      // Thursday night
+		{"2026-05-09T15:00:00Z", "tokyo sunday"}, // Already Sunday in Tokyo
+		{"2026-05-10T15:00:00Z", "default"},      // Monday in Tokyo
+	}
+	for _, tc := range testCases {
+		now, _ := time.Parse(time.RFC3339, tc.now)
+		s := New(&fakeAPI{}, Config{Windows: windows, Location: time.UTC, Now: func() time.Time { return now }})
+		if got := s.Current().Name; got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.now, tc.expected, got)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	api := &fakeAPI{
+		download: [2]int{0, 0},
+		upload:   [2]int{1000, 1000},
+		torrents: []*qbt.TorrentResponse{
+			{Hash: "a", Category: "backup", DlLimit: -1, UpLimit: -1},
+			{Hash: "b", Category: "backup", DlLimit: 500, UpLimit: 0},
+			{Hash: "c", Category: "tv", DlLimit: -1, UpLimit: -1},
+		},
+	}
+	now := time.Date(2026, 5, 4, 20, 0, 0, 0, time.UTC)
+	s := New(api, Config{
+		Location: time.UTC,
+		Now:      func() time.Time { return now },
+		Windows: []Window{{
+			Name:        "evening",
+			Start:       18 * time.Hour,
+			End:         23 * time.Hour,
+			Download:    Limit(1500),
+			Upload:      Limit(1000),
+			Alternative: Mode(true),
+			Categories:  map[string]Limits{"backup": {Download: 500}},
+		}},
+		Default: Window{
+			Download:    Limit(0),
+			Alternative: Mode(false),
+			Categories:  map[string]Limits{"backup": {}},
+		},
+	})
+
+	result, err := s.Apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "alternative true,download 1500,a download 500"
+	if got := strings.Join(api.calls, ","); got != expected || result.Window != "evening" || len(result.Changes) != 3 {
+		t.Errorf("Expected %q, got %q (%+v)", expected, got, result)
+	}
+
+	// Reapplying does nothing, although the server rounded the limit
+	api.calls = nil
+	if result, err := s.Apply(); err != nil || len(api.calls) != 0 || len(result.Changes) != 0 {
+		t.Errorf("Expected no changes, got %v %+v %v", api.calls, result, err)
+	}
+
+	now = now.Add(4 * time.Hour)
+	result, err = s.Apply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "alternative false,a download 0,b download 0"
+	if got := strings.Join(api.calls, ","); got != expected || result.Window != "default" {
+		t.Errorf("Expected %q, got %q (%+v)", expected, got, result)
+	}
+	if api.download != [2]int{0, 1024} || api.upload != [2]int{1000, 1000} {
+		t.Errorf("Expected the evening limit in the alternative mode only, got download %v upload %v", api.download, api.upload)
+	}
+}
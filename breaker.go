@@ -0,0 +1,158 @@
+package qbt
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states reported in ConnectionStatus
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// DefaultBreakerCooldown is how long an open circuit fails fast before probing again.
+const DefaultBreakerCooldown = 30 * time.Second
+
+// breakerTripCodes are the error classifications that indicate the instance itself
+// is down or unreachable, as opposed to a bad request or bad credentials.
+var breakerTripCodes = map[ErrorCode]bool{
+	ErrorCodeConnectionRefused:  true,
+	ErrorCodeNetworkUnreachable: true,
+	ErrorCodeSocketNotFound:     true,
+	ErrorCodeBadGateway:         true,
+	ErrorCodeServiceUnavailable: true,
+	ErrorCodeTimeout:            true,
+	ErrorCodeDNS:                true,
+}
+
+// circuitBreaker tracks consecutive infrastructure failures. After threshold
+// failures it opens and rejects calls until cooldown has elapsed; then a single
+// caller is let through as a half-open probe whose outcome closes or re-opens it.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     CircuitClosed,
+	}
+}
+
+// admit reports whether a call may proceed. When the cooldown has elapsed the
+// first caller moves the circuit to half-open and becomes the probe.
+func (cb *circuitBreaker) admit(now time.Time) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if now.Sub(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.state = CircuitHalfOpen
+		return true
+	case CircuitHalfOpen:
+		// A probe is already in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// record feeds the outcome of a call into the breaker and returns the resulting state.
+func (cb *circuitBreaker) record(err error, now time.Time) string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if err == nil || !breakerTripCodes[GetErrorCode(err)] {
+		cb.failures = 0
+		cb.state = CircuitClosed
+		return cb.state
+	}
+
+	cb.failures++
+	if cb.state == CircuitHalfOpen || cb.failures >= cb.threshold {
+		cb.state = CircuitOpen
+		cb.openedAt = now
+	}
+	return cb.state
+}
+
+func (cb *circuitBreaker) current() (string, time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state, cb.openedAt.Add(cb.cooldown)
+}
+
+// circuitState returns the breaker state, or "" when the breaker is disabled.
+func (qb *Client) circuitState() string {
+	breaker := qb.circuitBreaker()
+	if breaker == nil {
+		return ""
+	}
+	state, _ := breaker.current()
+	return state
+}
+
+func (qb *Client) circuitBreaker() *circuitBreaker {
+	qb.mu.RLock()
+	defer qb.mu.RUnlock()
+	return qb.breaker
+}
+
+// admitCircuit fails fast while the circuit is open.
+func (qb *Client) admitCircuit() error {
+	breaker := qb.circuitBreaker()
+	if breaker == nil || breaker.admit(time.Now()) {
+		return nil
+	}
+
+	_, retryAt := breaker.current()
+	return NewClientError(
+		ErrorCodeCircuitOpen,
+		fmt.Sprintf("Circuit breaker open - failing fast until %s", retryAt.Format(time.RFC3339)),
+		nil,
+		false,
+	)
+}
+
+// recordCircuit feeds a request outcome to the breaker, logging state changes.
+func (qb *Client) recordCircuit(err error) {
+	breaker := qb.circuitBreaker()
+	if breaker == nil {
+		return
+	}
+
+	before, _ := breaker.current()
+	after := breaker.record(err, time.Now())
+	if before != after {
+		qb.log().Warn("circuit breaker state changed",
+			append([]any{
+				slog.String(LogKeyOperation, "circuit_breaker"),
+				slog.String("from", before),
+				slog.String("to", after),
+			}, errorAttrs(err)...)...)
+	}
+}
+
+// isCircuitOpenError reports whether err was caused by an open circuit.
+func isCircuitOpenError(err error) bool {
+	return GetErrorCode(err) == ErrorCodeCircuitOpen
+}
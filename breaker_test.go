@@ -0,0 +1,138 @@
+package qbt
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	breaker := newCircuitBreaker(2, time.Minute)
+	now := time.Now()
+	refused := NewClientError(ErrorCodeConnectionRefused, "refused", nil, false)
+
+	if state := breaker.record(refused, now); state != CircuitClosed {
+		t.Fatalf("Expected closed after 1 failure, got %s", state)
+	}
+	if state := breaker.record(refused, now); state != CircuitOpen {
+		t.Fatalf("Expected open after 2 failures, got %s", state)
+	}
+
+	if breaker.admit(now.Add(30 * time.Second)) {
+		t.Error("Open circuit should reject calls during cooldown")
+	}
+
+	// First caller after the cooldown becomes the probe, others are rejected
+	if !breaker.admit(now.Add(2 * time.Minute)) {
+		t.Fatal("Expected a probe to be admitted after cooldown")
+	}
+	if breaker.admit(now.Add(2 * time.Minute)) {
+		t.Error("Only one probe should be admitted while half-open")
+	}
+
+	// A failed probe re-opens immediately
+	if state := breaker.record(refused, now.Add(2*time.Minute)); state != CircuitOpen {
+		t.Fatalf("Expected open after failed probe, got %s", state)
+	}
+
+	if !breaker.admit(now.Add(4 * time.Minute)) {
+		t.Fatal("Expected a probe to be admitted after second cooldown")
+	}
+	if state := breaker.record(nil, now.Add(4*time.Minute)); state != CircuitClosed {
+		t.Errorf("Expected closed after successful probe, got %s", state)
+	}
+}
+
+func TestCircuitBreakerIgnoresNonInfrastructureErrors(t *testing.T) {
+	breaker := newCircuitBreaker(1, time.Minute)
+
+	for _, err := range []error{
+		NewClientError(ErrorCodeAuthFailure, "bad credentials", nil, true),
+		errors.New("failed to decode response"),
+	} {
+		if state := breaker.record(err, time.Now()); state != CircuitClosed {
+			t.Errorf("%v should not open the circuit, got %s", err, state)
+		}
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	if newCircuitBreaker(0, time.Minute) != nil {
+		t.Error("Breaker should be nil when threshold is zero")
+	}
+
+	client := &Client{}
+	if err := client.admitCircuit(); err != nil {
+		t.Errorf("Disabled breaker should admit all calls: %v", err)
+	}
+	if state := client.circuitState(); state != "" {
+		t.Errorf("Disabled breaker should report no state, got %q", state)
+	}
+}
+
+func TestCircuitBreakerFailsFast(t *testing.T) {
+	var versionCalls int32
+	var down atomic.Bool
+	down.Store(true)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/app/version", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&versionCalls, 1)
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("v5.0.0"))
+	})
+	mux.HandleFunc("/api/v2/app/webapiVersion", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("2.11.2"))
+	})
+
+	client := newFakeClient(t, newFakeServer(t, mux), Config{
+		MaxRetries:       3,
+		BreakerThreshold: 2,
+		BreakerCooldown:  50 * time.Millisecond,
+	})
+
+	_, err := client.GetAPIVersion()
+	if GetErrorCode(err) != ErrorCodeCircuitOpen {
+		t.Fatalf("Expected circuit open error, got %v", err)
+	}
+	if calls := atomic.LoadInt32(&versionCalls); calls != 2 {
+		t.Errorf("Expected the circuit to open after 2 probes, got %d calls", calls)
+	}
+
+	status := client.GetConnectionStatus()
+	if status.Circuit != CircuitOpen || status.Status != StatusUnaccessible {
+		t.Errorf("Expected open circuit in connection status, got %+v", status)
+	}
+	if status.ErrorCode != ErrorCodeBadGateway {
+		t.Errorf("Expected last error to keep the root cause, got %s", status.ErrorCode)
+	}
+
+	// While open, calls don't reach the server at all
+	before := atomic.LoadInt32(&versionCalls)
+	if _, err := client.GetAPIVersion(); GetErrorCode(err) != ErrorCodeCircuitOpen {
+		t.Fatalf("Expected circuit open error, got %v", err)
+	}
+	if atomic.LoadInt32(&versionCalls) != before {
+		t.Error("Open circuit should not send requests")
+	}
+
+	// After the cooldown the version endpoint is probed and the circuit closes
+	down.Store(false)
+	time.Sleep(60 * time.Millisecond)
+
+	version, err := client.GetAPIVersion()
+	if err != nil {
+		t.Fatalf("Expected recovery after cooldown: %v", err)
+	}
+	if version != "2.11.2" {
+		t.Errorf("Expected version 2.11.2, got %q", version)
+	}
+	if state := client.GetConnectionStatus().Circuit; state != CircuitClosed {
+		t.Errorf("Expected closed circuit after recovery, got %s", state)
+	}
+}
@@ -0,0 +1,176 @@
+package qbt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed major.minor.patch version.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses versions such as "2.11.0", "v4.6.2" or "5.0.0beta1".
+// Missing components default to zero and pre-release suffixes are ignored.
+func ParseVersion(s string) (Version, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	parts := strings.SplitN(trimmed, ".", 3)
+
+	var numbers [3]int
+	for i, part := range parts {
+		// Keep the leading digits only ("0beta1" -> "0")
+		end := 0
+		for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		numbers[i], _ = strconv.Atoi(part[:end])
+		if end < len(part) {
+			break
+		}
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 when v is older than, equal to or newer than other.
+func (v Version) Compare(other Version) int {
+	for _, diff := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether v is equal to or newer than other.
+func (v Version) AtLeast(other Version) bool {
+	return v.Compare(other) >= 0
+}
+
+// Feature is an API feature introduced in a given Web API version.
+type Feature struct {
+	Name   string
+	MinAPI Version
+}
+
+// Known version-gated features
+var (
+	// FeatureBuildInfo is app/buildInfo (qBittorrent 4.2)
+	FeatureBuildInfo = Feature{Name: "build info", MinAPI: Version{2, 3, 0}}
+	// FeatureTorrentExport is torrents/export (qBittorrent 4.5)
+	FeatureTorrentExport = Feature{Name: "torrent export", MinAPI: Version{2, 8, 14}}
+	// FeatureInactiveSeedingLimit is inactiveSeedingTimeLimit in torrents/setShareLimits (qBittorrent 4.6)
+	FeatureInactiveSeedingLimit = Feature{Name: "inactive seeding time limit", MinAPI: Version{2, 9, 2}}
+	// FeatureStopStart renames torrents/pause and torrents/resume to stop and start (qBittorrent 5.0)
+	FeatureStopStart = Feature{Name: "torrents/stop and torrents/start", MinAPI: Version{2, 11, 0}}
+)
+
+// Capabilities describes the server the client is connected to.
+type Capabilities struct {
+	AppVersion Version // qBittorrent version (zero if it could not be read)
+	APIVersion Version // Web API version
+}
+
+// Supports reports whether the server provides feature.
+func (c *Capabilities) Supports(feature Feature) bool {
+	return c.APIVersion.AtLeast(feature.MinAPI)
+}
+
+// Capabilities detects the server versions on first use and caches them until
+// the next Update.
+func (qb *Client) Capabilities() (*Capabilities, error) {
+	qb.capsMu.Lock()
+	cached, gen := qb.caps, qb.capsGen
+	qb.capsMu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	// Detect without the lock so a slow server does not block every caller;
+	// concurrent first calls may detect twice, which is harmless
+	apiVersion, err := qb.GetAPIVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect server capabilities: %w", err)
+	}
+
+	caps := &Capabilities{}
+	caps.APIVersion, err = ParseVersion(apiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect server capabilities: %w", err)
+	}
+
+	// The app version is informational only; routing relies on the API version
+	if appVersion, err := qb.GetAppVersion(); err == nil {
+		caps.AppVersion, _ = ParseVersion(appVersion)
+	}
+
+	qb.capsMu.Lock()
+	if qb.capsGen == gen {
+		qb.caps = caps
+	}
+	qb.capsMu.Unlock()
+
+	qb.log().Debug("detected server capabilities",
+		"app_version", caps.AppVersion.String(),
+		"api_version", caps.APIVersion.String())
+	return caps, nil
+}
+
+// resetCapabilities forgets the detected versions, e.g. after BaseURL changed.
+func (qb *Client) resetCapabilities() {
+	qb.capsMu.Lock()
+	defer qb.capsMu.Unlock()
+	qb.caps = nil
+	qb.capsGen++
+}
+
+// requireFeature fails with ErrorCodeVersionIncompatible, before any request
+// is sent, when the server does not provide feature.
+func (qb *Client) requireFeature(feature Feature) error {
+	caps, err := qb.Capabilities()
+	if err != nil {
+		return err
+	}
+	if !caps.Supports(feature) {
+		return NewClientError(
+			ErrorCodeVersionIncompatible,
+			fmt.Sprintf("%s requires Web API %s or later, server has %s", feature.Name, feature.MinAPI, caps.APIVersion),
+			nil,
+			true,
+		)
+	}
+	return nil
+}
+
+// torrentAction returns the endpoint name for action on this server: stop and
+// start on qBittorrent 5, pause and resume before.
+func (qb *Client) torrentAction(action string) (string, error) {
+	caps, err := qb.Capabilities()
+	if err != nil {
+		return "", err
+	}
+	if caps.Supports(FeatureStopStart) {
+		return action, nil
+	}
+
+	switch action {
+	case "stop":
+		return "pause", nil
+	case "start":
+		return "resume", nil
+	default:
+		return action, nil
+	}
+}
@@ -0,0 +1,223 @@
+package qbt
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseVersion(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Version
+	}{
+		{"2.11.0", Version{2, 11, 0}},
+		{"v4.6.2", Version{4, 6, 2}},
+		{"5.0.0beta1", Version{5, 0, 0}},
+		{"v5.1", Version{5, 1, 0}},
+		{" 2.8.14\n", Version{2, 8, 14}},
+	}
+
+	for _, tc := range testCases {
+		version, err := ParseVersion(tc.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if version != tc.expected {
+			t.Errorf("%q: expected %v, got %v", tc.input, tc.expected, version)
+		}
+	}
+
+	for _, input := range []string{"", "latest", "v.1"} {
+		if _, err := ParseVersion(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+
+	if !(Version{2, 11, 0}).AtLeast(Version{2, 9, 2}) || (Version{2, 8, 14}).AtLeast(Version{2, 9, 0}) {
+		t.Error("Versions should compare numerically, not lexically")
+	}
+}
+
+// newVersionedServer fakes a server with the given Web API version and
+// records the torrent endpoints that were called.
+func newVersionedServer(t *testing.T, apiVersion string, calls *[]string, detections *atomic.Int32) *Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/app/webapiVersion", func(w http.ResponseWriter, r *http.Request) {
+		detections.Add(1)
+		w.Write([]byte(apiVersion))
+	})
+	mux.HandleFunc("/api/v2/torrents/", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		call := r.URL.Path[len("/api/v2/torrents/"):]
+		if _, ok := r.PostForm["inactiveSeedingTimeLimit"]; ok {
+			call += "+inactive"
+		}
+		*calls = append(*calls, call)
+	})
+
+	return newFakeClient(t, newFakeServer(t, mux), Config{})
+}
+
+func TestVersionRouting(t *testing.T) {
+	testCases := []struct {
+		apiVersion string
+		expected   []string
+	}{
+		{"2.9.3", []string{"pause", "resume", "pause", "resume"}},
+		{"2.11.0", []string{"stop", "start", "stop", "start"}},
+	}
+
+	for _, tc := range testCases {
+		var calls []string
+		var detections atomic.Int32
+		client := newVersionedServer(t, tc.apiVersion, &calls, &detections)
+
+		client.StopTorrents("a")
+		client.StartTorrents("a")
+		client.StopTorrent("a")
+		client.StartTorrent("a")
+
+		if len(calls) != len(tc.expected) {
+			t.Fatalf("API %s: expected calls %v, got %v", tc.apiVersion, tc.expected, calls)
+		}
+		for i := range calls {
+			if calls[i] != tc.expected[i] {
+				t.Errorf("API %s: expected calls %v, got %v", tc.apiVersion, tc.expected, calls)
+				break
+			}
+		}
+		if detections.Load() != 1 {
+			t.Errorf("API %s: capabilities should be detected once, got %d", tc.apiVersion, detections.Load())
+		}
+	}
+}
+
+func TestUnsupportedFeatures(t *testing.T) {
+	var calls []string
+	var detections atomic.Int32
+	client := newVersionedServer(t, "2.8.3", &calls, &detections)
+
+	_, err := client.ExportTorrent("a")
+	if GetErrorCode(err) != ErrorCodeVersionIncompatible || !IsPermanentError(err) {
+		t.Errorf("Expected a permanent %s, got %v", ErrorCodeVersionIncompatible, err)
+	}
+
+	err = client.SetTorrentShareLimit("a", 2, -2, 60)
+	if GetErrorCode(err) != ErrorCodeVersionIncompatible {
+		t.Errorf("Expected %s for an inactive seeding limit, got %v", ErrorCodeVersionIncompatible, err)
+	}
+
+	if len(calls) != 0 {
+		t.Errorf("No request should be sent for unsupported features, got %v", calls)
+	}
+
+	// The global default is accepted and the parameter omitted
+	if err := client.SetTorrentShareLimit("a", 2, -2, -2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calls) != 1 || calls[0] != "setShareLimits" {
+		t.Errorf("Expected setShareLimits without inactiveSeedingTimeLimit, got %v", calls)
+	}
+}
+
+func TestCapabilitiesResetOnUpdate(t *testing.T) {
+	var calls []string
+	var detections atomic.Int32
+	client := newVersionedServer(t, "2.11.0", &calls, &detections)
+
+	caps, err := client.Capabilities()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if caps.AppVersion != (Version{5, 0, 0}) {
+		t.Errorf("Expected app version 5.0.0, got %v", caps.AppVersion)
+	}
+
+	config := client.config
+	config.RequestTimeout = DefaultRequestTimeout
+	client.Update(config)
+	if _, err := client.Capabilities(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if detections.Load() != 2 {
+		t.Errorf("Expected detection to run again after Update, got %d", detections.Load())
+	}
+}
+
+func TestCapabilitiesDetectedWithoutLock(t *testing.T) {
+	var detections atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/app/webapiVersion", func(w http.ResponseWriter, r *http.Request) {
+		if detections.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		w.Write([]byte("2.11.0"))
+	})
+	client := newFakeClient(t, newFakeServer(t, mux), Config{})
+
+	detected := make(chan error, 1)
+	go func() {
+		_, err := client.Capabilities()
+		detected <- err
+	}()
+	<-started
+
+	// A reset is not held up by the slow detection
+	reset := make(chan struct{})
+	go func() {
+		client.resetCapabilities()
+		close(reset)
+	}()
+	select {
+	case <-reset:
+	case <-time.After(time.Second):
+		t.Fatal("resetCapabilities blocked while capabilities were being detected")
+	}
+
+	close(release)
+	if err := <-detected; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The detection that raced with the reset is not cached
+	if _, err := client.Capabilities(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if detections.Load() != 2 {
+		t.Errorf("Expected detection to run again after the reset, got %d", detections.Load())
+	}
+}
+
+func TestGetServerInfo(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/app/webapiVersion", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("2.9.3"))
+	})
+	mux.HandleFunc("/api/v2/app/buildInfo", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"qt":"6.4.2","libtorrent":"2.0.9.0","boost":"1.83.0","openssl":"3.1.4","zlib":"1.3","bitness":64}`))
+	})
+	mux.HandleFunc("/api/v2/app/defaultSavePath", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("/downloads"))
+	})
+
+	client := newFakeClient(t, newFakeServer(t, mux), Config{})
+	info, err := client.GetServerInfo()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if info.AppVersion != "v5.0.0" || info.APIVersion != "2.9.3" || info.DefaultSavePath != "/downloads" {
+		t.Errorf("Unexpected server info: %+v", info)
+	}
+	expected := BuildInfoResponse{Qt: "6.4.2", Libtorrent: "2.0.9.0", Boost: "1.83.0", OpenSSL: "3.1.4", Zlib: "1.3", Bitness: 64}
+	if info.BuildInfo == nil || *info.BuildInfo != expected {
+		t.Errorf("Expected build info %+v, got %+v", expected, info.BuildInfo)
+	}
+}
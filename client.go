@@ -5,12 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
-	"net/url"
-	"strings"
 	"time"
 
 	"github.com/jfxdev/go-qbt/request"
@@ -48,11 +45,25 @@ func New(config Config) (*Client, error) {
 		config.MaxLoginRetries = DefaultMaxLoginRetries
 	}
 
+	logger := newLogger(config)
+	config = resolveBaseURL(config)
+
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
 	config.jar = jar
 
 	client := &Client{
 		config:          config,
-		client:          &http.Client{Jar: jar, Timeout: config.RequestTimeout},
+		logger:          logger,
+		auth:            newAuthenticator(config),
+		rateLimiter:     newRateLimiter(config.RateLimit, config.RateBurst),
+		inFlight:        newInFlightLimiter(config.MaxInFlight),
+		breaker:         newCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		transport:       transport,
+		client:          &http.Client{Jar: jar, Timeout: config.RequestTimeout, Transport: roundTripperOrDefault(transport)},
 		MaxLoginRetries: config.MaxLoginRetries,
 		RetryDelay:      2 * time.Second,
 		cookieCache:     newCookieCache(),
@@ -63,6 +74,9 @@ func New(config Config) (*Client, error) {
 		authFailed:      false,
 	}
 
+	// Reuse a stored session, validated lazily on the first request
+	client.restoreSession()
+
 	// Start periodic cookie cleanup routine
 	go client.startCookieCleanup()
 
@@ -70,15 +84,41 @@ func New(config Config) (*Client, error) {
 }
 
 func (qb *Client) Update(config Config) {
+	logger := newLogger(config)
+	config = resolveBaseURL(config)
+
+	// An invalid TLS configuration keeps the previous transport
+	transport, err := newTransport(config)
+
 	qb.mu.Lock()
 
 	// Update runtime configuration
 	qb.config = config
+	qb.logger = logger
+	qb.auth = newAuthenticator(config)
+	qb.authBypassed.Store(false)
+	qb.rateLimiter = newRateLimiter(config.RateLimit, config.RateBurst)
+	qb.inFlight = newInFlightLimiter(config.MaxInFlight)
+	qb.breaker = newCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown)
 	if config.RequestTimeout > 0 {
 		qb.client.Timeout = config.RequestTimeout
 	}
+	previous := qb.transport
+	if err == nil {
+		qb.transport = transport
+		qb.client.Transport = roundTripperOrDefault(transport)
+	}
 	qb.mu.Unlock()
 
+	if err != nil {
+		qb.log().Error("keeping previous transport", LogKeyError, err)
+	} else if previous != nil {
+		previous.CloseIdleConnections()
+	}
+
+	// The server may have changed
+	qb.resetCapabilities()
+
 	// Invalidate cookies to force re-login
 	qb.invalidateCookies()
 }
@@ -91,7 +131,21 @@ func (qb *Client) Status() string {
 
 // checkAccessibility verifies that the qBittorrent API is accessible before attempting login.
 // This helps distinguish between infrastructure issues (502, 503) and authentication issues.
+// While the circuit breaker is open it fails fast; once the cooldown elapses this
+// check doubles as the half-open probe.
 func (qb *Client) checkAccessibility(ctx context.Context) error {
+	_, err := qb.probe(ctx)
+	return err
+}
+
+// probe performs the accessibility check and returns the status code of the
+// version endpoint. The request carries the authenticator headers but no
+// session cookie, so a 200 means this client needs no login.
+func (qb *Client) probe(ctx context.Context) (int, error) {
+	if err := qb.admitCircuit(); err != nil {
+		return 0, err
+	}
+
 	// Use the version endpoint which doesn't require authentication
 	checkCtx, cancel := context.WithTimeout(ctx, qb.config.RequestTimeout)
 	defer cancel()
@@ -99,26 +153,31 @@ func (qb *Client) checkAccessibility(ctx context.Context) error {
 	resp, err := request.Do(http.MethodGet,
 		fmt.Sprintf("%s/api/v2/app/version", qb.config.BaseURL),
 		request.WithContext(checkCtx),
+		request.WithHeaders(qb.authHeaders()),
+		request.WithTransport(qb.roundTripper()),
 	)
 	if err != nil {
 		// Classify network-level errors
 		clientErr := ClassifyError(err)
+		qb.recordCircuit(clientErr)
 		qb.setStatus(StatusUnaccessible)
 		qb.SetLastError(clientErr)
-		return clientErr
+		return 0, clientErr
 	}
 	defer resp.Body.Close()
 
 	// Check for HTTP-level errors that indicate infrastructure issues
 	if resp.StatusCode >= 500 {
 		clientErr := classifyHTTPStatusCode(resp.StatusCode, "")
+		qb.recordCircuit(clientErr)
 		qb.setStatus(StatusUnaccessible)
 		qb.SetLastError(clientErr)
-		return clientErr
+		return resp.StatusCode, clientErr
 	}
 
 	// API is accessible
-	return nil
+	qb.recordCircuit(nil)
+	return resp.StatusCode, nil
 }
 
 // Login attempts to authenticate with the qBittorrent API.
@@ -139,56 +198,22 @@ func (qb *Client) loginWithContext(ctx context.Context) error {
 	}
 
 	// First check if the API is accessible before attempting login
-	if err := qb.checkAccessibility(ctx); err != nil {
+	probeStatus, err := qb.probe(ctx)
+	if err != nil {
 		return err
 	}
 
-	data := url.Values{
-		"username": {qb.config.Username},
-		"password": {qb.config.Password},
-	}
-
-	headers := map[string]string{
-		"Content-Type": "application/x-www-form-urlencoded",
-	}
-
 	// Create a timeout context for the login request
 	loginCtx, cancel := context.WithTimeout(ctx, qb.config.RequestTimeout)
 	defer cancel()
 
-	resp, err := request.Do(http.MethodPost,
-		fmt.Sprintf("%s/api/v2/auth/login", qb.config.BaseURL),
-		request.WithBody(strings.NewReader(data.Encode())),
-		request.WithHeaders(headers),
-		request.WithCookieJar(qb.config.jar),
-		request.WithUpdateCookies(),
-		request.WithContext(loginCtx),
-	)
-	if err != nil {
-		// Classify the error
-		clientErr := ClassifyError(err)
-		qb.setStatus(StatusUnaccessible)
-		qb.SetLastError(clientErr)
-
-		// Return the classified error
-		return clientErr
-	}
-
-	defer resp.Body.Close()
-
-	// Read response body to check for "Fails." message
-	body, _ := io.ReadAll(resp.Body)
-	bodyStr := strings.TrimSpace(string(body))
+	auth := qb.authenticator()
+	err = auth.Login(loginCtx, qb.newAuthSession(probeStatus))
 
-	if resp.StatusCode != http.StatusOK {
-		clientErr := classifyHTTPStatusCode(resp.StatusCode, bodyStr)
-		qb.SetLastError(clientErr)
-		return clientErr
-	}
-
-	// Check if response contains "Fails." message even with 200 status
-	if strings.Contains(bodyStr, "Fails.") {
+	// Rejected credentials count towards the permanent lockout
+	if errors.Is(err, ErrCredentialsRejected) {
 		qb.setStatus(StatusUnauthorized)
+		qb.deleteSession()
 
 		// Increment consecutive failure counter
 		count := qb.incrementLoginFailCount()
@@ -198,31 +223,54 @@ func (qb *Client) loginWithContext(ctx context.Context) error {
 			qb.setAuthFailed(true)
 		}
 
+		var cause error
+		if err != ErrCredentialsRejected {
+			cause = err
+		}
 		clientErr := NewClientError(
 			ErrorCodeAuthFailure,
 			fmt.Sprintf("Invalid username or password (attempt %d/%d)", count, qb.config.MaxLoginRetries),
-			nil,
+			cause,
 			permanent,
 		)
 		qb.SetLastError(clientErr)
 
-		if qb.config.Debug {
-			log.Printf("Login auth failure %d/%d, permanent=%v", count, qb.config.MaxLoginRetries, permanent)
+		qb.log().Warn("login rejected",
+			slog.String(LogKeyOperation, "login"),
+			slog.Int(LogKeyAttempt, count),
+			slog.Int(LogKeyAttempts, qb.config.MaxLoginRetries),
+			slog.Bool("permanent", permanent),
+			slog.String(LogKeyErrorCode, string(clientErr.Code)),
+		)
+		return clientErr
+	}
+
+	if err != nil {
+		// HTTP-level failures are already classified; anything else is a
+		// transport error
+		var clientErr *ClientError
+		if !errors.As(err, &clientErr) {
+			clientErr = ClassifyError(err)
+			qb.setStatus(StatusUnaccessible)
 		}
+		qb.SetLastError(clientErr)
 		return clientErr
 	}
 
-	// Update cookie cache and mark as valid
-	qb.updateCookieCache(resp.Cookies())
+	// Mark the session as valid
+	loginTime := time.Now()
 	qb.setCookieValid(true)
-	qb.lastLoginTime = time.Now()
+	qb.setLastLoginTime(loginTime)
+	qb.saveSession(loginTime)
 	qb.setStatus(StatusConnected)
 	qb.SetLastError(nil) // Clear any previous error
 	qb.resetLoginFailCount()
 
-	if qb.config.Debug {
-		log.Println("Login successful, cookies cached")
+	if qb.AuthBypassed() {
+		qb.log().Info("authentication bypassed by server, no login needed", slog.String(LogKeyOperation, "login"))
+		return nil
 	}
+	qb.log().Info("login succeeded, cookies cached", slog.String(LogKeyOperation, "login"))
 	return nil
 }
 
@@ -232,6 +280,14 @@ func (qb *Client) ensureLoginWithContext(ctx context.Context) error {
 		return nil
 	}
 
+	// A session restored from the SessionStore is validated once before
+	// falling back to a fresh login
+	if qb.sessionRestored.Swap(false) && qb.isCookieValid() {
+		qb.setStatus(StatusConnected)
+		qb.log().Info("reusing stored session", slog.String(LogKeyOperation, "login"))
+		return nil
+	}
+
 	// Try login with smart retry and context
 	return qb.retryWithBackoffWithContext(ctx, func() error {
 		return qb.loginWithContext(ctx)
@@ -257,6 +313,8 @@ func (qb *Client) isCookieValid() bool {
 	resp, err := request.Do(http.MethodGet,
 		fmt.Sprintf("%s/api/v2/app/version", qb.config.BaseURL),
 		request.WithCookieJar(qb.config.jar),
+		request.WithHeaders(qb.authHeaders()),
+		request.WithTransport(qb.roundTripper()),
 		request.WithContext(ctx),
 	)
 
@@ -345,9 +403,8 @@ func (qb *Client) RefreshConnectionStatus(ctx context.Context) *ConnectionStatus
 	defer cancel()
 
 	if err := qb.loginWithContext(refreshCtx); err != nil {
-		if qb.config.Debug {
-			log.Printf("RefreshConnectionStatus: login failed: %v", err)
-		}
+		qb.log().Warn("connection status refresh failed",
+			append([]any{slog.String(LogKeyOperation, "refresh_connection_status")}, errorAttrs(err)...)...)
 		// Status and lastError were already updated by loginWithContext
 		return qb.GetConnectionStatus()
 	}
@@ -358,7 +415,8 @@ func (qb *Client) RefreshConnectionStatus(ctx context.Context) *ConnectionStatus
 // GetConnectionStatus returns the detailed connection status
 func (qb *Client) GetConnectionStatus() *ConnectionStatus {
 	status := &ConnectionStatus{
-		Status: qb.GetStatus(),
+		Status:  qb.GetStatus(),
+		Circuit: qb.circuitState(),
 	}
 
 	if lastErr := qb.GetLastError(); lastErr != nil {
@@ -408,9 +466,17 @@ func (qb *Client) resetLoginFailCount() {
 }
 
 func (qb *Client) isCookieExpired() bool {
+	qb.cookieValidMu.RLock()
+	defer qb.cookieValidMu.RUnlock()
 	return time.Since(qb.lastLoginTime) > CookieExpiryDuration
 }
 
+func (qb *Client) setLastLoginTime(t time.Time) {
+	qb.cookieValidMu.Lock()
+	defer qb.cookieValidMu.Unlock()
+	qb.lastLoginTime = t
+}
+
 func (qb *Client) invalidateCookies() {
 	qb.setCookieValid(false)
 	qb.cookieCache.clear()
@@ -423,8 +489,8 @@ func (qb *Client) invalidateCookies() {
 		qb.config.jar = jar
 		qb.client.Jar = jar
 		qb.mu.Unlock()
-	} else if qb.config.Debug {
-		log.Printf("failed to recreate cookie jar: %v", err)
+	} else {
+		qb.log().Error("failed to recreate cookie jar", errorAttrs(err)...)
 	}
 }
 
@@ -437,20 +503,22 @@ func (qb *Client) retryWithBackoff(operation func() error, operationName string)
 
 	for attempt := 0; attempt <= qb.retryConfig.MaxRetries; attempt++ {
 		if err := operation(); err == nil {
-			if attempt > 0 && qb.config.Debug {
-				log.Printf("%s succeeded after %d retries", operationName, attempt)
+			if attempt > 0 {
+				qb.logRetrySucceeded(operationName, attempt)
 			}
 			return nil
 		} else {
 			lastErr = err
+
+			// An open circuit means the instance is down - fail fast
+			if isCircuitOpenError(err) {
+				return err
+			}
 		}
 
 		if attempt < qb.retryConfig.MaxRetries {
-			delay := qb.calculateBackoffDelay(attempt)
-			if qb.config.Debug {
-				log.Printf("%s failed (attempt %d/%d), retrying in %v: %v",
-					operationName, attempt+1, qb.retryConfig.MaxRetries+1, delay, lastErr)
-			}
+			delay := qb.retryDelay(attempt, lastErr)
+			qb.logRetry(operationName, attempt, delay, lastErr)
 			time.Sleep(delay)
 		}
 	}
@@ -481,28 +549,32 @@ func (qb *Client) retryWithBackoffWithContext(ctx context.Context, operation fun
 		}
 
 		if err := operation(); err == nil {
-			if attempt > 0 && qb.config.Debug {
-				log.Printf("%s succeeded after %d retries", operationName, attempt)
+			if attempt > 0 {
+				qb.logRetrySucceeded(operationName, attempt)
 			}
 			return nil
 		} else {
 			lastErr = err
 
+			// An open circuit means the instance is down - fail fast
+			if isCircuitOpenError(err) {
+				return err
+			}
+
 			// Check if this is a permanent error - don't retry
 			if IsPermanentError(err) {
-				if qb.config.Debug {
-					log.Printf("%s failed with permanent error, not retrying: %v", operationName, err)
-				}
+				qb.log().Error("operation failed with permanent error, not retrying",
+					append([]any{
+						slog.String(LogKeyOperation, operationName),
+						slog.Int(LogKeyAttempt, attempt+1),
+					}, errorAttrs(err)...)...)
 				return err
 			}
 		}
 
 		if attempt < qb.retryConfig.MaxRetries {
-			delay := qb.calculateBackoffDelay(attempt)
-			if qb.config.Debug {
-				log.Printf("%s failed (attempt %d/%d), retrying in %v: %v",
-					operationName, attempt+1, qb.retryConfig.MaxRetries+1, delay, lastErr)
-			}
+			delay := qb.retryDelay(attempt, lastErr)
+			qb.logRetry(operationName, attempt, delay, lastErr)
 
 			// Use context-aware sleep
 			select {
@@ -519,15 +591,26 @@ func (qb *Client) retryWithBackoffWithContext(ctx context.Context, operation fun
 }
 
 func (qb *Client) calculateBackoffDelay(attempt int) time.Duration {
-	delay := qb.retryConfig.BaseDelay
-	for i := 0; i < attempt; i++ {
-		delay = time.Duration(float64(delay) * qb.retryConfig.BackoffFactor)
-		if delay > qb.retryConfig.MaxDelay {
-			delay = qb.retryConfig.MaxDelay
-			break
-		}
-	}
-	return delay
+	return qb.retryConfig.Delay(attempt)
+}
+
+// logRetry records a failed attempt that is about to be retried after delay.
+func (qb *Client) logRetry(operationName string, attempt int, delay time.Duration, err error) {
+	qb.log().Warn("operation failed, retrying",
+		append([]any{
+			slog.String(LogKeyOperation, operationName),
+			slog.Int(LogKeyAttempt, attempt+1),
+			slog.Int(LogKeyAttempts, qb.retryConfig.MaxRetries+1),
+			slog.Duration(LogKeyDelay, delay),
+		}, errorAttrs(err)...)...)
+}
+
+// logRetrySucceeded records an operation that recovered after retries.
+func (qb *Client) logRetrySucceeded(operationName string, retries int) {
+	qb.log().Info("operation succeeded after retries",
+		slog.String(LogKeyOperation, operationName),
+		slog.Int(LogKeyAttempt, retries+1),
+	)
 }
 
 func (qb *Client) startCookieCleanup() {
@@ -543,49 +626,35 @@ func (qb *Client) startCookieCleanup() {
 			// will trigger re-authentication via ensureLoginWithContext.
 			// Setting StatusUnauthorized here caused stale "auth failed" reports
 			// when the health endpoint read cached status.
-			if qb.config.Debug {
-				log.Println("Cookies expired, cleared from cache — next request will re-authenticate")
-			}
+			qb.log().Debug("cookies expired, cleared from cache; next request will re-authenticate",
+				slog.String(LogKeyOperation, "cookie_cleanup"))
 		}
 	}
 }
 
+// Close logs out, unless a SessionStore is configured: the stored session is
+// then kept for the next client and only the cached cookies are dropped. Use
+// Logout to end a stored session.
 func (qb *Client) Close() error {
 	// If client is not fully configured, just clear cache
-	if qb.config.BaseURL == "" || qb.config.jar == nil {
+	if qb.config.BaseURL == "" || qb.config.jar == nil || qb.config.SessionStore != nil {
 		qb.invalidateCookies()
 		return nil
 	}
+	return qb.Logout()
+}
 
-	headers := map[string]string{
-		"Content-Type": "application/x-www-form-urlencoded",
-	}
-
+// Logout ends the session on the server and deletes it from the SessionStore.
+func (qb *Client) Logout() error {
 	ctx, cancel := context.WithTimeout(context.Background(), qb.config.RequestTimeout)
 	defer cancel()
 
-	resp, err := request.Do(http.MethodPost,
-		fmt.Sprintf("%s/api/v2/auth/logout", qb.config.BaseURL),
-		request.WithCookieJar(qb.config.jar),
-		request.WithHeaders(headers),
-		request.WithContext(ctx),
-	)
-	if err != nil {
-		// Even on error, ensure local cache is cleared
-		qb.invalidateCookies()
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		qb.invalidateCookies()
-		return fmt.Errorf("logout failed. Status: %d, Response: %s", resp.StatusCode, body)
-	}
+	err := qb.authenticator().Logout(ctx, qb.newAuthSession(0))
 
-	// Clear cookie cache on logout
+	// Clear cookie cache on logout, even when it failed
+	qb.deleteSession()
 	qb.invalidateCookies()
-	return nil
+	return err
 }
 
 // Helpers for cookie cache
@@ -607,6 +676,18 @@ func (cc *CookieCache) update(cookies []*http.Cookie) {
 	cc.lastUsed = time.Now()
 }
 
+// snapshot returns the cached cookies.
+func (cc *CookieCache) snapshot() []*http.Cookie {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	cookies := make([]*http.Cookie, 0, len(cc.cookies))
+	for _, cookie := range cc.cookies {
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
 func (cc *CookieCache) clear() {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
@@ -616,6 +697,26 @@ func (cc *CookieCache) clear() {
 	cc.lastUsed = time.Now()
 }
 
+// DefaultRetryConfig returns the retry settings used by a client created
+// with a zero Config.
+func DefaultRetryConfig() *RetryConfig {
+	return newRetryConfig(Config{MaxRetries: DefaultMaxRetries, RetryBackoff: DefaultRetryBackoff})
+}
+
+// Delay returns the backoff before retry attempt+1: BaseDelay multiplied by
+// BackoffFactor for each previous attempt, capped at MaxDelay.
+func (rc *RetryConfig) Delay(attempt int) time.Duration {
+	delay := rc.BaseDelay
+	for i := 0; i < attempt; i++ {
+		delay = time.Duration(float64(delay) * rc.BackoffFactor)
+		if rc.MaxDelay > 0 && delay > rc.MaxDelay {
+			delay = rc.MaxDelay
+			break
+		}
+	}
+	return delay
+}
+
 func newRetryConfig(config Config) *RetryConfig {
 	return &RetryConfig{
 		MaxRetries:     config.MaxRetries,
@@ -2,8 +2,11 @@ package qbt
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -547,7 +550,7 @@ func TestAlternativeRateLimits(t *testing.T) {
 	err = client.SetAlternativeRateLimits(downloadLimit, uploadLimit)
 	if err != nil {
 		// Expected to fail without real qBittorrent instance
-		if !strings.Contains(err.Error(), "failed to get global settings") {
+		if !strings.Contains(err.Error(), "failed to get preferences") {
 			t.Errorf("Unexpected error: %v", err)
 		}
 	}
@@ -573,12 +576,93 @@ func TestAlternativeRateLimitsWithZeroValues(t *testing.T) {
 	err = client.SetAlternativeRateLimits(downloadLimit, uploadLimit)
 	if err != nil {
 		// Expected to fail without real qBittorrent instance
-		if !strings.Contains(err.Error(), "failed to get global settings") {
+		if !strings.Contains(err.Error(), "failed to get preferences") {
 			t.Errorf("Unexpected error: %v", err)
 		}
 	}
 }
 
+func TestSpeedLimitsMode(t *testing.T) {
+	alternative := false
+	toggles := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/transfer/speedLimitsMode", func(w http.ResponseWriter, r *http.Request) {
+		if alternative {
+			w.Write([]byte("1"))
+		} else {
+			w.Write([]byte("0"))
+		}
+	})
+	mux.HandleFunc("/api/v2/transfer/toggleSpeedLimitsMode", func(w http.ResponseWriter, r *http.Request) {
+		toggles++
+		alternative = !alternative
+	})
+	client := newFakeClient(t, newFakeServer(t, mux), Config{})
+
+	for _, mode := range []bool{true, true, false, false} {
+		if err := client.SetSpeedLimitsMode(mode); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got, err := client.GetSpeedLimitsMode(); err != nil || got != mode {
+			t.Errorf("Expected mode %v, got %v (%v)", mode, got, err)
+		}
+	}
+	if toggles != 2 {
+		t.Errorf("Expected 2 toggles, got %d", toggles)
+	}
+}
+
+func TestAlternativeRateLimitsIdempotent(t *testing.T) {
+	prefs := map[string]interface{}{"alt_dl_limit": 1024, "alt_up_limit": 1024}
+	writes := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/app/preferences", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(prefs)
+	})
+	mux.HandleFunc("/api/v2/app/setPreferences", func(w http.ResponseWriter, r *http.Request) {
+		writes++
+		var updates map[string]float64
+		json.Unmarshal([]byte(r.FormValue("json")), &updates)
+		// Like qBittorrent, store whole KiB/s, and clamp the upload limit
+		for key, value := range updates {
+			prefs[key] = NormalizeSpeedLimit(int(value))
+		}
+		prefs["alt_up_limit"] = min(prefs["alt_up_limit"].(int), 1024)
+	})
+	client := newFakeClient(t, newFakeServer(t, mux), Config{})
+
+	if err := client.SetAlternativeRateLimits(1024, 512); err != nil || writes != 0 {
+		t.Errorf("Expected no write for unchanged limits, got %d writes (%v)", writes, err)
+	}
+	if err := client.SetAlternativeRateLimits(2048, 512); err != nil || writes != 1 {
+		t.Errorf("Expected one write, got %d writes (%v)", writes, err)
+	}
+	err := client.SetAlternativeRateLimits(2048, 4096)
+	if err == nil || !strings.Contains(err.Error(), "effective limits are 2048/1024") {
+		t.Errorf("Expected the effective limits to be reported, got %v", err)
+	}
+	if download, upload, err := client.GetAlternativeRateLimits(); err != nil || download != 2048 || upload != 1024 {
+		t.Errorf("Expected the effective limits 2048/1024, got %d/%d (%v)", download, upload, err)
+	}
+
+	// Limits the server rounds are idempotent too
+	writes = 0
+	for i := 0; i < 2; i++ {
+		if err := client.SetAlternativeRateLimits(1500, -1); err != nil || writes != 1 {
+			t.Errorf("Expected one write for rounded limits, got %d writes (%v)", writes, err)
+		}
+	}
+}
+
+func TestNormalizeSpeedLimit(t *testing.T) {
+	testCases := map[int]int{-1: 0, 0: 0, 1: 1024, 1024: 1024, 1500: 1024, 2048: 2048, 3000: 2048}
+	for limit, expected := range testCases {
+		if got := NormalizeSpeedLimit(limit); got != expected {
+			t.Errorf("NormalizeSpeedLimit(%d): expected %d, got %d", limit, expected, got)
+		}
+	}
+}
+
 func TestMaxActiveTorrentLimitsWithZeroValues(t *testing.T) {
 	client, err := New(Config{
 		BaseURL:        "http://localhost:8080",
@@ -630,3 +714,115 @@ func TestMaxActiveTorrentLimitsWithNegativeValues(t *testing.T) {
 		}
 	}
 }
+
+func TestListRSSItems(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/rss/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"top": {"uid": "1", "url": "https://example.com/top.rss"},
+			"TV": {
+				"daily": {"uid": "2", "url": "https://example.com/daily.rss"},
+				"Anime": {"weekly": {"uid": "3", "url": "https://example.com/weekly.rss"}}
+			},
+			"Empty": {}
+		}`))
+	})
+	client := newFakeClient(t, newFakeServer(t, mux), Config{})
+
+	feeds, folders, err := client.ListRSSItems()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"top":             "https://example.com/top.rss",
+		`TV\daily`:        "https://example.com/daily.rss",
+		`TV\Anime\weekly`: "https://example.com/weekly.rss",
+	}
+	if len(feeds) != len(expected) {
+		t.Errorf("Expected %d feeds, got %v", len(expected), feeds)
+	}
+	for path, url := range expected {
+		if feeds[path].URL != url {
+			t.Errorf("Expected %s at %s, got %+v", url, path, feeds[path])
+		}
+	}
+	if fmt.Sprint(folders) != `[Empty TV TV\Anime]` {
+		t.Errorf("Unexpected folders: %v", folders)
+	}
+}
+
+func TestTorrentTagsEncoding(t *testing.T) {
+	var bodies []string
+	mux := http.NewServeMux()
+	for _, endpoint := range []string{"addTags", "removeTags"} {
+		mux.HandleFunc("/api/v2/torrents/"+endpoint, func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+		})
+	}
+	client := newFakeClient(t, newFakeServer(t, mux), Config{})
+
+	if err := client.AddTorrentTags("abc", []string{"a", "b c"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := client.DeleteTorrentTags("abc", []string{"a", "b c"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Every tag in one comma-separated parameter, not repeated values
+	expected := "hashes=abc&tags=a%2Cb+c"
+	if len(bodies) != 2 || bodies[0] != expected || bodies[1] != expected {
+		t.Errorf("Expected bodies %q, got %q", expected, bodies)
+	}
+}
+
+func TestAddTorrentStopped(t *testing.T) {
+	var forms []url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/torrents/add", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseMultipartForm(1 << 20)
+		forms = append(forms, r.Form)
+	})
+	client := newFakeClient(t, newFakeServer(t, mux), Config{})
+
+	if err := client.AddTorrentLink(TorrentConfig{MagnetURI: "magnet:?xt=urn:btih:abc", Paused: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	manual := false
+	if err := client.AddTorrentFile("a.torrent", []byte("d4:infodee"), TorrentConfig{Paused: true, ContentLayout: ContentLayoutOriginal, AutoTMM: &manual}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// qBittorrent 5 reads "stopped", older versions "paused"
+	for i, form := range forms {
+		if form.Get("stopped") != "true" || form.Get("paused") != "true" {
+			t.Errorf("Request %d: expected stopped and paused to be set, got %v", i, form)
+		}
+	}
+	if len(forms) != 2 {
+		t.Fatalf("Expected 2 add requests, got %d", len(forms))
+	}
+	if _, ok := forms[0]["contentLayout"]; ok || forms[0].Get("autoTMM") != "" {
+		t.Errorf("Unset layout and management should be left to the server, got %v", forms[0])
+	}
+	if forms[1].Get("contentLayout") != "Original" || forms[1].Get("autoTMM") != "false" {
+		t.Errorf("Expected contentLayout and autoTMM to be sent, got %v", forms[1])
+	}
+}
+
+func TestListTorrentsInactiveSeedingDefault(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/torrents/info", func(w http.ResponseWriter, r *http.Request) {
+		// qBittorrent 4.5 has no inactive_seeding_time_limit
+		w.Write([]byte(`[{"hash":"old","magnet_uri":"magnet:?xt=urn:btih:old"},{"hash":"new","magnet_uri":"magnet:?xt=urn:btih:new","inactive_seeding_time_limit":0}]`))
+	})
+	client := newFakeClient(t, newFakeServer(t, mux), Config{})
+
+	torrents, err := client.ListTorrents(ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(torrents) != 2 || torrents[0].InactiveSeedingTimeLimit != -2 || torrents[1].InactiveSeedingTimeLimit != 0 {
+		t.Errorf("Expected -2 only when the field is missing, got %+v", torrents)
+	}
+}
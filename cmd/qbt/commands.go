@@ -0,0 +1,539 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// command is a qbt subcommand.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(a *app, args []string) error
+}
+
+var commands = []*command{
+	{"list", "list [-filter state] [-category name] [-tag name] [-sort field] [-reverse] [-limit n] [hash...]", "List torrents", runList},
+	{"add", "add [-category name] [-savepath dir] [-tags a,b] [-paused] [-skip-checking] <magnet|url|file>...", "Add torrents from magnet links, URLs or .torrent files", runAdd},
+	{"start", "start <hash>...|all", "Start torrents", runStart},
+	{"stop", "stop <hash>...|all", "Stop torrents", runStop},
+	{"delete", "delete [-files] <hash>...", "Delete torrents, optionally with their data", runDelete},
+	{"tag", "tag [-remove] <hash> <tag>...", "Add or remove torrent tags", runTag},
+	{"category", "category <hash> [name]", "Set a torrent category, or clear it when no name is given", runCategory},
+	{"files", "files <hash>", "List the files of a torrent", runFiles},
+	{"trackers", "trackers <hash>", "List the trackers of a torrent", runTrackers},
+	{"peers", "peers <hash>", "List the peers of a torrent", runPeers},
+	{"prefs", "prefs get [key...] | prefs set key=value...", "Show or change preferences", runPrefs},
+	{"logs", "logs [-follow] [-interval 2s] [-last-id n]", "Show the qBittorrent log", runLogs},
+	{"transfer", "transfer", "Show global transfer statistics", runTransfer},
+	{"status", "status", "Show the connection status", runStatus},
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// newFlagSet creates a flag set for the running command that reports errors
+// instead of exiting.
+func (a *app) newFlagSet() *flag.FlagSet {
+	flags := flag.NewFlagSet(a.cmd.name, flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	flags.Usage = func() {
+		a.usage()
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// usage prints the usage line of the running command.
+func (a *app) usage() {
+	fmt.Fprintf(a.stderr, "Usage: qbt %s\n", a.cmd.usage)
+}
+
+// requireArgs fails when fewer than n positional arguments were given.
+func requireArgs(flags *flag.FlagSet, n int) error {
+	if flags.NArg() < n {
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+var errUsage = errors.New("invalid usage")
+
+func runList(a *app, args []string) error {
+	flags := a.newFlagSet()
+	var opts qbt.ListOptions
+	flags.StringVar(&opts.Filter, "filter", "", "state filter (all, downloading, seeding, completed, stopped, active, inactive, stalled, errored)")
+	flags.StringVar(&opts.Category, "category", "", "only torrents in this category")
+	flags.StringVar(&opts.Tag, "tag", "", "only torrents with this tag")
+	flags.StringVar(&opts.Sort, "sort", "", "sort by field (e.g. name, added_on, ratio)")
+	flags.BoolVar(&opts.Reverse, "reverse", false, "reverse the sort order")
+	flags.IntVar(&opts.Limit, "limit", 0, "max number of torrents")
+	flags.IntVar(&opts.Offset, "offset", 0, "number of torrents to skip")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	opts.Hashes = flags.Args()
+
+	torrents, err := a.client.ListTorrents(opts)
+	if err != nil {
+		return err
+	}
+
+	return a.printer.print(torrents, table{
+		headers: []string{"HASH", "NAME", "STATE", "PROGRESS", "SIZE", "RATIO", "CATEGORY", "TAGS"},
+		rows: func(human bool) [][]string {
+			rows := make([][]string, 0, len(torrents))
+			for _, t := range torrents {
+				rows = append(rows, []string{
+					t.Hash, t.Name, t.State,
+					formatPercent(t.Progress, human),
+					formatBytes(int64(t.Size), human),
+					fmt.Sprintf("%.2f", t.Ratio),
+					t.Category, t.Tags,
+				})
+			}
+			return rows
+		},
+	})
+}
+
+func runAdd(a *app, args []string) error {
+	flags := a.newFlagSet()
+	var opts qbt.TorrentConfig
+	var tags string
+	flags.StringVar(&opts.Category, "category", "", "category to assign")
+	flags.StringVar(&opts.Directory, "savepath", "", "download directory")
+	flags.StringVar(&tags, "tags", "", "comma-separated tags to assign")
+	flags.BoolVar(&opts.Paused, "paused", false, "add in stopped state")
+	flags.BoolVar(&opts.SkipChecking, "skip-checking", false, "skip hash checking")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := requireArgs(flags, 1); err != nil {
+		return err
+	}
+	if tags != "" {
+		opts.Tags = strings.Split(tags, ",")
+	}
+
+	for _, source := range flags.Args() {
+		if isLink(source) {
+			opts.MagnetURI = source
+			if err := a.client.AddTorrentLink(opts); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return fmt.Errorf("failed to read torrent file: %w", err)
+		}
+		if err := a.client.AddTorrentFile(source, data, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isLink reports whether source is a magnet link or URL rather than a local file.
+func isLink(source string) bool {
+	for _, prefix := range []string{"magnet:", "http://", "https://", "bc://bt/"} {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinHashes builds the pipe-separated hash list used by bulk endpoints.
+func joinHashes(hashes []string) string {
+	return strings.Join(hashes, "|")
+}
+
+func runStart(a *app, args []string) error {
+	flags := a.newFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := requireArgs(flags, 1); err != nil {
+		return err
+	}
+	return a.client.StartTorrents(joinHashes(flags.Args()))
+}
+
+func runStop(a *app, args []string) error {
+	flags := a.newFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := requireArgs(flags, 1); err != nil {
+		return err
+	}
+	return a.client.StopTorrents(joinHashes(flags.Args()))
+}
+
+func runDelete(a *app, args []string) error {
+	flags := a.newFlagSet()
+	deleteFiles := flags.Bool("files", false, "also delete downloaded data")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := requireArgs(flags, 1); err != nil {
+		return err
+	}
+	return a.client.DeleteTorrents(joinHashes(flags.Args()), *deleteFiles)
+}
+
+func runTag(a *app, args []string) error {
+	flags := a.newFlagSet()
+	remove := flags.Bool("remove", false, "remove the tags instead of adding them")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := requireArgs(flags, 2); err != nil {
+		return err
+	}
+
+	hash, tags := flags.Arg(0), flags.Args()[1:]
+	if *remove {
+		return a.client.DeleteTorrentTags(hash, tags)
+	}
+	return a.client.AddTorrentTags(hash, tags)
+}
+
+func runCategory(a *app, args []string) error {
+	flags := a.newFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := requireArgs(flags, 1); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 1 {
+		return a.client.RemoveCategory(flags.Arg(0))
+	}
+	return a.client.SetCategory(flags.Arg(0), flags.Arg(1))
+}
+
+func runFiles(a *app, args []string) error {
+	flags := a.newFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := requireArgs(flags, 1); err != nil {
+		return err
+	}
+
+	files, err := a.client.ListTorrentFiles(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return a.printer.print(files, table{
+		headers: []string{"NAME", "SIZE", "PROGRESS", "PRIORITY"},
+		rows: func(human bool) [][]string {
+			rows := make([][]string, 0, len(files))
+			for _, f := range files {
+				rows = append(rows, []string{
+					f.Name,
+					formatBytes(f.Size, human),
+					formatPercent(f.Progress, human),
+					fmt.Sprintf("%d", f.Priority),
+				})
+			}
+			return rows
+		},
+	})
+}
+
+func runTrackers(a *app, args []string) error {
+	flags := a.newFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := requireArgs(flags, 1); err != nil {
+		return err
+	}
+
+	trackers, err := a.client.GetTorrentTrackers(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return a.printer.print(trackers, table{
+		headers: []string{"URL", "STATUS", "TIER", "SEEDS", "PEERS", "MESSAGE"},
+		rows: func(human bool) [][]string {
+			rows := make([][]string, 0, len(trackers))
+			for _, t := range trackers {
+				rows = append(rows, []string{
+					t.URL,
+					fmt.Sprintf("%d", t.Status),
+					fmt.Sprintf("%d", t.Tier),
+					fmt.Sprintf("%d", t.NumSeeds),
+					fmt.Sprintf("%d", t.NumPeers),
+					t.Msg,
+				})
+			}
+			return rows
+		},
+	})
+}
+
+func runPeers(a *app, args []string) error {
+	flags := a.newFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := requireArgs(flags, 1); err != nil {
+		return err
+	}
+
+	peers, err := a.client.GetTorrentPeers(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return a.printer.print(peers, table{
+		headers: []string{"IP", "PORT", "CLIENT", "PROGRESS", "DOWN", "UP", "COUNTRY"},
+		rows: func(human bool) [][]string {
+			rows := make([][]string, 0, len(peers))
+			for _, p := range peers {
+				rows = append(rows, []string{
+					p.IP,
+					fmt.Sprintf("%d", p.Port),
+					p.Client,
+					formatPercent(p.Progress, human),
+					formatSpeed(int64(p.DownloadSpeed), human),
+					formatSpeed(int64(p.UploadSpeed), human),
+					p.CountryCode,
+				})
+			}
+			return rows
+		},
+	})
+}
+
+func runPrefs(a *app, args []string) error {
+	if len(args) == 0 {
+		a.usage()
+		return errUsage
+	}
+
+	switch args[0] {
+	case "get":
+		return runPrefsGet(a, args[1:])
+	case "set":
+		return runPrefsSet(a, args[1:])
+	default:
+		a.usage()
+		return errUsage
+	}
+}
+
+func runPrefsGet(a *app, keys []string) error {
+	prefs, err := a.client.GetPreferences()
+	if err != nil {
+		return err
+	}
+
+	if len(keys) > 0 {
+		selected := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			value, ok := prefs[key]
+			if !ok {
+				return fmt.Errorf("unknown preference %q", key)
+			}
+			selected[key] = value
+		}
+		prefs = selected
+	}
+
+	names := make([]string, 0, len(prefs))
+	for name := range prefs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return a.printer.print(prefs, table{
+		headers: []string{"KEY", "VALUE"},
+		rows: func(human bool) [][]string {
+			rows := make([][]string, 0, len(names))
+			for _, name := range names {
+				value, _ := json.Marshal(prefs[name])
+				rows = append(rows, []string{name, string(value)})
+			}
+			return rows
+		},
+	})
+}
+
+func runPrefsSet(a *app, pairs []string) error {
+	if len(pairs) == 0 {
+		a.usage()
+		return errUsage
+	}
+
+	prefs, err := parseAssignments(pairs)
+	if err != nil {
+		return err
+	}
+	return a.client.SetPreferences(prefs)
+}
+
+// parseAssignments parses key=value pairs. Values that are valid JSON (numbers,
+// booleans, objects) keep their type; anything else is sent as a string.
+func parseAssignments(pairs []string) (map[string]interface{}, error) {
+	prefs := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		key, raw, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid assignment %q (want key=value)", pair)
+		}
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		prefs[key] = value
+	}
+	return prefs, nil
+}
+
+// logTypes maps qBittorrent log type bits to labels.
+var logTypes = map[int]string{1: "NORMAL", 2: "INFO", 4: "WARNING", 8: "CRITICAL"}
+
+func runLogs(a *app, args []string) error {
+	flags := a.newFlagSet()
+	follow := flags.Bool("follow", false, "keep polling for new entries")
+	interval := flags.Duration("interval", 2*time.Second, "poll interval with -follow")
+	lastID := flags.Int("last-id", -1, "only show entries after this ID")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	for {
+		entries, err := a.client.GetLogs(true, true, true, true, *lastID)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			*lastID = entries[len(entries)-1].ID
+			if err := a.printLogs(entries); err != nil {
+				return err
+			}
+		}
+
+		if !*follow {
+			return nil
+		}
+
+		select {
+		case <-a.ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// printLogs prints log entries; with -output json each entry is one JSON line
+// so that followed output can be streamed.
+func (a *app) printLogs(entries []*qbt.LogEntry) error {
+	if a.printer.format == formatJSON {
+		encoder := json.NewEncoder(a.printer.out)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return a.printer.print(entries, table{
+		headers: []string{"ID", "TIME", "TYPE", "MESSAGE"},
+		rows: func(human bool) [][]string {
+			rows := make([][]string, 0, len(entries))
+			for _, e := range entries {
+				timestamp := fmt.Sprintf("%d", e.Timestamp)
+				if human {
+					timestamp = time.Unix(e.Timestamp, 0).Format(time.DateTime)
+				}
+				rows = append(rows, []string{fmt.Sprintf("%d", e.ID), timestamp, logTypes[e.Type], e.Message})
+			}
+			return rows
+		},
+	})
+}
+
+func runTransfer(a *app, args []string) error {
+	info, err := a.client.GetTransferInfo()
+	if err != nil {
+		return err
+	}
+
+	return a.printer.print(info, table{
+		headers: []string{"DOWN", "UP", "DOWNLOADED", "UPLOADED", "DHT NODES", "CONNECTION"},
+		rows: func(human bool) [][]string {
+			return [][]string{{
+				formatSpeed(int64(info.DlInfoSpeed), human),
+				formatSpeed(int64(info.UpInfoSpeed), human),
+				formatBytes(int64(info.DlInfoData), human),
+				formatBytes(int64(info.UpInfoData), human),
+				fmt.Sprintf("%d", info.DhtNodes),
+				info.ConnectionStatus,
+			}}
+		},
+	})
+}
+
+func runStatus(a *app, args []string) error {
+	status := a.client.RefreshConnectionStatus(a.ctx)
+
+	if err := a.printer.print(status, table{
+		headers: []string{"STATUS", "ERROR", "MESSAGE"},
+		rows: func(human bool) [][]string {
+			return [][]string{{status.Status, string(status.ErrorCode), status.Message}}
+		},
+	}); err != nil {
+		return err
+	}
+
+	if status.Status != qbt.StatusConnected {
+		return errSilent
+	}
+	return nil
+}
+
+// errSilent signals failure through the exit code without printing an error.
+var errSilent = errors.New("")
+
+// writeUsage prints the top-level help.
+func writeUsage(w io.Writer, global *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: qbt [global flags] <command> [flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nGlobal flags:\n")
+	global.SetOutput(w)
+	global.PrintDefaults()
+	fmt.Fprintf(w, "\nEnvironment: %s, %s, %s, %s, %s, %s\n",
+		envConfig, envProfile, envURL, envUsername, envPassword, envTimeout)
+}
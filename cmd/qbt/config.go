@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// Environment variables read by the CLI
+const (
+	envConfig   = "QBT_CONFIG"
+	envProfile  = "QBT_PROFILE"
+	envURL      = "QBT_URL"
+	envUsername = "QBT_USERNAME"
+	envPassword = "QBT_PASSWORD"
+	envTimeout  = "QBT_TIMEOUT"
+)
+
+const defaultProfileName = "default"
+
+// fileConfig is the on-disk configuration with named profiles.
+//
+//	{
+//	  "default_profile": "home",
+//	  "profiles": {
+//	    "home": {"url": "http://localhost:8080", "username": "admin", "password": "secret"},
+//	    "seedbox": {"url": "https://seedbox.example.com", "username": "ops", "timeout": "1m"}
+//	  }
+//	}
+type fileConfig struct {
+	DefaultProfile string             `json:"default_profile"`
+	Profiles       map[string]profile `json:"profiles"`
+}
+
+// profile holds the connection settings of one qBittorrent instance.
+type profile struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Timeout  string `json:"timeout"` // Go duration, e.g. "30s"
+}
+
+// globalOptions are the flags accepted before the command name.
+type globalOptions struct {
+	configPath string
+	profile    string
+	url        string
+	username   string
+	password   string
+	timeout    string
+	output     string
+}
+
+// defaultConfigPath returns the per-user config file location.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "qbt", "config.json")
+}
+
+// loadFileConfig reads the config file. A missing file is only an error when
+// its path was given explicitly.
+func loadFileConfig(path string, explicit bool) (*fileConfig, error) {
+	if path == "" {
+		return &fileConfig{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !explicit {
+			return &fileConfig{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config fileConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// resolveProfile merges the config file profile, environment variables and
+// flags, in increasing order of precedence.
+func resolveProfile(opts globalOptions, getenv func(string) string) (profile, error) {
+	path, explicit := opts.configPath, opts.configPath != ""
+	if path == "" {
+		path, explicit = getenv(envConfig), getenv(envConfig) != ""
+	}
+	if path == "" {
+		path = defaultConfigPath()
+	}
+
+	config, err := loadFileConfig(path, explicit)
+	if err != nil {
+		return profile{}, err
+	}
+
+	name := firstNonEmpty(opts.profile, getenv(envProfile))
+	requested := name != ""
+	if name == "" {
+		name = firstNonEmpty(config.DefaultProfile, defaultProfileName)
+	}
+
+	resolved, ok := config.Profiles[name]
+	if !ok && requested {
+		return profile{}, fmt.Errorf("profile %q not found in %s", name, path)
+	}
+
+	resolved.URL = firstNonEmpty(opts.url, getenv(envURL), resolved.URL)
+	resolved.Username = firstNonEmpty(opts.username, getenv(envUsername), resolved.Username)
+	resolved.Password = firstNonEmpty(opts.password, getenv(envPassword), resolved.Password)
+	resolved.Timeout = firstNonEmpty(opts.timeout, getenv(envTimeout), resolved.Timeout)
+
+	if resolved.URL == "" {
+		return profile{}, fmt.Errorf("no qBittorrent URL configured: use -url, %s or a config file profile", envURL)
+	}
+	return resolved, nil
+}
+
+// clientConfig converts the profile into an SDK configuration.
+func (p profile) clientConfig() (qbt.Config, error) {
+	config := qbt.Config{
+		BaseURL:  p.URL,
+		Username: p.Username,
+		Password: p.Password,
+	}
+	if p.Timeout != "" {
+		timeout, err := time.ParseDuration(p.Timeout)
+		if err != nil {
+			return config, fmt.Errorf("invalid timeout %q: %w", p.Timeout, err)
+		}
+		config.RequestTimeout = timeout
+	}
+	return config, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
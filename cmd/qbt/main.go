@@ -0,0 +1,103 @@
+// Command qbt is a command-line client for qBittorrent built on the go-qbt SDK.
+//
+// Connection settings are read from flags, QBT_* environment variables or a
+// JSON config file with named profiles, in decreasing order of precedence.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// app carries the state shared by all commands.
+type app struct {
+	ctx     context.Context
+	cmd     *command
+	client  *qbt.Client
+	printer *printer
+	stderr  io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run executes the CLI and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	var opts globalOptions
+	global := flag.NewFlagSet("qbt", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.StringVar(&opts.configPath, "config", "", "config file (default "+defaultConfigPath()+")")
+	global.StringVar(&opts.profile, "profile", "", "config file profile to use")
+	global.StringVar(&opts.url, "url", "", "qBittorrent WebUI URL")
+	global.StringVar(&opts.username, "username", "", "WebUI username")
+	global.StringVar(&opts.password, "password", "", "WebUI password")
+	global.StringVar(&opts.timeout, "timeout", "", "request timeout (e.g. 30s)")
+	global.StringVar(&opts.output, "output", formatTable, "output format: table, json or csv")
+	global.Usage = func() { writeUsage(stderr, global) }
+
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if global.NArg() == 0 {
+		writeUsage(stderr, global)
+		return 2
+	}
+
+	cmd := findCommand(global.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(stderr, "qbt: unknown command %q\n\n", global.Arg(0))
+		writeUsage(stderr, global)
+		return 2
+	}
+
+	out, err := newPrinter(stdout, opts.output)
+	if err != nil {
+		fmt.Fprintf(stderr, "qbt: %v\n", err)
+		return 2
+	}
+
+	resolved, err := resolveProfile(opts, getenv)
+	if err != nil {
+		fmt.Fprintf(stderr, "qbt: %v\n", err)
+		return 1
+	}
+	config, err := resolved.clientConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "qbt: %v\n", err)
+		return 1
+	}
+
+	client, err := qbt.New(config)
+	if err != nil {
+		fmt.Fprintf(stderr, "qbt: %v\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	a := &app{ctx: ctx, cmd: cmd, client: client, printer: out, stderr: stderr}
+	if err := cmd.run(a, global.Args()[1:]); err != nil {
+		switch {
+		case errors.Is(err, errUsage):
+			return 2
+		case errors.Is(err, errSilent):
+		default:
+			fmt.Fprintf(stderr, "qbt %s: %v\n", cmd.name, err)
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+		"default_profile": "home",
+		"profiles": {
+			"home": {"url": "http://home:8080", "username": "admin", "password": "secret"},
+			"seedbox": {"url": "https://seedbox", "username": "ops", "timeout": "1m"}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+
+	resolved, err := resolveProfile(globalOptions{configPath: path}, getenv)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resolved.URL != "http://home:8080" || resolved.Password != "secret" {
+		t.Errorf("Expected the default profile, got %+v", resolved)
+	}
+
+	env[envProfile] = "seedbox"
+	env[envUsername] = "env-user"
+	resolved, err = resolveProfile(globalOptions{configPath: path, username: "flag-user"}, getenv)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resolved.URL != "https://seedbox" || resolved.Username != "flag-user" || resolved.Timeout != "1m" {
+		t.Errorf("Expected seedbox profile with flag override, got %+v", resolved)
+	}
+
+	delete(env, envProfile)
+	env[envURL] = "http://env:8080"
+	resolved, err = resolveProfile(globalOptions{configPath: path}, getenv)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resolved.URL != "http://env:8080" || resolved.Username != "env-user" {
+		t.Errorf("Environment should override the file, got %+v", resolved)
+	}
+
+	if _, err := resolveProfile(globalOptions{configPath: path, profile: "missing"}, getenv); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+	if _, err := resolveProfile(globalOptions{configPath: filepath.Join(t.TempDir(), "none.json")}, getenv); err == nil {
+		t.Error("Expected an error for a missing explicit config file")
+	}
+}
+
+func TestParseAssignments(t *testing.T) {
+	prefs, err := parseAssignments([]string{"dl_limit=1024", "dht=false", "save_path=/data/downloads"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if prefs["dl_limit"] != float64(1024) || prefs["dht"] != false || prefs["save_path"] != "/data/downloads" {
+		t.Errorf("Unexpected parsed values: %#v", prefs)
+	}
+
+	if _, err := parseAssignments([]string{"novalue"}); err == nil {
+		t.Error("Expected an error for an assignment without '='")
+	}
+}
+
+func TestFormatters(t *testing.T) {
+	testCases := []struct {
+		got, expected string
+	}{
+		{formatBytes(512, true), "512 B"},
+		{formatBytes(1536, true), "1.5 KiB"},
+		{formatBytes(3*1024*1024*1024, true), "3.0 GiB"},
+		{formatBytes(1536, false), "1536"},
+		{formatSpeed(2048, true), "2.0 KiB/s"},
+		{formatPercent(0.5, true), "50.0%"},
+		{formatPercent(0.5, false), "0.5000"},
+	}
+	for _, tc := range testCases {
+		if tc.got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, tc.got)
+		}
+	}
+}
+
+// newTestServer fakes the qBittorrent endpoints used by the CLI tests.
+func newTestServer(t *testing.T, requests *[]url.Values) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "SID", Value: "test", Path: "/"})
+		w.Write([]byte("Ok."))
+	})
+	mux.HandleFunc("/api/v2/auth/logout", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/api/v2/app/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v5.0.0"))
+	})
+	mux.HandleFunc("/api/v2/app/webapiVersion", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("2.11.0"))
+	})
+	mux.HandleFunc("/api/v2/torrents/info", func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.Query())
+		w.Write([]byte(`[{"hash":"abc","name":"debian.iso","state":"uploading","progress":1,"size":2048,"ratio":1.5,"magnet_uri":"magnet:?xt=urn:btih:abc"}]`))
+	})
+	for _, endpoint := range []string{"stop", "addTags", "removeTags"} {
+		mux.HandleFunc("/api/v2/torrents/"+endpoint, func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			*requests = append(*requests, r.PostForm)
+		})
+	}
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunCommands(t *testing.T) {
+	var requests []url.Values
+	server := newTestServer(t, &requests)
+	getenv := func(key string) string {
+		if key == envURL {
+			return server.URL
+		}
+		return ""
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-output", "json", "list", "-filter", "seeding", "-sort", "ratio", "abc"}, &stdout, &stderr, getenv)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	var torrents []map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &torrents); err != nil {
+		t.Fatalf("Output is not JSON: %v\n%s", err, stdout.String())
+	}
+	if len(torrents) != 1 || torrents[0]["hash"] != "abc" {
+		t.Errorf("Unexpected torrents: %v", torrents)
+	}
+	if query := requests[0]; query.Get("filter") != "seeding" || query.Get("sort") != "ratio" || query.Get("hashes") != "abc" {
+		t.Errorf("Unexpected list query: %v", query)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"list"}, &stdout, &stderr, getenv); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "2.0 KiB") || !strings.Contains(stdout.String(), "100.0%") {
+		t.Errorf("Table output should be human-readable:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-output", "csv", "list"}, &stdout, &stderr, getenv); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[1], ",2048,") {
+		t.Errorf("Unexpected CSV output:\n%s", stdout.String())
+	}
+
+	if code := run([]string{"stop", "abc", "def"}, &stdout, &stderr, getenv); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if hashes := requests[len(requests)-1].Get("hashes"); hashes != "abc|def" {
+		t.Errorf("Expected pipe-joined hashes, got %q", hashes)
+	}
+
+	for _, args := range [][]string{{"tag", "abc", "a", "b", "c"}, {"tag", "-remove", "abc", "a", "b", "c"}} {
+		if code := run(args, &stdout, &stderr, getenv); code != 0 {
+			t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+		}
+		if tags := requests[len(requests)-1]["tags"]; len(tags) != 1 || tags[0] != "a,b,c" {
+			t.Errorf("%v: expected every tag in one comma-joined parameter, got %q", args, tags)
+		}
+	}
+}
+
+func TestRunUsageErrors(t *testing.T) {
+	getenv := func(string) string { return "" }
+
+	testCases := [][]string{
+		{},
+		{"bogus"},
+		{"-output", "yaml", "list"},
+	}
+	for _, args := range testCases {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr, getenv); code != 2 {
+			t.Errorf("%v: expected exit code 2, got %d", args, code)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-config", filepath.Join(t.TempDir(), "none.json"), "list"}, &stdout, &stderr, getenv); code != 1 {
+		t.Errorf("Expected exit code 1 without a configured URL, got %d", code)
+	}
+}
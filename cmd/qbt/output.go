@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats accepted by -output
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// table is the tabular view of a command result. Rows are built twice so the
+// table format can use human-readable values while CSV keeps raw numbers.
+type table struct {
+	headers []string
+	rows    func(human bool) [][]string
+}
+
+// printer writes command results in the selected format.
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+func newPrinter(out io.Writer, format string) (*printer, error) {
+	switch format {
+	case formatTable, formatJSON, formatCSV:
+		return &printer{out: out, format: format}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (want table, json or csv)", format)
+	}
+}
+
+// print writes value as JSON, or t as a table or CSV.
+func (p *printer) print(value interface{}, t table) error {
+	switch p.format {
+	case formatJSON:
+		encoder := json.NewEncoder(p.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case formatCSV:
+		writer := csv.NewWriter(p.out)
+		if err := writer.Write(t.headers); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(t.rows(false)); err != nil {
+			return err
+		}
+		return writer.Error()
+	default:
+		writer := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(t.headers, "\t"))
+		for _, row := range t.rows(true) {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	}
+}
+
+// formatBytes renders a byte count, human-readable when requested.
+func formatBytes(n int64, human bool) string {
+	if !human {
+		return fmt.Sprintf("%d", n)
+	}
+
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatSpeed renders a byte rate, human-readable when requested.
+func formatSpeed(n int64, human bool) string {
+	if !human {
+		return fmt.Sprintf("%d", n)
+	}
+	return formatBytes(n, true) + "/s"
+}
+
+// formatPercent renders a 0..1 progress value.
+func formatPercent(progress float64, human bool) string {
+	if !human {
+		return fmt.Sprintf("%.4f", progress)
+	}
+	return fmt.Sprintf("%.1f%%", progress*100)
+}
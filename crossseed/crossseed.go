@@ -0,0 +1,271 @@
+/*
+Package crossseed adds torrents for content that is already on disk.
+
+A candidate .torrent from another tracker is matched against the complete
+torrents of an instance by file names and sizes. When a match is found the
+candidate is added with the save path of the match, with hash checking
+skipped and tagged "cross-seed", so it seeds the existing data right away:
+
+	matcher := crossseed.New(client, crossseed.Options{
+		Verify:  true, // Hash pieces of the local data before adding
+		PathMap: map[string]string{"/downloads": "/srv/torrents"},
+	})
+	result, err := matcher.CrossSeedFile("candidate.torrent")
+	fmt.Println(result.Status, result.Reason)
+
+Verification reads the data through the local file system, so PathMap must
+translate the save paths qBittorrent reports when it runs in a container.
+*/
+package crossseed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	qbt "github.com/jfxdev/go-qbt"
+	"github.com/jfxdev/go-qbt/metainfo"
+)
+
+// DefaultTag marks torrents added by the matcher.
+const DefaultTag = "cross-seed"
+
+// API is the part of *qbt.Client used by the matcher.
+type API interface {
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error)
+	AddTorrentFile(filename string, torrent []byte, opts qbt.TorrentConfig) error
+}
+
+// Options configure a Matcher.
+type Options struct {
+	Tag          string            // Tag added to cross-seeded torrents (default: "cross-seed")
+	Category     string            // Category for added torrents (default: the category of the match)
+	Verify       bool              // Hash pieces of the local data before adding (default: false)
+	VerifyPieces int               // Pieces to hash, spread evenly over the torrent (default: all)
+	PathMap      map[string]string // Server path prefixes to local paths for Verify (default: paths are the same)
+	Paused       bool              // Add cross-seeded torrents stopped (default: false)
+	DryRun       bool              // Match without adding (default: false)
+}
+
+// Status is the outcome for a candidate.
+type Status string
+
+const (
+	StatusAdded    Status = "added"    // Matched and added (or would be, in a dry run)
+	StatusExists   Status = "exists"   // The candidate is already on the instance
+	StatusNoMatch  Status = "no-match" // No complete torrent has the same files
+	StatusMismatch Status = "mismatch" // A match was found but its data failed verification
+)
+
+// Match is an existing torrent with the same content as a candidate.
+type Match struct {
+	Hash     string `json:"hash"`
+	Name     string `json:"name"`
+	SavePath string `json:"save_path"`
+	Category string `json:"category"`
+}
+
+// Result describes what happened to a candidate.
+type Result struct {
+	InfoHash string `json:"info_hash"`
+	Name     string `json:"name"`
+	Status   Status `json:"status"`
+	Match    *Match `json:"match,omitempty"`
+	Verified bool   `json:"verified"`
+	Reason   string `json:"reason,omitempty"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+// Matcher finds existing content for candidate torrents.
+type Matcher struct {
+	api  API
+	opts Options
+}
+
+// New creates a matcher for the instance behind api.
+func New(api API, opts Options) *Matcher {
+	if opts.Tag == "" {
+		opts.Tag = DefaultTag
+	}
+	return &Matcher{api: api, opts: opts}
+}
+
+// CrossSeedFile reads a .torrent file and cross-seeds it.
+func (m *Matcher) CrossSeedFile(name string) (*Result, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read torrent file: %w", err)
+	}
+	return m.CrossSeed(filepath.Base(name), data)
+}
+
+// CrossSeed matches the .torrent data against the instance and adds it with
+// the save path of the match.
+func (m *Matcher) CrossSeed(filename string, data []byte) (*Result, error) {
+	info, err := metainfo.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{InfoHash: info.InfoHash, Name: info.Name, DryRun: m.opts.DryRun}
+
+	torrents, err := m.api.ListTorrents(qbt.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list torrents: %w", err)
+	}
+	for _, t := range torrents {
+		if strings.EqualFold(t.Hash, info.InfoHash) || strings.EqualFold(t.InfoHashV1, info.InfoHash) {
+			result.Status = StatusExists
+			result.Reason = "already added as " + t.Name
+			return result, nil
+		}
+	}
+
+	match, err := m.find(info, torrents)
+	if err != nil {
+		return nil, err
+	}
+	if match == nil {
+		result.Status = StatusNoMatch
+		return result, nil
+	}
+	result.Match = match
+
+	if m.opts.Verify {
+		bad, err := m.verify(info, match)
+		if err != nil {
+			return nil, err
+		}
+		if bad >= 0 {
+			result.Status = StatusMismatch
+			result.Reason = fmt.Sprintf("piece %d does not match the data of %s", bad, match.Name)
+			return result, nil
+		}
+		result.Verified = true
+	}
+
+	result.Status = StatusAdded
+	if m.opts.DryRun {
+		return result, nil
+	}
+
+	category := m.opts.Category
+	if category == "" {
+		category = match.Category
+	}
+	// The files must land exactly where they were matched: keep the layout
+	// of the torrent and stop a category's automatic management from
+	// overriding the save path
+	autoTMM := false
+	err = m.api.AddTorrentFile(filename, data, qbt.TorrentConfig{
+		Directory:     match.SavePath,
+		Category:      category,
+		Tags:          []string{m.opts.Tag},
+		Paused:        m.opts.Paused,
+		SkipChecking:  true,
+		ContentLayout: qbt.ContentLayoutOriginal,
+		AutoTMM:       &autoTMM,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add %s: %w", info.Name, err)
+	}
+	return result, nil
+}
+
+// Find returns the first complete torrent whose files match the candidate,
+// or nil.
+func (m *Matcher) Find(info *metainfo.MetaInfo) (*Match, error) {
+	torrents, err := m.api.ListTorrents(qbt.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list torrents: %w", err)
+	}
+	return m.find(info, torrents)
+}
+
+func (m *Matcher) find(info *metainfo.MetaInfo, torrents []*qbt.TorrentResponse) (*Match, error) {
+	want := contentFiles(info)
+	var size int64
+	for _, f := range want {
+		size += f.Length
+	}
+
+	for _, t := range torrents {
+		// Size is cheap to compare; only list the files of plausible matches
+		if t.Progress < 1 || int64(t.Size) != size {
+			continue
+		}
+		files, err := m.api.ListTorrentFiles(t.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files of %s: %w", t.Hash, err)
+		}
+		if sameFiles(want, files) {
+			return &Match{Hash: t.Hash, Name: t.Name, SavePath: t.SavePath, Category: t.Category}, nil
+		}
+	}
+	return nil, nil
+}
+
+// sameFiles compares names and sizes regardless of order. Names have to match
+// too: the candidate is added with checking skipped, so qBittorrent must find
+// every file at the path the candidate names.
+func sameFiles(want []metainfo.File, have []*qbt.TorrentFile) bool {
+	if len(want) != len(have) {
+		return false
+	}
+	key := func(name string, size int64) string {
+		return fmt.Sprintf("%d %s", size, name)
+	}
+
+	counts := make(map[string]int, len(want))
+	for _, f := range want {
+		counts[key(f.Path, f.Length)]++
+	}
+	for _, f := range have {
+		k := key(f.Name, f.Size)
+		if counts[k] == 0 {
+			return false
+		}
+		counts[k]--
+	}
+	return true
+}
+
+// verify hashes pieces of the match's local data and returns the index of
+// the first bad piece, or -1.
+func (m *Matcher) verify(info *metainfo.MetaInfo, match *Match) (int, error) {
+	dir := filepath.FromSlash(qbt.MapPath(match.SavePath, m.opts.PathMap))
+	for _, i := range sample(len(info.Pieces), m.opts.VerifyPieces) {
+		ok, err := info.VerifyPiece(dir, i)
+		if err != nil {
+			return 0, fmt.Errorf("failed to verify %s: %w", match.Name, err)
+		}
+		if !ok {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// contentFiles returns the files of info without padding files.
+func contentFiles(info *metainfo.MetaInfo) []metainfo.File {
+	return slices.DeleteFunc(slices.Clone(info.Files), func(f metainfo.File) bool { return f.Padding })
+}
+
+// sample returns n piece indexes spread evenly over total pieces, always
+// including the first and last piece. n <= 0 selects every piece.
+func sample(total, n int) []int {
+	if n <= 0 || n >= total {
+		n = total
+	}
+	indexes := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		idx := 0
+		if n > 1 {
+			idx = i * (total - 1) / (n - 1)
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes
+}
@@ -0,0 +1,139 @@
+package crossseed
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// fakeAPI serves one complete torrent saved under /downloads/music.
+type fakeAPI struct {
+	torrents  []*qbt.TorrentResponse
+	files     map[string][]*qbt.TorrentFile
+	added     []qbt.TorrentConfig
+	fileLists int
+}
+
+func (f *fakeAPI) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	return f.torrents, nil
+}
+
+func (f *fakeAPI) ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error) {
+	f.fileLists++
+	return f.files[hash], nil
+}
+
+func (f *fakeAPI) AddTorrentFile(filename string, torrent []byte, opts qbt.TorrentConfig) error {
+	f.added = append(f.added, opts)
+	return nil
+}
+
+var content = []byte(strings.Repeat("0123456789", 5)) // 30 bytes in a.flac, 20 in b.flac
+
+// torrentFile bencodes a two-file torrent of content named "Album".
+func torrentFile(source string, second string) []byte {
+	var pieces strings.Builder
+	for i := 0; i < len(content); i += 16 {
+		sum := sha1.Sum(content[i:min(i+16, len(content))])
+		pieces.Write(sum[:])
+	}
+	info := fmt.Sprintf("d5:filesld6:lengthi30e4:pathl6:a.flaceed6:lengthi20e4:pathl%d:%seee4:name5:Album12:piece lengthi16e6:pieces%d:%s6:source%d:%se",
+		len(second), second, pieces.Len(), pieces.String(), len(source), source)
+	return []byte("d4:info" + info + "e")
+}
+
+func newAPI() *fakeAPI {
+	return &fakeAPI{
+		torrents: []*qbt.TorrentResponse{
+			{Hash: "other", Size: 50, Progress: 0.5, SavePath: "/downloads/incomplete"},
+			{Hash: "small", Size: 10, Progress: 1},
+			{Hash: "album", Name: "Album", Size: 50, Progress: 1, SavePath: "/downloads/music", Category: "music"},
+		},
+		files: map[string][]*qbt.TorrentFile{
+			"album": {{Name: "Album/b.flac", Size: 20}, {Name: "Album/a.flac", Size: 30}},
+		},
+	}
+}
+
+func TestCrossSeed(t *testing.T) {
+	api := newAPI()
+	result, err := New(api, Options{}).CrossSeed("album.torrent", torrentFile("OTHER", "b.flac"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Status != StatusAdded || result.Match == nil || result.Match.Hash != "album" {
+		t.Fatalf("Expected a match with album, got %+v", result)
+	}
+	if api.fileLists != 1 {
+		t.Errorf("Expected only the size-matching complete torrent to be listed, got %d lists", api.fileLists)
+	}
+
+	manual := false
+	expected := qbt.TorrentConfig{Directory: "/downloads/music", Category: "music", Tags: []string{"cross-seed"}, SkipChecking: true,
+		ContentLayout: qbt.ContentLayoutOriginal, AutoTMM: &manual}
+	if len(api.added) != 1 || !reflect.DeepEqual(api.added[0], expected) {
+		t.Errorf("Expected add %+v, got %+v", expected, api.added)
+	}
+
+	// Adding the same candidate again is detected by its info hash
+	api.torrents = append(api.torrents, &qbt.TorrentResponse{Hash: strings.ToUpper(result.InfoHash), Name: "Album"})
+	result, err = New(api, Options{}).CrossSeed("album.torrent", torrentFile("OTHER", "b.flac"))
+	if err != nil || result.Status != StatusExists {
+		t.Errorf("Expected the candidate to exist, got %+v %v", result, err)
+	}
+}
+
+func TestCrossSeedNames(t *testing.T) {
+	renamed := torrentFile("OTHER", "renamed.flac")
+
+	api := newAPI()
+	result, err := New(api, Options{}).CrossSeed("album.torrent", renamed)
+	if err != nil || result.Status != StatusNoMatch || len(api.added) != 0 {
+		t.Errorf("Expected no match for a renamed file, got %+v %v", result, err)
+	}
+}
+
+func TestCrossSeedVerify(t *testing.T) {
+	host := t.TempDir()
+	dir := filepath.Join(host, "music", "Album")
+	os.MkdirAll(dir, 0o755)
+	os.WriteFile(filepath.Join(dir, "a.flac"), content[:30], 0o644)
+	os.WriteFile(filepath.Join(dir, "b.flac"), content[30:], 0o644)
+
+	opts := Options{Verify: true, PathMap: map[string]string{"/downloads": host}}
+	api := newAPI()
+	result, err := New(api, opts).CrossSeed("album.torrent", torrentFile("OTHER", "b.flac"))
+	if err != nil || result.Status != StatusAdded || !result.Verified {
+		t.Fatalf("Expected a verified match, got %+v %v", result, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "b.flac"), []byte(strings.Repeat("x", 20)), 0o644)
+	api = newAPI()
+	result, err = New(api, opts).CrossSeed("album.torrent", torrentFile("OTHER", "b.flac"))
+	if err != nil || result.Status != StatusMismatch || len(api.added) != 0 {
+		t.Errorf("Expected a mismatch, got %+v %v", result, err)
+	}
+}
+
+func TestSample(t *testing.T) {
+	testCases := []struct {
+		total, n int
+		expected []int
+	}{
+		{4, 0, []int{0, 1, 2, 3}},
+		{4, 9, []int{0, 1, 2, 3}},
+		{10, 3, []int{0, 4, 9}},
+		{10, 1, []int{0}},
+	}
+	for _, tc := range testCases {
+		if got := sample(tc.total, tc.n); fmt.Sprint(got) != fmt.Sprint(tc.expected) {
+			t.Errorf("sample(%d, %d): expected %v, got %v", tc.total, tc.n, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+/*
+Package diagnose finds stalled and dead torrents and remediates them.
+
+Each torrent is classified from its state, seeds, availability and last
+activity, and from its tracker messages when no tracker is working:
+
+  - missing-files: the data is gone from disk
+  - errored: qBittorrent reports an I/O or other error
+  - tracker-unregistered: a tracker says the torrent is not registered
+  - metadata-stuck: a magnet has not received its metadata in time
+  - stalled-no-seeds: a download has no seeds, no full copy in the swarm and
+    no recent activity
+
+Every condition maps to remedies run in order. The defaults only reannounce,
+recheck and tag; deleting is opt-in. A torrent that already carries the tag
+of one of its remedies was remediated by an earlier run and is only reported:
+
+	analyzer, err := diagnose.New(client, diagnose.Config{
+		Remediation: map[diagnose.Condition][]diagnose.Remedy{
+			diagnose.ConditionUnregistered: {{Type: diagnose.RemedyDelete}},
+		},
+	})
+	report, err := analyzer.Run()
+	fmt.Print(report)
+*/
+package diagnose
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// API is the part of *qbt.Client used by the analyzer.
+type API interface {
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	GetTorrentTrackers(hash string) ([]*qbt.TorrentTracker, error)
+	ForceReannounce(hash string) error
+	ForceRecheck(hash string) error
+	AddTorrentTags(hash string, tags []string) error
+	DeleteTorrents(hash string, deleteFiles bool) error
+}
+
+// Condition is a detected problem.
+type Condition string
+
+const (
+	ConditionMissingFiles  Condition = "missing-files"
+	ConditionErrored       Condition = "errored"
+	ConditionUnregistered  Condition = "tracker-unregistered"
+	ConditionMetadataStuck Condition = "metadata-stuck"
+	ConditionStalledNoSeed Condition = "stalled-no-seeds"
+)
+
+// RemedyType is a remediation action.
+type RemedyType string
+
+const (
+	RemedyReannounce RemedyType = "reannounce"
+	RemedyRecheck    RemedyType = "recheck"
+	RemedyTag        RemedyType = "tag"
+	RemedyDelete     RemedyType = "delete"
+)
+
+// Remedy is one remediation step.
+type Remedy struct {
+	Type        RemedyType `json:"type"`
+	Tag         string     `json:"tag,omitempty"`          // Tag: tag to add (default: the condition name)
+	DeleteFiles bool       `json:"delete_files,omitempty"` // Delete: also remove the data
+}
+
+// DefaultUnregisteredPatterns match the messages trackers send for torrents
+// they do not know (any more). Matching is case-insensitive.
+var DefaultUnregisteredPatterns = []string{
+	`unregistered`,
+	`not registered`,
+	`torrent not found`,
+	`unknown torrent`,
+	`infohash not found`,
+	`torrent (has been|was) (deleted|removed|trumped)`,
+	`trumped`,
+}
+
+// DefaultRemediation is used for conditions missing from Config.Remediation.
+var DefaultRemediation = map[Condition][]Remedy{
+	ConditionMissingFiles:  {{Type: RemedyRecheck}, {Type: RemedyTag}},
+	ConditionErrored:       {{Type: RemedyRecheck}, {Type: RemedyTag}},
+	ConditionUnregistered:  {{Type: RemedyTag}},
+	ConditionMetadataStuck: {{Type: RemedyReannounce}, {Type: RemedyTag}},
+	ConditionStalledNoSeed: {{Type: RemedyReannounce}, {Type: RemedyTag}},
+}
+
+// Default values for Config
+const (
+	DefaultStalledAfter    = 24 * time.Hour
+	DefaultMetadataTimeout = time.Hour
+)
+
+// Config configures an Analyzer.
+type Config struct {
+	UnregisteredPatterns []string               // Regular expressions for unregistered tracker messages (default: DefaultUnregisteredPatterns)
+	StalledAfter         time.Duration          // Inactivity before a seedless download counts as stalled (default: 24h)
+	MetadataTimeout      time.Duration          // Time a magnet may wait for metadata (default: 1h)
+	Remediation          map[Condition][]Remedy // Remedies by condition; an empty list disables remediation (default: DefaultRemediation)
+	Select               qbt.ListOptions        // Torrents to analyze (default: all)
+	DryRun               bool                   // Classify without remediating (default: false)
+	Now                  func() time.Time       // Clock (default: time.Now)
+}
+
+// RemedyResult is the outcome of one remedy.
+type RemedyResult struct {
+	Remedy  Remedy `json:"remedy"`
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Finding is a torrent with a detected condition.
+type Finding struct {
+	Hash      string         `json:"hash"`
+	Name      string         `json:"name"`
+	Condition Condition      `json:"condition"`
+	Detail    string         `json:"detail"`
+	Remedies  []RemedyResult `json:"remedies,omitempty"`
+	Error     string         `json:"error,omitempty"` // Set when the torrent could not be fully analyzed
+}
+
+// Report summarizes an analysis.
+type Report struct {
+	Checked  int               `json:"checked"`
+	Findings []Finding         `json:"findings"`
+	Counts   map[Condition]int `json:"counts"`
+	DryRun   bool              `json:"dry_run"`
+}
+
+// String renders the report one finding per line, followed by the counts.
+func (r *Report) String() string {
+	var b strings.Builder
+	errors := 0
+	for _, f := range r.Findings {
+		if f.Condition == "" {
+			errors++
+			fmt.Fprintf(&b, "%-20s %s %s: %s\n", "unknown", f.Hash, f.Name, f.Error)
+			continue
+		}
+		fmt.Fprintf(&b, "%-20s %s %s: %s", f.Condition, f.Hash, f.Name, f.Detail)
+		if f.Error != "" {
+			errors++
+			fmt.Fprintf(&b, " (%s)", f.Error)
+		}
+		for _, res := range f.Remedies {
+			switch {
+			case res.Error != "":
+				fmt.Fprintf(&b, " [%s failed: %s]", res.Remedy.Type, res.Error)
+			case res.Applied:
+				fmt.Fprintf(&b, " [%s]", res.Remedy.Type)
+			default:
+				fmt.Fprintf(&b, " [%s skipped]", res.Remedy.Type)
+			}
+		}
+		b.WriteByte('\n')
+	}
+
+	conditions := make([]string, 0, len(r.Counts))
+	for c, n := range r.Counts {
+		conditions = append(conditions, fmt.Sprintf("%d %s", n, c))
+	}
+	sort.Strings(conditions)
+	if errors > 0 {
+		conditions = append(conditions, fmt.Sprintf("%d not fully analyzed", errors))
+	}
+	if len(conditions) == 0 {
+		conditions = []string{"no problems"}
+	}
+	fmt.Fprintf(&b, "Checked %d torrents: %s.\n", r.Checked, strings.Join(conditions, ", "))
+	return b.String()
+}
+
+// Analyzer classifies and remediates the torrents of an instance.
+type Analyzer struct {
+	api      API
+	config   Config
+	patterns []*regexp.Regexp
+}
+
+// New creates an analyzer. It fails if an unregistered pattern does not
+// compile.
+func New(api API, config Config) (*Analyzer, error) {
+	if config.UnregisteredPatterns == nil {
+		config.UnregisteredPatterns = DefaultUnregisteredPatterns
+	}
+	if config.StalledAfter <= 0 {
+		config.StalledAfter = DefaultStalledAfter
+	}
+	if config.MetadataTimeout <= 0 {
+		config.MetadataTimeout = DefaultMetadataTimeout
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	a := &Analyzer{api: api, config: config}
+	for i, p := range config.UnregisteredPatterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unregistered pattern %d: %w", i, err)
+		}
+		a.patterns = append(a.patterns, re)
+	}
+	return a, nil
+}
+
+// Run classifies every selected torrent and runs the remedies for each
+// finding. A failing remedy is recorded and skips the remaining remedies of
+// that torrent. A torrent whose trackers cannot be fetched is classified
+// without them and reported with the error.
+func (a *Analyzer) Run() (*Report, error) {
+	torrents, err := a.api.ListTorrents(a.config.Select)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list torrents: %w", err)
+	}
+
+	report := &Report{Checked: len(torrents), Counts: make(map[Condition]int), DryRun: a.config.DryRun}
+	for _, t := range torrents {
+		// qBittorrent reports the first working tracker; only fetch the
+		// tracker list when there is none
+		var trackers []*qbt.TorrentTracker
+		var trackerErr error
+		if t.Tracker == "" {
+			trackers, trackerErr = a.api.GetTorrentTrackers(t.Hash)
+		}
+
+		condition, detail, ok := a.Classify(t, trackers)
+		if !ok && trackerErr == nil {
+			continue
+		}
+
+		finding := Finding{Hash: t.Hash, Name: t.Name, Condition: condition, Detail: detail}
+		if trackerErr != nil {
+			finding.Error = fmt.Sprintf("failed to get trackers: %v", trackerErr)
+		}
+		if ok {
+			finding.Remedies = a.remediate(t, condition)
+			report.Counts[condition]++
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+	return report, nil
+}
+
+// Classify returns the condition of t, if any. trackers may be nil when t
+// has a working tracker.
+func (a *Analyzer) Classify(t *qbt.TorrentResponse, trackers []*qbt.TorrentTracker) (Condition, string, bool) {
+	now := a.config.Now()
+
+	switch t.State {
+	case "missingFiles":
+		return ConditionMissingFiles, "files are missing from " + t.SavePath, true
+	case "error":
+		return ConditionErrored, "torrent is in the error state", true
+	}
+
+	for _, tr := range trackers {
+		// Skip the DHT, PeX and LSD pseudo-trackers
+		if strings.HasPrefix(tr.URL, "** [") {
+			continue
+		}
+		for _, p := range a.patterns {
+			if p.MatchString(tr.Msg) {
+				return ConditionUnregistered, fmt.Sprintf("%s: %s", trackerHost(tr.URL), tr.Msg), true
+			}
+		}
+	}
+
+	if t.State == "metaDL" || t.State == "forcedMetaDL" {
+		waiting := now.Sub(time.Unix(int64(t.AddedOn), 0))
+		if waiting >= a.config.MetadataTimeout {
+			return ConditionMetadataStuck, fmt.Sprintf("no metadata after %s", waiting.Round(time.Minute)), true
+		}
+		return "", "", false
+	}
+
+	if t.State == "stalledDL" && t.NumSeeds == 0 && t.NumComplete == 0 && t.Availability < 1 {
+		last := t.LastActivity
+		if last <= 0 {
+			last = int64(t.AddedOn)
+		}
+		idle := now.Sub(time.Unix(last, 0))
+		if idle >= a.config.StalledAfter {
+			return ConditionStalledNoSeed, fmt.Sprintf("no seeds, availability %.2f, inactive for %s", t.Availability, idle.Round(time.Minute)), true
+		}
+	}
+
+	return "", "", false
+}
+
+func (a *Analyzer) remediate(t *qbt.TorrentResponse, condition Condition) []RemedyResult {
+	remedies, ok := a.config.Remediation[condition]
+	if !ok {
+		remedies = DefaultRemediation[condition]
+	}
+
+	// Tagged by an earlier run: rechecking or reannouncing again on every
+	// run would not help
+	done := slices.ContainsFunc(remedies, func(remedy Remedy) bool {
+		return remedy.Type == RemedyTag && hasTag(t, remedyTag(remedy, condition))
+	})
+
+	results := make([]RemedyResult, 0, len(remedies))
+	failed := false
+	for _, remedy := range remedies {
+		result := RemedyResult{Remedy: remedy}
+		if !a.config.DryRun && !failed && !done {
+			if err := a.apply(t, condition, remedy); err != nil {
+				result.Error = err.Error()
+				failed = true
+			} else {
+				result.Applied = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (a *Analyzer) apply(t *qbt.TorrentResponse, condition Condition, remedy Remedy) error {
+	switch remedy.Type {
+	case RemedyReannounce:
+		return a.api.ForceReannounce(t.Hash)
+	case RemedyRecheck:
+		return a.api.ForceRecheck(t.Hash)
+	case RemedyTag:
+		return a.api.AddTorrentTags(t.Hash, []string{remedyTag(remedy, condition)})
+	case RemedyDelete:
+		return a.api.DeleteTorrents(t.Hash, remedy.DeleteFiles)
+	default:
+		return fmt.Errorf("unknown remedy %q", remedy.Type)
+	}
+}
+
+// remedyTag returns the tag a RemedyTag adds for condition.
+func remedyTag(remedy Remedy, condition Condition) string {
+	if remedy.Tag == "" {
+		return string(condition)
+	}
+	return remedy.Tag
+}
+
+func hasTag(t *qbt.TorrentResponse, tag string) bool {
+	for _, existing := range strings.Split(t.Tags, ",") {
+		if strings.TrimSpace(existing) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// trackerHost returns the host of a tracker URL, or the URL itself when it
+// does not parse.
+func trackerHost(tracker string) string {
+	u, err := url.Parse(tracker)
+	if err != nil || u.Hostname() == "" {
+		return tracker
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,225 @@
+package diagnose
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+var testNow = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeAPI records the remedies applied.
+type fakeAPI struct {
+	torrents     []*qbt.TorrentResponse
+	trackers     map[string][]*qbt.TorrentTracker
+	trackerCalls []string
+	actions      []string
+	failRecheck  bool
+	failTrackers bool
+}
+
+func (f *fakeAPI) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	return f.torrents, nil
+}
+
+func (f *fakeAPI) GetTorrentTrackers(hash string) ([]*qbt.TorrentTracker, error) {
+	f.trackerCalls = append(f.trackerCalls, hash)
+	if f.failTrackers {
+		return nil, fmt.Errorf("timeout")
+	}
+	return f.trackers[hash], nil
+}
+
+func (f *fakeAPI) ForceReannounce(hash string) error {
+	f.actions = append(f.actions, "reannounce "+hash)
+	return nil
+}
+
+func (f *fakeAPI) ForceRecheck(hash string) error {
+	if f.failRecheck {
+		return fmt.Errorf("recheck unavailable")
+	}
+	f.actions = append(f.actions, "recheck "+hash)
+	return nil
+}
+
+func (f *fakeAPI) AddTorrentTags(hash string, tags []string) error {
+	f.actions = append(f.actions, fmt.Sprintf("tag %s %v", hash, tags))
+	return nil
+}
+
+func (f *fakeAPI) DeleteTorrents(hash string, deleteFiles bool) error {
+	f.actions = append(f.actions, fmt.Sprintf("delete %s files=%v", hash, deleteFiles))
+	return nil
+}
+
+func unix(t time.Time) int64 { return t.Unix() }
+
+func newAnalyzer(t *testing.T, api API, config Config) *Analyzer {
+	t.Helper()
+	analyzer, err := New(api, config)
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	return analyzer
+}
+
+func TestClassify(t *testing.T) {
+	analyzer := newAnalyzer(t, &fakeAPI{}, Config{Now: func() time.Time { return testNow }})
+
+	unregistered := []*qbt.TorrentTracker{
+		{URL: "** [DHT] **", Msg: "unregistered"},
+		{URL: "https://tracker.example.org:443/announce", Status: 4, Msg: "Unregistered Torrent"},
+	}
+	dhtOnly := []*qbt.TorrentTracker{{URL: "** [DHT] **", Msg: "unregistered"}}
+
+	testCases := []struct {
+		name     string
+		torrent  *qbt.TorrentResponse
+		trackers []*qbt.TorrentTracker
+		expected Condition // Empty when healthy
+	}{
+		{"missing files", &qbt.TorrentResponse{State: "missingFiles"}, nil, ConditionMissingFiles},
+		{"errored", &qbt.TorrentResponse{State: "error"}, nil, ConditionErrored},
+		{"unregistered", &qbt.TorrentResponse{State: "stalledUP"}, unregistered, ConditionUnregistered},
+		{"pseudo-tracker ignored", &qbt.TorrentResponse{State: "stalledUP"}, dhtOnly, ""},
+		{"metadata stuck", &qbt.TorrentResponse{State: "metaDL", AddedOn: int(unix(testNow.Add(-2 * time.Hour)))}, nil, ConditionMetadataStuck},
+		{"metadata pending", &qbt.TorrentResponse{State: "metaDL", AddedOn: int(unix(testNow.Add(-time.Minute)))}, nil, ""},
+		{"stalled", &qbt.TorrentResponse{State: "stalledDL", Availability: 0.4, LastActivity: unix(testNow.Add(-48 * time.Hour))}, nil, ConditionStalledNoSeed},
+		{"stalled with full copy", &qbt.TorrentResponse{State: "stalledDL", Availability: 1.2, LastActivity: unix(testNow.Add(-48 * time.Hour))}, nil, ""},
+		{"stalled recently active", &qbt.TorrentResponse{State: "stalledDL", LastActivity: unix(testNow.Add(-time.Hour))}, nil, ""},
+		{"stalled with seeds", &qbt.TorrentResponse{State: "stalledDL", NumSeeds: 1, LastActivity: unix(testNow.Add(-48 * time.Hour))}, nil, ""},
+		{"healthy", &qbt.TorrentResponse{State: "downloading", Availability: 3}, nil, ""},
+	}
+
+	for _, tc := range testCases {
+		condition, detail, ok := analyzer.Classify(tc.torrent, tc.trackers)
+		if condition != tc.expected || ok != (tc.expected != "") {
+			t.Errorf("%s: expected %q, got %q (ok=%v)", tc.name, tc.expected, condition, ok)
+		}
+		if ok && detail == "" {
+			t.Errorf("%s: expected a detail", tc.name)
+		}
+	}
+}
+
+func TestCustomPatterns(t *testing.T) {
+	analyzer := newAnalyzer(t, &fakeAPI{}, Config{UnregisteredPatterns: []string{`season pack uploaded`}})
+	trackers := []*qbt.TorrentTracker{{URL: "https://tracker.example.org/announce", Msg: "Season Pack Uploaded"}}
+
+	condition, detail, _ := analyzer.Classify(&qbt.TorrentResponse{State: "stalledUP"}, trackers)
+	if condition != ConditionUnregistered || !strings.Contains(detail, "tracker.example.org") {
+		t.Errorf("Expected custom pattern to match, got %q %q", condition, detail)
+	}
+
+	condition, _, _ = analyzer.Classify(&qbt.TorrentResponse{State: "stalledUP"}, []*qbt.TorrentTracker{{URL: "https://x/announce", Msg: "unregistered torrent"}})
+	if condition != "" {
+		t.Errorf("Custom patterns should replace the defaults, got %q", condition)
+	}
+
+	if _, err := New(&fakeAPI{}, Config{UnregisteredPatterns: []string{`(unclosed`}}); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
+
+func TestRun(t *testing.T) {
+	newAPI := func() *fakeAPI {
+		return &fakeAPI{
+			torrents: []*qbt.TorrentResponse{
+				{Hash: "a", State: "uploading", Tracker: "https://tracker.example.org/announce"},
+				{Hash: "b", State: "stalledUP"},
+				{Hash: "c", State: "missingFiles", Tracker: "https://tracker.example.org/announce"},
+			},
+			trackers: map[string][]*qbt.TorrentTracker{
+				"b": {{URL: "https://tracker.example.org/announce", Status: 4, Msg: "torrent not registered with this tracker"}},
+			},
+		}
+	}
+
+	api := newAPI()
+	report, err := newAnalyzer(t, api, Config{DryRun: true}).Run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(api.actions) != 0 {
+		t.Errorf("Dry run should not remediate: %v", api.actions)
+	}
+	if fmt.Sprint(api.trackerCalls) != "[b]" {
+		t.Errorf("Trackers should only be fetched without a working tracker, got %v", api.trackerCalls)
+	}
+	if report.Checked != 3 || len(report.Findings) != 2 || report.Counts[ConditionUnregistered] != 1 || report.Counts[ConditionMissingFiles] != 1 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if !strings.Contains(report.String(), "Checked 3 torrents: 1 missing-files, 1 tracker-unregistered.") {
+		t.Errorf("Unexpected summary:\n%s", report)
+	}
+
+	api = newAPI()
+	_, err = newAnalyzer(t, api, Config{Remediation: map[Condition][]Remedy{
+		ConditionUnregistered: {{Type: RemedyTag, Tag: "dead"}, {Type: RemedyDelete, DeleteFiles: true}},
+	}}).Run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"tag b [dead]", "delete b files=true", "recheck c", "tag c [missing-files]"}
+	if fmt.Sprint(api.actions) != fmt.Sprint(expected) {
+		t.Errorf("Expected actions %v, got %v", expected, api.actions)
+	}
+}
+
+func TestRunRecordsFailures(t *testing.T) {
+	api := &fakeAPI{failRecheck: true, torrents: []*qbt.TorrentResponse{{Hash: "a", State: "error", Tracker: "https://x/announce"}}}
+
+	report, err := newAnalyzer(t, api, Config{}).Run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	remedies := report.Findings[0].Remedies
+	if len(remedies) != 2 || remedies[0].Error == "" || remedies[1].Applied || len(api.actions) != 0 {
+		t.Errorf("Expected the failure to be recorded and later remedies skipped, got %+v %v", remedies, api.actions)
+	}
+	if !strings.Contains(report.String(), "[recheck failed: recheck unavailable] [tag skipped]") {
+		t.Errorf("Unexpected report:\n%s", report)
+	}
+}
+
+func TestRunContinuesWithoutTrackers(t *testing.T) {
+	api := &fakeAPI{failTrackers: true, torrents: []*qbt.TorrentResponse{
+		{Hash: "a", State: "stalledUP"},
+		{Hash: "b", State: "missingFiles"},
+		{Hash: "c", State: "uploading", Tracker: "https://x/announce"},
+	}}
+
+	report, err := newAnalyzer(t, api, Config{DryRun: true}).Run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Findings) != 2 || report.Findings[0].Error == "" || report.Findings[1].Condition != ConditionMissingFiles {
+		t.Fatalf("Expected every torrent analyzed and the errors recorded, got %+v", report.Findings)
+	}
+	if !strings.Contains(report.String(), "Checked 3 torrents: 1 missing-files, 2 not fully analyzed.") {
+		t.Errorf("Unexpected report:\n%s", report)
+	}
+}
+
+func TestRunSkipsRemediatedTorrents(t *testing.T) {
+	api := &fakeAPI{torrents: []*qbt.TorrentResponse{
+		{Hash: "a", State: "missingFiles", Tags: "keep, missing-files", Tracker: "https://x/announce"},
+		{Hash: "b", State: "missingFiles", Tags: "keep", Tracker: "https://x/announce"},
+	}}
+
+	report, err := newAnalyzer(t, api, Config{}).Run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"recheck b", "tag b [missing-files]"}
+	if fmt.Sprint(api.actions) != fmt.Sprint(expected) {
+		t.Errorf("Expected only the untagged torrent remediated, got %v", api.actions)
+	}
+	if report.Counts[ConditionMissingFiles] != 2 {
+		t.Errorf("Tagged torrents should still be reported, got %+v", report.Counts)
+	}
+}
@@ -0,0 +1,74 @@
+package qbt
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// unixScheme is the BaseURL prefix that selects a Unix domain socket,
+// e.g. "unix:///run/qbittorrent/webui.sock".
+const unixScheme = "unix://"
+
+// unixHostURL replaces a unix:// BaseURL in requests; the host is only used
+// for the Host header and the cookie jar.
+const unixHostURL = "http://localhost"
+
+// DialFunc dials the connection used for a request, like net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// resolveBaseURL rewrites a unix:// BaseURL to a plain HTTP URL and records the
+// socket path so the transport dials it instead.
+func resolveBaseURL(config Config) Config {
+	if !strings.HasPrefix(config.BaseURL, unixScheme) {
+		config.socketPath = ""
+		return config
+	}
+
+	config.socketPath = strings.TrimPrefix(config.BaseURL, unixScheme)
+	config.BaseURL = unixHostURL
+	return config
+}
+
+// dialer returns the dial function of the transport, or nil for the default.
+// A Unix socket is dialed through DialContext when both are configured.
+func dialer(config Config) DialFunc {
+	if config.socketPath == "" {
+		return config.DialContext
+	}
+
+	dial := config.DialContext
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	path := config.socketPath
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dial(ctx, "unix", path)
+	}
+}
+
+// newTransport builds the HTTP transport shared by every request of a client.
+// It returns nil, meaning http.DefaultTransport, when neither TLS options nor
+// a custom dialer are set.
+func newTransport(config Config) (*http.Transport, error) {
+	dial := dialer(config)
+	if config.TLS == nil && dial == nil {
+		return nil, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if dial != nil {
+		transport.DialContext = dial
+	}
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.build()
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
@@ -0,0 +1,82 @@
+package qbt
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// newUnixServer serves the fake qBittorrent API on a Unix socket.
+func newUnixServer(t *testing.T, mux *http.ServeMux) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "webui.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen on socket: %v", err)
+	}
+
+	server := &http.Server{Handler: fakeHandler(mux)}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return path
+}
+
+func TestUnixSocketBaseURL(t *testing.T) {
+	path := newUnixServer(t, transferInfoMux())
+
+	client, err := New(Config{BaseURL: "unix://" + path, Username: "admin", Password: "adminadmin"})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := client.GetTransferInfo(); err != nil {
+		t.Fatalf("Expected request over the socket to succeed, got %v", err)
+	}
+	if status := client.RefreshConnectionStatus(context.Background()); status.Status != StatusConnected {
+		t.Errorf("Expected connected status, got %+v", status)
+	}
+}
+
+func TestUnixSocketMissing(t *testing.T) {
+	client, err := New(Config{
+		BaseURL:      "unix://" + filepath.Join(t.TempDir(), "missing.sock"),
+		MaxRetries:   1,
+		RetryBackoff: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	_, err = client.GetTransferInfo()
+	if GetErrorCode(err) != ErrorCodeSocketNotFound {
+		t.Errorf("Expected %s, got %v", ErrorCodeSocketNotFound, err)
+	}
+}
+
+func TestCustomDialContext(t *testing.T) {
+	path := newUnixServer(t, transferInfoMux())
+
+	// Route every connection to the socket, as a proxy dialer would
+	var dials atomic.Int32
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+
+	client, err := New(Config{BaseURL: "http://qbittorrent.internal:8080", DialContext: dial})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if _, err := client.GetTransferInfo(); err != nil {
+		t.Fatalf("Expected request through the custom dialer to succeed, got %v", err)
+	}
+	if dials.Load() == 0 {
+		t.Error("Expected the custom dialer to be used")
+	}
+}
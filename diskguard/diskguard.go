@@ -0,0 +1,320 @@
+/*
+Package diskguard stops downloads before the download volume fills up and
+resumes them once space is available again.
+
+Every check reads server_state.free_space_on_disk. Once it drops below
+MinFree, the running downloads are stopped, lowest queue priority first, and
+tagged so the guard can find them again even after a restart. Stopped
+downloads are resumed, highest priority first, once free space is back above
+ResumeFree:
+
+	guard := diskguard.New(client, diskguard.Config{
+		MinFree:    20 << 30, // Keep 20 GiB free
+		ResumeFree: 50 << 30, // Resume once 50 GiB are free again
+	})
+	go guard.Run(ctx)
+
+With ProjectNeeds set the guard acts before the threshold is crossed: the
+bytes the running downloads still need are compared with the free space
+minus MinFree, downloads that do not fit are stopped, and held downloads
+only resume if their remaining bytes fit. Preallocated files already hold
+their space, so this mode can stop downloads while space is plentiful.
+
+Torrents added stopped can be released through the guard, which starts them
+only if their size fits. Otherwise they are tagged with ReleaseTag and a
+later check starts them once their remaining bytes fit next to the running
+downloads, whether or not ProjectNeeds is set:
+
+	client.AddTorrentLink(qbt.TorrentConfig{MagnetURI: uri, Paused: true})
+	started, err := guard.Release(hash)
+*/
+package diskguard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// Default values for Config
+const (
+	DefaultInterval   = time.Minute
+	DefaultTag        = "disk-guard"
+	DefaultReleaseTag = "disk-guard-release"
+)
+
+// API is the part of *qbt.Client used by the guard.
+type API interface {
+	GetMainData() (*qbt.MainDataResponse, error)
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	StopTorrents(hash string) error
+	StartTorrents(hash string) error
+	AddTorrentTags(hash string, tags []string) error
+	DeleteTorrentTags(hash string, tags []string) error
+}
+
+// Config configures a Guard.
+type Config struct {
+	MinFree      int64         // Downloads are stopped once free space drops below this many bytes
+	ProjectNeeds bool          // Also stop downloads whose remaining bytes would not fit above MinFree (default: false)
+	ResumeFree   int64         // Free bytes required before stopped downloads resume (default: MinFree plus 10%)
+	Interval     time.Duration // Time between checks in Run (default: 1m)
+	Tag          string        // Tag marking torrents stopped by the guard (default: "disk-guard")
+	ReleaseTag   string        // Tag marking torrents held by Release until they fit (default: "disk-guard-release")
+	Logger       *slog.Logger  // Receives stop, resume and error events (default: discard)
+}
+
+// Status is the outcome of a check.
+type Status struct {
+	FreeSpace int64    // Free bytes reported by the server
+	Needed    int64    // Bytes the running downloads still need after the check
+	Stopped   []string // Hashes stopped by this check
+	Resumed   []string // Hashes resumed by this check
+}
+
+// Guard watches free space on one instance.
+type Guard struct {
+	api    API
+	config Config
+	logger *slog.Logger
+}
+
+// New creates a guard for the instance behind api.
+func New(api API, config Config) *Guard {
+	if config.ResumeFree < config.MinFree {
+		config.ResumeFree = config.MinFree + config.MinFree/10
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.Tag == "" {
+		config.Tag = DefaultTag
+	}
+	if config.ReleaseTag == "" {
+		config.ReleaseTag = DefaultReleaseTag
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Guard{api: api, config: config, logger: logger}
+}
+
+// Run checks every Interval until ctx is cancelled. Failed checks are logged
+// and retried on the next tick.
+func (g *Guard) Run(ctx context.Context) error {
+	ticker := time.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := g.Check(); err != nil {
+			g.logger.Warn("disk guard check failed", qbt.LogKeyError, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check stops the running downloads once free space is below MinFree and
+// resumes them once it is back above ResumeFree. With ProjectNeeds it stops
+// and resumes downloads by whether their remaining bytes fit. Torrents held by
+// Release start as soon as their remaining bytes fit, regardless of ResumeFree.
+func (g *Guard) Check() (*Status, error) {
+	free, err := g.freeSpace()
+	if err != nil {
+		return nil, err
+	}
+
+	torrents, err := g.api.ListTorrents(qbt.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list torrents: %w", err)
+	}
+	sortByPriority(torrents)
+
+	status := &Status{FreeSpace: free}
+
+	// Highest priority first: keep downloads while they fit
+	var held, overflow []*qbt.TorrentResponse
+	for _, t := range torrents {
+		switch {
+		case g.heldByGuard(t) != "":
+			held = append(held, t)
+		case !isDownloading(t):
+		case g.fits(status.Needed+t.AmountLeft, free):
+			status.Needed += t.AmountLeft
+		default:
+			overflow = append(overflow, t)
+		}
+	}
+
+	// Stop the rest, lowest priority first
+	for i := len(overflow) - 1; i >= 0; i-- {
+		if err := g.stop(overflow[i]); err != nil {
+			return status, err
+		}
+		status.Stopped = append(status.Stopped, overflow[i].Hash)
+	}
+
+	for _, t := range held {
+		needed := status.Needed + t.AmountLeft
+		if g.heldByGuard(t) == g.config.ReleaseTag {
+			// Never started: like Release, start it once its size fits
+			if needed > free-g.config.MinFree {
+				continue
+			}
+		} else if free < g.config.ResumeFree || !g.fits(needed, free) {
+			// Hysteresis: stopped downloads wait until free space is back above ResumeFree
+			continue
+		}
+		if err := g.resume(t); err != nil {
+			return status, err
+		}
+		status.Needed += t.AmountLeft
+		status.Resumed = append(status.Resumed, t.Hash)
+	}
+	return status, nil
+}
+
+// Release starts a torrent that was added stopped if its remaining size fits
+// in the free space next to the running downloads. Otherwise the torrent is
+// tagged with ReleaseTag and a later check starts it once it fits. ResumeFree
+// does not apply to these torrents. It returns false while the torrent's
+// metadata, and hence its size, is not known yet.
+func (g *Guard) Release(hash string) (bool, error) {
+	torrents, err := g.api.ListTorrents(qbt.ListOptions{Hashes: []string{hash}})
+	if err != nil {
+		return false, fmt.Errorf("failed to get torrent: %w", err)
+	}
+	if len(torrents) == 0 {
+		return false, fmt.Errorf("torrent not found with hash: %s", hash)
+	}
+	t := torrents[0]
+	if t.Size <= 0 {
+		return false, nil
+	}
+
+	free, err := g.freeSpace()
+	if err != nil {
+		return false, err
+	}
+	running, err := g.api.ListTorrents(qbt.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to list torrents: %w", err)
+	}
+	var needed int64
+	for _, r := range running {
+		if r.Hash != hash && isDownloading(r) {
+			needed += r.AmountLeft
+		}
+	}
+
+	if needed+t.AmountLeft > free-g.config.MinFree {
+		if g.heldByGuard(t) == "" {
+			if err := g.api.AddTorrentTags(hash, []string{g.config.ReleaseTag}); err != nil {
+				return false, err
+			}
+		}
+		g.logger.Info("disk guard holding torrent until space is available",
+			"hash", hash, "amount_left", t.AmountLeft, "free_space", free)
+		return false, nil
+	}
+
+	if g.heldByGuard(t) != "" {
+		if err := g.resume(t); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if err := g.api.StartTorrents(hash); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// fits reports whether downloads that still need the given bytes may run.
+func (g *Guard) fits(needed, free int64) bool {
+	if g.config.ProjectNeeds {
+		return needed <= free-g.config.MinFree
+	}
+	return free >= g.config.MinFree
+}
+
+func (g *Guard) freeSpace() (int64, error) {
+	data, err := g.api.GetMainData()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get free space: %w", err)
+	}
+	return int64(data.ServerState.FreeSpaceOnDisk), nil
+}
+
+func (g *Guard) stop(t *qbt.TorrentResponse) error {
+	if err := g.api.StopTorrents(t.Hash); err != nil {
+		return err
+	}
+	if err := g.api.AddTorrentTags(t.Hash, []string{g.config.Tag}); err != nil {
+		return err
+	}
+	g.logger.Info("disk guard stopped download", "hash", t.Hash, "name", t.Name, "amount_left", t.AmountLeft)
+	return nil
+}
+
+func (g *Guard) resume(t *qbt.TorrentResponse) error {
+	if err := g.api.StartTorrents(t.Hash); err != nil {
+		return err
+	}
+	if err := g.api.DeleteTorrentTags(t.Hash, []string{g.heldByGuard(t)}); err != nil {
+		return err
+	}
+	g.logger.Info("disk guard resumed download", "hash", t.Hash, "name", t.Name, "amount_left", t.AmountLeft)
+	return nil
+}
+
+// heldByGuard returns Tag if the guard stopped t, ReleaseTag if it holds t
+// for Release, or "".
+func (g *Guard) heldByGuard(t *qbt.TorrentResponse) string {
+	for _, tag := range strings.Split(t.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag == g.config.Tag || tag == g.config.ReleaseTag {
+			return tag
+		}
+	}
+	return ""
+}
+
+// isDownloading reports whether t is running and still needs data.
+func isDownloading(t *qbt.TorrentResponse) bool {
+	if t.AmountLeft <= 0 {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(t.State, "stopped"), strings.HasPrefix(t.State, "paused"),
+		t.State == "error", t.State == "missingFiles":
+		return false
+	}
+	return true
+}
+
+// sortByPriority orders torrents from the top of the download queue down.
+// Without queueing (priority 0) older torrents come first.
+func sortByPriority(torrents []*qbt.TorrentResponse) {
+	rank := func(t *qbt.TorrentResponse) int {
+		if t.Priority <= 0 {
+			return int(^uint(0) >> 1)
+		}
+		return t.Priority
+	}
+	sort.SliceStable(torrents, func(i, j int) bool {
+		if rank(torrents[i]) != rank(torrents[j]) {
+			return rank(torrents[i]) < rank(torrents[j])
+		}
+		return torrents[i].AddedOn < torrents[j].AddedOn
+	})
+}
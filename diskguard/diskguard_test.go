@@ -0,0 +1,237 @@
+package diskguard
+
+import (
+	"strings"
+	"testing"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+const gib = 1 << 30
+
+// fakeAPI is an in-memory instance.
+type fakeAPI struct {
+	free     int64
+	torrents []*qbt.TorrentResponse
+}
+
+func (f *fakeAPI) GetMainData() (*qbt.MainDataResponse, error) {
+	return &qbt.MainDataResponse{ServerState: qbt.MainDataServerStateResponse{FreeSpaceOnDisk: int(f.free)}}, nil
+}
+
+func (f *fakeAPI) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	var out []*qbt.TorrentResponse
+	for _, t := range f.torrents {
+		if len(opts.Hashes) == 0 || opts.Hashes[0] == t.Hash {
+			copied := *t
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAPI) find(hash string) *qbt.TorrentResponse {
+	for _, t := range f.torrents {
+		if t.Hash == hash {
+			return t
+		}
+	}
+	return nil
+}
+
+func (f *fakeAPI) StopTorrents(hash string) error {
+	f.find(hash).State = "stoppedDL"
+	return nil
+}
+
+func (f *fakeAPI) StartTorrents(hash string) error {
+	f.find(hash).State = "downloading"
+	return nil
+}
+
+func (f *fakeAPI) AddTorrentTags(hash string, tags []string) error {
+	t := f.find(hash)
+	t.Tags = strings.Trim(t.Tags+", "+tags[0], ", ")
+	return nil
+}
+
+func (f *fakeAPI) DeleteTorrentTags(hash string, tags []string) error {
+	t := f.find(hash)
+	var kept []string
+	for _, tag := range strings.Split(t.Tags, ", ") {
+		if tag != tags[0] && tag != "" {
+			kept = append(kept, tag)
+		}
+	}
+	t.Tags = strings.Join(kept, ", ")
+	return nil
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{free: 100 * gib, torrents: []*qbt.TorrentResponse{
+		{Hash: "low", Priority: 3, State: "downloading", AmountLeft: 30 * gib, Tags: "movies"},
+		{Hash: "high", Priority: 1, State: "downloading", AmountLeft: 40 * gib},
+		{Hash: "mid", Priority: 2, State: "stalledDL", AmountLeft: 20 * gib},
+		{Hash: "seed", State: "uploading"},
+	}}
+}
+
+func hashes(api *fakeAPI, state string) []string {
+	var out []string
+	for _, t := range api.torrents {
+		if t.State == state {
+			out = append(out, t.Hash)
+		}
+	}
+	return out
+}
+
+func TestCheckThreshold(t *testing.T) {
+	api := newFakeAPI()
+	guard := New(api, Config{MinFree: 20 * gib, ResumeFree: 60 * gib})
+
+	// 90 GiB still needed, but nothing happens while free space is above MinFree
+	status, err := guard.Check()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(status.Stopped) != 0 || status.Needed != 90*gib {
+		t.Errorf("Expected no download to stop above MinFree, got %+v", status)
+	}
+
+	api.free = 10 * gib
+	status, err = guard.Check()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(status.Stopped, ",") != "low,mid,high" {
+		t.Errorf("Expected every download to stop lowest priority first, got %+v", status)
+	}
+
+	api.free = 50 * gib
+	if status, _ := guard.Check(); len(status.Resumed) != 0 {
+		t.Errorf("Nothing should resume below ResumeFree, got %+v", status)
+	}
+
+	api.free = 60 * gib
+	status, err = guard.Check()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(status.Resumed, ",") != "high,mid,low" {
+		t.Errorf("Expected every download to resume highest priority first, got %+v", status)
+	}
+}
+
+func TestCheckStopsLowestPriorityFirst(t *testing.T) {
+	api := newFakeAPI()
+	guard := New(api, Config{MinFree: 20 * gib, ResumeFree: 40 * gib, ProjectNeeds: true})
+
+	// 90 GiB needed, 80 GiB available above the reserve
+	status, err := guard.Check()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(status.Stopped) != 1 || status.Stopped[0] != "low" || status.Needed != 60*gib {
+		t.Errorf("Expected only the lowest priority download to stop, got %+v", status)
+	}
+	if !strings.Contains(api.find("low").Tags, DefaultTag) || !strings.Contains(api.find("low").Tags, "movies") {
+		t.Errorf("Stopped torrent should be tagged, got %q", api.find("low").Tags)
+	}
+
+	// The disk fills up: everything stops
+	api.free = 10 * gib
+	status, err = guard.Check()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(status.Stopped) != 2 || status.Stopped[0] != "mid" || status.Stopped[1] != "high" {
+		t.Errorf("Expected the remaining downloads to stop lowest priority first, got %+v", status)
+	}
+}
+
+func TestCheckResumesWithHysteresis(t *testing.T) {
+	api := newFakeAPI()
+	api.free = 10 * gib
+	guard := New(api, Config{MinFree: 20 * gib, ResumeFree: 60 * gib, ProjectNeeds: true})
+	if _, err := guard.Check(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(hashes(api, "stoppedDL")) != 3 {
+		t.Fatalf("Expected every download to stop, got %v", hashes(api, "stoppedDL"))
+	}
+
+	// Above MinFree but below ResumeFree: stay stopped
+	api.free = 55 * gib
+	status, _ := guard.Check()
+	if len(status.Resumed) != 0 {
+		t.Errorf("Nothing should resume below ResumeFree, got %+v", status)
+	}
+
+	// 60 GiB available above the reserve: high (40) and mid (20) fit, low does not
+	api.free = 80 * gib
+	status, err := guard.Check()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(status.Resumed) != 2 || status.Resumed[0] != "high" || status.Resumed[1] != "mid" {
+		t.Errorf("Expected high and mid to resume, got %+v", status)
+	}
+	if api.find("high").Tags != "" || !strings.Contains(api.find("low").Tags, DefaultTag) {
+		t.Errorf("Resumed torrents should lose the tag: high=%q low=%q", api.find("high").Tags, api.find("low").Tags)
+	}
+
+	// A fresh guard (e.g. after a restart) finds the held torrent by its tag
+	api.free = 200 * gib
+	status, _ = New(api, Config{MinFree: 20 * gib, ProjectNeeds: true}).Check()
+	if len(status.Resumed) != 1 || status.Resumed[0] != "low" {
+		t.Errorf("Expected low to resume, got %+v", status)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	api := newFakeAPI()
+	api.torrents = append(api.torrents,
+		&qbt.TorrentResponse{Hash: "new", State: "stoppedDL", Size: 50 * gib, AmountLeft: 50 * gib},
+		&qbt.TorrentResponse{Hash: "magnet", State: "stoppedDL"},
+	)
+	api.free = 200 * gib
+	// ResumeFree only holds back downloads the guard stopped
+	guard := New(api, Config{MinFree: 20 * gib, ResumeFree: 400 * gib})
+
+	if started, err := guard.Release("magnet"); err != nil || started {
+		t.Errorf("A torrent without metadata should not be released, got %v %v", started, err)
+	}
+
+	// 90 GiB needed by running downloads + 50 GiB fits in 180 GiB
+	if started, err := guard.Release("new"); err != nil || !started {
+		t.Errorf("Expected the torrent to start, got %v %v", started, err)
+	}
+
+	api.find("new").State = "stoppedDL"
+	api.free = 150 * gib
+	if started, err := guard.Release("new"); err != nil || started {
+		t.Errorf("Expected the torrent to be held, got %v %v", started, err)
+	}
+	if api.find("new").Tags != DefaultReleaseTag {
+		t.Errorf("A held torrent should be tagged for the guard, got %q", api.find("new").Tags)
+	}
+
+	// 90 + 50 GiB still does not fit in 130 GiB
+	status, err := guard.Check()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(status.Resumed) != 0 || api.find("new").State != "stoppedDL" {
+		t.Errorf("The held torrent should wait until it fits, got %+v", status)
+	}
+
+	api.free = 300 * gib
+	status, err = guard.Check()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(status.Resumed) != 1 || status.Resumed[0] != "new" || api.find("new").Tags != "" {
+		t.Errorf("Expected the held torrent to start once it fits, got %+v", status)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io/fs"
 	"net"
 	"net/url"
 	"strings"
@@ -31,6 +32,9 @@ const (
 	// ErrorCodeSSLError indicates SSL/TLS certificate or connection error
 	ErrorCodeSSLError ErrorCode = "SSL_ERROR"
 
+	// ErrorCodeCertificatePinMismatch indicates the server certificate does not match the configured SPKI pins
+	ErrorCodeCertificatePinMismatch ErrorCode = "CERT_PIN_MISMATCH"
+
 	// ErrorCodeVersionIncompatible indicates incompatible qBittorrent version
 	ErrorCodeVersionIncompatible ErrorCode = "VERSION_INCOMPATIBLE"
 
@@ -40,12 +44,21 @@ const (
 	// ErrorCodeNetworkUnreachable indicates network routing issues
 	ErrorCodeNetworkUnreachable ErrorCode = "NETWORK_UNREACHABLE"
 
+	// ErrorCodeSocketNotFound indicates the Unix socket path does not exist - the server may not be running yet
+	ErrorCodeSocketNotFound ErrorCode = "SOCKET_NOT_FOUND"
+
+	// ErrorCodePermissionDenied indicates the process may not open the socket - check file permissions
+	ErrorCodePermissionDenied ErrorCode = "PERMISSION_DENIED"
+
 	// ErrorCodeBadGateway indicates a proxy/gateway error (502)
 	ErrorCodeBadGateway ErrorCode = "BAD_GATEWAY"
 
 	// ErrorCodeServiceUnavailable indicates the service is temporarily unavailable (503)
 	ErrorCodeServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
 
+	// ErrorCodeCircuitOpen indicates the circuit breaker is open and the call failed fast
+	ErrorCodeCircuitOpen ErrorCode = "CIRCUIT_OPEN"
+
 	// ErrorCodeUnknown indicates an unclassified error
 	ErrorCodeUnknown ErrorCode = "UNKNOWN"
 )
@@ -100,6 +113,17 @@ func ClassifyError(err error) *ClientError {
 
 	errStr := err.Error()
 
+	// Certificate pinning failures, before the generic TLS checks below
+	var pinErr *PinMismatchError
+	if errors.As(err, &pinErr) {
+		return NewClientError(
+			ErrorCodeCertificatePinMismatch,
+			"Server certificate does not match the pinned public keys",
+			err,
+			true,
+		)
+	}
+
 	// DNS errors
 	var dnsErr *net.DNSError
 	if errors.As(err, &dnsErr) {
@@ -155,6 +179,24 @@ func ClassifyError(err error) *ClientError {
 
 // classifyOpError classifies net.OpError errors
 func classifyOpError(opErr *net.OpError, originalErr error) *ClientError {
+	// Unix socket errors (ENOENT, EACCES)
+	if opErr.Op == "dial" && errors.Is(opErr, fs.ErrNotExist) {
+		return NewClientError(
+			ErrorCodeSocketNotFound,
+			"Socket does not exist - server may not be running",
+			originalErr,
+			false,
+		)
+	}
+	if opErr.Op == "dial" && errors.Is(opErr, fs.ErrPermission) {
+		return NewClientError(
+			ErrorCodePermissionDenied,
+			"Permission denied - check socket permissions",
+			originalErr,
+			true,
+		)
+	}
+
 	// Connection refused
 	if opErr.Op == "dial" {
 		if strings.Contains(opErr.Error(), "connection refused") {
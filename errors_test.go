@@ -5,6 +5,8 @@ import (
 	"crypto/tls"
 	"errors"
 	"net"
+	"os"
+	"syscall"
 	"testing"
 )
 
@@ -190,6 +192,37 @@ func TestClassifyErrorNetworkUnreachable(t *testing.T) {
 	}
 }
 
+func TestClassifyErrorUnixSocket(t *testing.T) {
+	tests := []struct {
+		name      string
+		errno     syscall.Errno
+		expected  ErrorCode
+		permanent bool
+	}{
+		{"missing socket", syscall.ENOENT, ErrorCodeSocketNotFound, false},
+		{"permission denied", syscall.EACCES, ErrorCodePermissionDenied, true},
+		{"no listener", syscall.ECONNREFUSED, ErrorCodeConnectionRefused, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opErr := &net.OpError{
+				Op:  "dial",
+				Net: "unix",
+				Err: os.NewSyscallError("connect", tt.errno),
+			}
+			result := ClassifyError(opErr)
+
+			if result.Code != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result.Code)
+			}
+			if result.Permanent != tt.permanent {
+				t.Errorf("Expected permanent=%v, got %v", tt.permanent, result.Permanent)
+			}
+		})
+	}
+}
+
 func TestClassifyHTTPStatusCode(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -0,0 +1,67 @@
+package qbt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newFakeServer starts an httptest server that answers the login handshake
+// like qBittorrent and delegates every other API path to mux.
+func newFakeServer(t *testing.T, mux *http.ServeMux) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(fakeHandler(mux))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// fakeHandler wraps mux with the login, logout and version endpoints.
+func fakeHandler(mux *http.ServeMux) http.Handler {
+	root := http.NewServeMux()
+	if !hasPattern(mux, "/api/v2/auth/login") {
+		root.HandleFunc("/api/v2/auth/login", func(w http.ResponseWriter, r *http.Request) {
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: "test-session", Path: "/"})
+			w.Write([]byte("Ok."))
+		})
+	}
+	root.HandleFunc("/api/v2/auth/logout", func(w http.ResponseWriter, r *http.Request) {})
+	root.Handle("/", mux)
+	if !hasPattern(mux, "/api/v2/app/version") {
+		// Like qBittorrent without auth bypass, the version needs a session
+		root.HandleFunc("/api/v2/app/version", func(w http.ResponseWriter, r *http.Request) {
+			if _, err := r.Cookie("SID"); err != nil {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte("v5.0.0"))
+		})
+	}
+	return root
+}
+
+func hasPattern(mux *http.ServeMux, path string) bool {
+	_, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, path, nil))
+	return pattern != ""
+}
+
+// newFakeClient returns a client pointed at server with fast retries.
+func newFakeClient(t *testing.T, server *httptest.Server, config Config) *Client {
+	t.Helper()
+
+	config.BaseURL = server.URL
+	if config.Username == "" {
+		config.Username = "admin"
+		config.Password = "adminadmin"
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = time.Millisecond
+	}
+
+	client, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
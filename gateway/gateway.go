@@ -0,0 +1,315 @@
+/*
+Package gateway exposes torrent operations as a REST/JSON API with API keys
+scoped to categories or tags, so teams can share an instance without the
+qBittorrent admin password.
+
+A key only sees the torrents in its categories or with its tags; other
+torrents answer 404 as if they did not exist. Torrents added through a key
+are placed in its first category, or get its first tag, so they stay in
+scope. Tags that scope another key cannot be set through a key:
+
+	handler := gateway.New(client, gateway.Config{
+		Keys: []gateway.Key{
+			{Name: "team-a", Key: os.Getenv("TEAM_A_KEY"), Categories: []string{"team-a"},
+				Permissions: []gateway.Permission{gateway.PermRead, gateway.PermAdd, gateway.PermControl}},
+			{Name: "ops", Key: os.Getenv("OPS_KEY"), All: true,
+				Permissions: []gateway.Permission{gateway.PermRead, gateway.PermDelete}},
+		},
+		Audit: auditFile,
+	})
+	http.ListenAndServe(":8081", handler)
+
+Routes, authenticated with "Authorization: Bearer <key>" or "X-API-Key":
+
+	GET    /api/v1/torrents                 list (filter, sort, reverse, limit, offset, category, tag)
+	POST   /api/v1/torrents                 add {"urls": [...], "torrent": base64, "category", "tags", "paused"}
+	GET    /api/v1/torrents/{hash}          get
+	GET    /api/v1/torrents/{hash}/files    list files
+	POST   /api/v1/torrents/{hash}/start    start
+	POST   /api/v1/torrents/{hash}/stop     stop
+	PUT    /api/v1/torrents/{hash}/limits   {"download": bytes/s, "upload": bytes/s}
+	POST   /api/v1/torrents/{hash}/tags     {"add": [...], "remove": [...]}
+	DELETE /api/v1/torrents/{hash}          delete (?delete_files=true needs PermDeleteFiles)
+*/
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// API is the part of *qbt.Client used by the gateway.
+type API interface {
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error)
+	AddTorrentLink(opts qbt.TorrentConfig) error
+	AddTorrentFile(filename string, torrent []byte, opts qbt.TorrentConfig) error
+	StartTorrents(hash string) error
+	StopTorrents(hash string) error
+	DeleteTorrents(hash string, deleteFiles bool) error
+	SetTorrentDownloadLimit(hash string, limit int) error
+	SetTorrentUploadLimit(hash string, limit int) error
+	AddTorrentTags(hash string, tags []string) error
+	DeleteTorrentTags(hash string, tags []string) error
+}
+
+// Permission allows a group of operations.
+type Permission string
+
+const (
+	PermRead        Permission = "read"         // List and get torrents and files
+	PermAdd         Permission = "add"          // Add torrents
+	PermControl     Permission = "control"      // Start, stop, limit and tag torrents
+	PermDelete      Permission = "delete"       // Delete torrents, keeping their data
+	PermDeleteFiles Permission = "delete_files" // Delete torrents with their data
+)
+
+// Key is an API key and its scope.
+type Key struct {
+	Name        string       // Identifies the tenant in the audit log
+	Key         string       // Secret sent by the client
+	Categories  []string     // Torrents in any of these categories are in scope
+	Tags        []string     // Torrents with any of these tags are in scope
+	All         bool         // Every torrent is in scope; Categories and Tags are ignored
+	Permissions []Permission // Allowed operations (default: PermRead)
+}
+
+// Config configures a gateway.
+type Config struct {
+	Keys   []Key            // API keys; requests without a matching key are rejected
+	Audit  io.Writer        // Receives every request as a JSON line (optional)
+	Logger *slog.Logger     // Receives upstream errors (default: discard)
+	Now    func() time.Time // Clock for audit entries (default: time.Now)
+}
+
+// AuditEntry is one line of the audit log.
+type AuditEntry struct {
+	Time    time.Time   `json:"time"`
+	Key     string      `json:"key"`
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Action  string      `json:"action"`
+	Hash    string      `json:"hash,omitempty"`
+	Status  int         `json:"status"`
+	Error   string      `json:"error,omitempty"`
+	Remote  string      `json:"remote"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// Gateway is the REST handler.
+type Gateway struct {
+	api    API
+	config Config
+	logger *slog.Logger
+	mux    *http.ServeMux
+
+	auditMu sync.Mutex
+}
+
+// New creates a gateway for the instance behind api.
+func New(api API, config Config) *Gateway {
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	g := &Gateway{api: api, config: config, logger: logger, mux: http.NewServeMux()}
+
+	g.handle("GET /api/v1/torrents", "list", PermRead, g.list)
+	g.handle("POST /api/v1/torrents", "add", PermAdd, g.add)
+	g.handle("GET /api/v1/torrents/{hash}", "get", PermRead, g.get)
+	g.handle("GET /api/v1/torrents/{hash}/files", "files", PermRead, g.files)
+	g.handle("POST /api/v1/torrents/{hash}/start", "start", PermControl, g.start)
+	g.handle("POST /api/v1/torrents/{hash}/stop", "stop", PermControl, g.stop)
+	g.handle("PUT /api/v1/torrents/{hash}/limits", "limits", PermControl, g.limits)
+	g.handle("POST /api/v1/torrents/{hash}/tags", "tags", PermControl, g.tags)
+	g.handle("DELETE /api/v1/torrents/{hash}", "delete", PermDelete, g.delete)
+	return g
+}
+
+// ServeHTTP routes a request.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// call is the state of one request.
+type call struct {
+	key    *Key
+	w      http.ResponseWriter
+	r      *http.Request
+	entry  AuditEntry
+	logger *slog.Logger
+}
+
+// handlerFunc handles an authenticated and authorized request.
+type handlerFunc func(c *call)
+
+func (g *Gateway) handle(pattern, action string, perm Permission, fn handlerFunc) {
+	g.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		c := &call{w: w, r: r, logger: g.logger, entry: AuditEntry{
+			Time:   g.config.Now(),
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Action: action,
+			Hash:   r.PathValue("hash"),
+			Remote: r.RemoteAddr,
+		}}
+		defer g.audit(&c.entry)
+
+		key := g.authenticate(r)
+		if key == nil {
+			c.fail(http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+		c.key = key
+		c.entry.Key = key.Name
+
+		if !key.allows(perm) {
+			c.fail(http.StatusForbidden, "permission "+string(perm)+" required")
+			return
+		}
+		fn(c)
+	})
+}
+
+// authenticate returns the key presented by r, or nil.
+func (g *Gateway) authenticate(r *http.Request) *Key {
+	presented := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); presented == "" && strings.HasPrefix(auth, "Bearer ") {
+		presented = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if presented == "" {
+		return nil
+	}
+
+	var found *Key
+	for i := range g.config.Keys {
+		// Compare with every key so the timing does not reveal which matched
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(g.config.Keys[i].Key)) == 1 && g.config.Keys[i].Key != "" {
+			found = &g.config.Keys[i]
+		}
+	}
+	return found
+}
+
+func (k *Key) allows(perm Permission) bool {
+	if len(k.Permissions) == 0 {
+		return perm == PermRead
+	}
+	if perm == PermDelete && slices.Contains(k.Permissions, PermDeleteFiles) {
+		return true
+	}
+	return slices.Contains(k.Permissions, perm)
+}
+
+// scopes reports whether t is visible to the key.
+func (k *Key) scopes(t *qbt.TorrentResponse) bool {
+	if k.All {
+		return true
+	}
+	if slices.Contains(k.Categories, t.Category) {
+		return true
+	}
+	for _, tag := range splitTags(t.Tags) {
+		if slices.Contains(k.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// foreignTag returns the first of tags that scopes another key but not k, or
+// "". Setting such a tag would put the torrent in the other key's scope, and
+// removing it would take the torrent out of that scope.
+func (g *Gateway) foreignTag(k *Key, tags []string) string {
+	if k.All {
+		return ""
+	}
+	for _, tag := range tags {
+		if slices.Contains(k.Tags, tag) {
+			continue
+		}
+		for _, other := range g.config.Keys {
+			if !other.All && slices.Contains(other.Tags, tag) {
+				return tag
+			}
+		}
+	}
+	return ""
+}
+
+func (g *Gateway) audit(entry *AuditEntry) {
+	if g.config.Audit == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	g.auditMu.Lock()
+	defer g.auditMu.Unlock()
+	g.config.Audit.Write(append(data, '\n'))
+}
+
+func (c *call) respond(status int, body interface{}) {
+	c.entry.Status = status
+	writeJSON(c.w, status, body)
+}
+
+func (c *call) fail(status int, message string) {
+	c.entry.Status = status
+	c.entry.Error = message
+	writeError(c.w, status, message)
+}
+
+// upstream reports a failed qBittorrent call without exposing its details.
+func (c *call) upstream(err error) {
+	c.logger.Warn("gateway upstream call failed", "key", c.key.Name, "action", c.entry.Action, qbt.LogKeyError, err)
+	c.entry.Status = http.StatusBadGateway
+	c.entry.Error = err.Error()
+	writeError(c.w, http.StatusBadGateway, "qBittorrent request failed")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func splitTags(tags string) []string {
+	var result []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// normalizeTags splits and trims tags the way qBittorrent parses its tags
+// parameter, so scope checks see the tags that will actually be set.
+func normalizeTags(tags []string) []string {
+	var result []string
+	for _, tag := range tags {
+		result = append(result, splitTags(tag)...)
+	}
+	return result
+}
@@ -0,0 +1,264 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// fakeAPI records the calls made by the gateway.
+type fakeAPI struct {
+	torrents []*qbt.TorrentResponse
+	calls    []string
+	fail     bool
+}
+
+func (f *fakeAPI) record(format string, args ...interface{}) error {
+	if f.fail {
+		return fmt.Errorf("connection refused")
+	}
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+	return nil
+}
+
+func (f *fakeAPI) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	var result []*qbt.TorrentResponse
+	for _, t := range f.torrents {
+		if len(opts.Hashes) == 0 || opts.Hashes[0] == t.Hash {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeAPI) ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error) {
+	return []*qbt.TorrentFile{{Name: "a.mkv", Size: 1}}, nil
+}
+
+func (f *fakeAPI) AddTorrentLink(opts qbt.TorrentConfig) error {
+	return f.record("add %s category=%s tags=%v", opts.MagnetURI, opts.Category, opts.Tags)
+}
+
+func (f *fakeAPI) AddTorrentFile(filename string, torrent []byte, opts qbt.TorrentConfig) error {
+	return f.record("add-file %s category=%s", torrent, opts.Category)
+}
+
+func (f *fakeAPI) StartTorrents(hash string) error { return f.record("start %s", hash) }
+func (f *fakeAPI) StopTorrents(hash string) error  { return f.record("stop %s", hash) }
+
+func (f *fakeAPI) DeleteTorrents(hash string, deleteFiles bool) error {
+	return f.record("delete %s files=%v", hash, deleteFiles)
+}
+
+func (f *fakeAPI) SetTorrentDownloadLimit(hash string, limit int) error {
+	return f.record("dl-limit %s %d", hash, limit)
+}
+
+func (f *fakeAPI) SetTorrentUploadLimit(hash string, limit int) error {
+	return f.record("up-limit %s %d", hash, limit)
+}
+
+func (f *fakeAPI) AddTorrentTags(hash string, tags []string) error {
+	return f.record("tag %s %v", hash, tags)
+}
+
+func (f *fakeAPI) DeleteTorrentTags(hash string, tags []string) error {
+	return f.record("untag %s %v", hash, tags)
+}
+
+type testGateway struct {
+	api   *fakeAPI
+	audit bytes.Buffer
+	gw    *Gateway
+}
+
+func newTestGateway() *testGateway {
+	tg := &testGateway{api: &fakeAPI{torrents: []*qbt.TorrentResponse{
+		{Hash: "a1", Name: "A one", Category: "team-a"},
+		{Hash: "a2", Name: "A two", Category: "team-a", Tags: "shared"},
+		{Hash: "b1", Name: "B one", Category: "team-b", Tags: "shared, keep"},
+	}}}
+	tg.gw = New(tg.api, Config{
+		Keys: []Key{
+			{Name: "team-a", Key: "key-a", Categories: []string{"team-a"}, Permissions: []Permission{PermRead, PermAdd, PermControl}},
+			{Name: "shared", Key: "key-s", Tags: []string{"shared"}, Permissions: []Permission{PermRead, PermAdd, PermControl, PermDelete}},
+			{Name: "reader", Key: "key-r", All: true},
+		},
+		Audit: &tg.audit,
+		Now:   func() time.Time { return time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC) },
+	})
+	return tg
+}
+
+func (tg *testGateway) do(method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	tg.gw.ServeHTTP(rec, req)
+	return rec
+}
+
+func names(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var torrents []qbt.TorrentResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &torrents); err != nil {
+		t.Fatalf("Invalid list response %q: %v", rec.Body.String(), err)
+	}
+	var result []string
+	for _, tr := range torrents {
+		result = append(result, tr.Hash)
+	}
+	return strings.Join(result, ",")
+}
+
+func TestScoping(t *testing.T) {
+	tg := newTestGateway()
+
+	if rec := tg.do("GET", "/api/v1/torrents", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a key, got %d", rec.Code)
+	}
+	if rec := tg.do("GET", "/api/v1/torrents", "wrong", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong key, got %d", rec.Code)
+	}
+
+	testCases := []struct {
+		key, query, expected string
+	}{
+		{"key-a", "", "a1,a2"},
+		{"key-s", "", "a2,b1"},
+		{"key-r", "", "a1,a2,b1"},
+		{"key-r", "?offset=1&limit=1", "a2"},
+		{"key-a", "?offset=1", "a2"},
+	}
+	for _, tc := range testCases {
+		rec := tg.do("GET", "/api/v1/torrents"+tc.query, tc.key, "")
+		if rec.Code != http.StatusOK || names(t, rec) != tc.expected {
+			t.Errorf("%s%s: expected %s, got %d %s", tc.key, tc.query, tc.expected, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Out-of-scope torrents look like missing ones
+	if rec := tg.do("GET", "/api/v1/torrents/b1", "key-a", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another team's torrent, got %d", rec.Code)
+	}
+	if rec := tg.do("POST", "/api/v1/torrents/b1/stop", "key-a", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 when stopping another team's torrent, got %d", rec.Code)
+	}
+	if rec := tg.do("GET", "/api/v1/torrents/b1/files", "key-s", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected tag-scoped access, got %d", rec.Code)
+	}
+	if len(tg.api.calls) != 0 {
+		t.Errorf("Expected no modifying calls, got %v", tg.api.calls)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	tg := newTestGateway()
+
+	testCases := []struct {
+		method, path, key, body string
+		expected                int
+	}{
+		{"POST", "/api/v1/torrents/a1/stop", "key-r", "", http.StatusForbidden},
+		{"DELETE", "/api/v1/torrents/a1", "key-a", "", http.StatusForbidden},
+		{"DELETE", "/api/v1/torrents/b1?delete_files=true", "key-s", "", http.StatusForbidden},
+		{"POST", "/api/v1/torrents/b1/tags", "key-s", `{"remove": ["shared"]}`, http.StatusForbidden},
+		{"POST", "/api/v1/torrents", "key-a", `{"urls": ["magnet:?xt=x"], "category": "team-b"}`, http.StatusForbidden},
+		{"POST", "/api/v1/torrents", "key-a", `{"urls": ["magnet:?xt=x"], "tags": ["shared"]}`, http.StatusForbidden},
+		{"POST", "/api/v1/torrents/a1/tags", "key-a", `{"add": ["shared"]}`, http.StatusForbidden},
+		{"POST", "/api/v1/torrents/a1/tags", "key-a", `{"add": ["x,shared"]}`, http.StatusForbidden},
+		{"POST", "/api/v1/torrents/a1/tags", "key-a", `{"add": [" shared"]}`, http.StatusForbidden},
+		{"POST", "/api/v1/torrents/b1/tags", "key-s", `{"remove": ["keep,shared"]}`, http.StatusForbidden},
+		{"POST", "/api/v1/torrents/a2/tags", "key-a", `{"remove": ["shared"]}`, http.StatusForbidden},
+		{"POST", "/api/v1/torrents/a2/tags", "key-a", `{"remove": ["x, shared"]}`, http.StatusForbidden},
+		{"POST", "/api/v1/torrents", "key-a", `{"urls": ["magnet:?xt=x"], "tags": ["x, shared"]}`, http.StatusForbidden},
+		{"POST", "/api/v1/torrents", "key-a", `{}`, http.StatusBadRequest},
+		{"PUT", "/api/v1/torrents/a1/limits", "key-a", `not json`, http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		if rec := tg.do(tc.method, tc.path, tc.key, tc.body); rec.Code != tc.expected {
+			t.Errorf("%s %s as %s: expected %d, got %d %s", tc.method, tc.path, tc.key, tc.expected, rec.Code, rec.Body.String())
+		}
+	}
+	if len(tg.api.calls) != 0 {
+		t.Errorf("Expected no calls, got %v", tg.api.calls)
+	}
+}
+
+func TestOperations(t *testing.T) {
+	tg := newTestGateway()
+
+	requests := []struct {
+		method, path, key, body string
+		expected                int
+	}{
+		{"POST", "/api/v1/torrents", "key-a", `{"urls": ["magnet:?xt=1"], "tags": ["x"]}`, http.StatusAccepted},
+		{"POST", "/api/v1/torrents", "key-s", `{"urls": ["magnet:?xt=2"]}`, http.StatusAccepted},
+		{"POST", "/api/v1/torrents", "key-a", `{"torrent": "ZGF0YQ=="}`, http.StatusAccepted},
+		{"POST", "/api/v1/torrents/a1/start", "key-a", "", http.StatusNoContent},
+		{"PUT", "/api/v1/torrents/a2/limits", "key-a", `{"upload": 1024}`, http.StatusNoContent},
+		{"POST", "/api/v1/torrents/b1/tags", "key-s", `{"add": ["done, later"], "remove": ["keep"]}`, http.StatusNoContent},
+		{"DELETE", "/api/v1/torrents/b1", "key-s", "", http.StatusNoContent},
+	}
+	for _, r := range requests {
+		if rec := tg.do(r.method, r.path, r.key, r.body); rec.Code != r.expected {
+			t.Errorf("%s %s: expected %d, got %d %s", r.method, r.path, r.expected, rec.Code, rec.Body.String())
+		}
+	}
+
+	expected := []string{
+		"add magnet:?xt=1 category=team-a tags=[x]",
+		"add magnet:?xt=2 category= tags=[shared]",
+		"add-file data category=team-a",
+		"start a1",
+		"up-limit a2 1024",
+		"untag b1 [keep]",
+		"tag b1 [done later]",
+		"delete b1 files=false",
+	}
+	if strings.Join(tg.api.calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(tg.api.calls, "\n"))
+	}
+}
+
+func TestAudit(t *testing.T) {
+	tg := newTestGateway()
+	tg.do("GET", "/api/v1/torrents", "bad", "")
+	tg.do("POST", "/api/v1/torrents/a1/stop", "key-a", "")
+	tg.api.fail = true
+	rec := tg.do("POST", "/api/v1/torrents/a1/start", "key-a", "")
+	if rec.Code != http.StatusBadGateway || strings.Contains(rec.Body.String(), "connection refused") {
+		t.Errorf("Expected a 502 without upstream details, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(tg.audit.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 audit lines, got %q", tg.audit.String())
+	}
+	var entries []AuditEntry
+	for _, line := range lines {
+		var e AuditEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Audit line is not JSON: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	if entries[0].Status != http.StatusUnauthorized || entries[0].Key != "" {
+		t.Errorf("Unexpected entry %+v", entries[0])
+	}
+	if entries[1].Key != "team-a" || entries[1].Action != "stop" || entries[1].Hash != "a1" || entries[1].Status != http.StatusNoContent {
+		t.Errorf("Unexpected entry %+v", entries[1])
+	}
+	if entries[2].Status != http.StatusBadGateway || entries[2].Error != "connection refused" {
+		t.Errorf("Unexpected entry %+v", entries[2])
+	}
+}
@@ -0,0 +1,291 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// maxBody bounds request bodies, which may carry a .torrent file.
+const maxBody = 16 << 20
+
+func (c *call) decode(v interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(c.w, c.r.Body, maxBody)).Decode(v); err != nil {
+		c.fail(http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// torrent returns the torrent named in the path if it is in scope. Torrents
+// out of scope are reported as not found.
+func (g *Gateway) torrent(c *call) *qbt.TorrentResponse {
+	hash := strings.ToLower(c.r.PathValue("hash"))
+	torrents, err := g.api.ListTorrents(qbt.ListOptions{Hashes: []string{hash}})
+	if err != nil {
+		c.upstream(err)
+		return nil
+	}
+	for _, t := range torrents {
+		if strings.EqualFold(t.Hash, hash) && c.key.scopes(t) {
+			return t
+		}
+	}
+	c.fail(http.StatusNotFound, "torrent not found")
+	return nil
+}
+
+func (g *Gateway) list(c *call) {
+	q := c.r.URL.Query()
+	opts := qbt.ListOptions{
+		Filter:   q.Get("filter"),
+		Category: q.Get("category"),
+		Tag:      q.Get("tag"),
+		Sort:     q.Get("sort"),
+		Reverse:  q.Get("reverse") == "true",
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+
+	// Scoping happens here, so paging has to as well
+	torrents, err := g.api.ListTorrents(opts)
+	if err != nil {
+		c.upstream(err)
+		return
+	}
+	visible := []*qbt.TorrentResponse{}
+	for _, t := range torrents {
+		if c.key.scopes(t) {
+			visible = append(visible, t)
+		}
+	}
+	if offset > 0 {
+		visible = visible[min(offset, len(visible)):]
+	}
+	if limit > 0 {
+		visible = visible[:min(limit, len(visible))]
+	}
+	c.respond(http.StatusOK, visible)
+}
+
+func (g *Gateway) get(c *call) {
+	if t := g.torrent(c); t != nil {
+		c.respond(http.StatusOK, t)
+	}
+}
+
+func (g *Gateway) files(c *call) {
+	t := g.torrent(c)
+	if t == nil {
+		return
+	}
+	files, err := g.api.ListTorrentFiles(t.Hash)
+	if err != nil {
+		c.upstream(err)
+		return
+	}
+	c.respond(http.StatusOK, files)
+}
+
+// AddRequest is the body of POST /api/v1/torrents.
+type AddRequest struct {
+	URLs     []string `json:"urls"`     // Magnet links or HTTP(S) URLs
+	Torrent  string   `json:"torrent"`  // Base64-encoded .torrent file
+	Category string   `json:"category"` // Must be one of the key's categories (default: the first)
+	Tags     []string `json:"tags"`
+	Paused   bool     `json:"paused"`
+}
+
+func (g *Gateway) add(c *call) {
+	var req AddRequest
+	if !c.decode(&req) {
+		return
+	}
+	if len(req.URLs) == 0 && req.Torrent == "" {
+		c.fail(http.StatusBadRequest, "urls or torrent required")
+		return
+	}
+
+	req.Tags = normalizeTags(req.Tags)
+	if tag := g.foreignTag(c.key, req.Tags); tag != "" {
+		c.fail(http.StatusForbidden, "tag "+tag+" is out of scope")
+		return
+	}
+
+	config := qbt.TorrentConfig{Category: req.Category, Tags: req.Tags, Paused: req.Paused}
+	if !c.key.All {
+		switch {
+		case len(c.key.Categories) > 0 && req.Category == "":
+			config.Category = c.key.Categories[0]
+		case len(c.key.Categories) > 0 && !slices.Contains(c.key.Categories, req.Category):
+			c.fail(http.StatusForbidden, "category "+req.Category+" is out of scope")
+			return
+		case len(c.key.Categories) == 0 && len(c.key.Tags) > 0:
+			// Scoped by tags only: the added torrent must carry one
+			if !slices.ContainsFunc(req.Tags, func(tag string) bool { return slices.Contains(c.key.Tags, tag) }) {
+				config.Tags = append(config.Tags, c.key.Tags[0])
+			}
+			if req.Category != "" {
+				c.fail(http.StatusForbidden, "category "+req.Category+" is out of scope")
+				return
+			}
+		case len(c.key.Categories) == 0:
+			c.fail(http.StatusForbidden, "key has no scope to add torrents to")
+			return
+		}
+	}
+	c.entry.Details = map[string]interface{}{"urls": req.URLs, "category": config.Category, "tags": config.Tags, "file": req.Torrent != ""}
+
+	if req.Torrent != "" {
+		data, err := base64.StdEncoding.DecodeString(req.Torrent)
+		if err != nil {
+			c.fail(http.StatusBadRequest, "torrent is not valid base64")
+			return
+		}
+		if err := g.api.AddTorrentFile("upload.torrent", data, config); err != nil {
+			c.upstream(err)
+			return
+		}
+	}
+	for _, u := range req.URLs {
+		config.MagnetURI = u
+		if err := g.api.AddTorrentLink(config); err != nil {
+			c.upstream(err)
+			return
+		}
+	}
+	c.respond(http.StatusAccepted, map[string]interface{}{"category": config.Category, "tags": config.Tags})
+}
+
+func (g *Gateway) start(c *call) {
+	g.simple(c, g.api.StartTorrents)
+}
+
+func (g *Gateway) stop(c *call) {
+	g.simple(c, g.api.StopTorrents)
+}
+
+func (g *Gateway) simple(c *call, fn func(hash string) error) {
+	t := g.torrent(c)
+	if t == nil {
+		return
+	}
+	if err := fn(t.Hash); err != nil {
+		c.upstream(err)
+		return
+	}
+	c.respond(http.StatusNoContent, nil)
+}
+
+// LimitsRequest is the body of PUT /api/v1/torrents/{hash}/limits. Omitted
+// limits are unchanged; 0 removes a limit.
+type LimitsRequest struct {
+	Download *int `json:"download"` // Bytes/s
+	Upload   *int `json:"upload"`   // Bytes/s
+}
+
+func (g *Gateway) limits(c *call) {
+	var req LimitsRequest
+	if !c.decode(&req) {
+		return
+	}
+	t := g.torrent(c)
+	if t == nil {
+		return
+	}
+	c.entry.Details = req
+
+	if req.Download != nil {
+		if err := g.api.SetTorrentDownloadLimit(t.Hash, *req.Download); err != nil {
+			c.upstream(err)
+			return
+		}
+	}
+	if req.Upload != nil {
+		if err := g.api.SetTorrentUploadLimit(t.Hash, *req.Upload); err != nil {
+			c.upstream(err)
+			return
+		}
+	}
+	c.respond(http.StatusNoContent, nil)
+}
+
+// TagsRequest is the body of POST /api/v1/torrents/{hash}/tags.
+type TagsRequest struct {
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+func (g *Gateway) tags(c *call) {
+	var req TagsRequest
+	if !c.decode(&req) {
+		return
+	}
+	t := g.torrent(c)
+	if t == nil {
+		return
+	}
+	req.Add = normalizeTags(req.Add)
+	req.Remove = normalizeTags(req.Remove)
+	c.entry.Details = req
+
+	// Adding another key's scope tag would hand the torrent over, removing
+	// one would take it away from that key
+	if tag := g.foreignTag(c.key, append(slices.Clone(req.Add), req.Remove...)); tag != "" {
+		c.fail(http.StatusForbidden, "tag "+tag+" is out of scope")
+		return
+	}
+
+	// A torrent must not leave the key's scope by losing its last scope tag
+	if !c.key.All && !slices.Contains(c.key.Categories, t.Category) {
+		var remaining []string
+		for _, tag := range splitTags(t.Tags) {
+			if !slices.Contains(req.Remove, tag) {
+				remaining = append(remaining, tag)
+			}
+		}
+		remaining = append(remaining, req.Add...)
+		if !slices.ContainsFunc(remaining, func(tag string) bool { return slices.Contains(c.key.Tags, tag) }) {
+			c.fail(http.StatusForbidden, "removing these tags would take the torrent out of scope")
+			return
+		}
+	}
+
+	if len(req.Remove) > 0 {
+		if err := g.api.DeleteTorrentTags(t.Hash, req.Remove); err != nil {
+			c.upstream(err)
+			return
+		}
+	}
+	if len(req.Add) > 0 {
+		if err := g.api.AddTorrentTags(t.Hash, req.Add); err != nil {
+			c.upstream(err)
+			return
+		}
+	}
+	c.respond(http.StatusNoContent, nil)
+}
+
+func (g *Gateway) delete(c *call) {
+	deleteFiles := c.r.URL.Query().Get("delete_files") == "true"
+	if deleteFiles && !c.key.allows(PermDeleteFiles) {
+		c.fail(http.StatusForbidden, "permission "+string(PermDeleteFiles)+" required")
+		return
+	}
+	t := g.torrent(c)
+	if t == nil {
+		return
+	}
+	c.entry.Details = map[string]interface{}{"delete_files": deleteFiles, "name": t.Name}
+
+	if err := g.api.DeleteTorrents(t.Hash, deleteFiles); err != nil {
+		c.upstream(err)
+		return
+	}
+	c.respond(http.StatusNoContent, nil)
+}
@@ -0,0 +1,192 @@
+/*
+Package health serves liveness and readiness endpoints for a client.
+
+Both handlers refresh the connection status with RefreshConnectionStatus and
+answer with a JSON Report. Liveness fails (503) only when the instance is
+unaccessible or authentication failed permanently; readiness also requires
+the client to be connected and runs the optional checks:
+
+	checker := health.New(client, health.Config{
+		MinFreeSpace:       10 << 30, // Not ready below 10 GiB free
+		MinDHTNodes:        1,
+		RequireConnectable: true, // Not ready while firewalled
+	})
+	http.Handle("/livez", checker.Liveness())
+	http.Handle("/readyz", checker.Readiness())
+*/
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// Report statuses
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Names of the optional checks in Report.Checks
+const (
+	CheckFreeSpace   = "free_space"
+	CheckDHTNodes    = "dht_nodes"
+	CheckConnectable = "connectable"
+)
+
+// qBittorrent's connection_status values
+const (
+	connectionConnected  = "connected"
+	connectionFirewalled = "firewalled"
+)
+
+// API is the part of *qbt.Client used by the checker.
+type API interface {
+	RefreshConnectionStatus(ctx context.Context) *qbt.ConnectionStatus
+	GetMainData() (*qbt.MainDataResponse, error)
+	GetTransferInfo() (*qbt.TransferInfoResponse, error)
+}
+
+// Config enables the optional readiness checks.
+type Config struct {
+	MinFreeSpace       int64 // Free bytes required on the download disk (default: 0, unchecked)
+	MinDHTNodes        int   // DHT nodes required (default: 0, unchecked)
+	RequireConnectable bool  // Fail while qBittorrent reports itself firewalled or disconnected
+}
+
+// Check is the outcome of one optional check.
+type Check struct {
+	Status  string      `json:"status"`
+	Value   interface{} `json:"value,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// Report is the body of both endpoints.
+type Report struct {
+	Status     string                `json:"status"`
+	Connection *qbt.ConnectionStatus `json:"connection"`
+	Checks     map[string]*Check     `json:"checks,omitempty"`
+}
+
+// Checker computes liveness and readiness for one instance.
+type Checker struct {
+	api    API
+	config Config
+}
+
+// New creates a checker for the instance behind api.
+func New(api API, config Config) *Checker {
+	return &Checker{api: api, config: config}
+}
+
+// Live reports whether the instance is reachable with usable credentials.
+// Transient states such as initializing or a failed request that will be
+// retried still count as alive.
+func (c *Checker) Live(ctx context.Context) *Report {
+	conn := c.api.RefreshConnectionStatus(ctx)
+	report := &Report{Status: StatusOK, Connection: conn}
+	if conn.Status == qbt.StatusUnaccessible || (conn.Status == qbt.StatusUnauthorized && conn.Permanent) {
+		report.Status = StatusFail
+	}
+	return report
+}
+
+// Ready reports whether the instance is connected and passes the
+// configured checks. Checks are skipped while the client is not connected.
+func (c *Checker) Ready(ctx context.Context) *Report {
+	conn := c.api.RefreshConnectionStatus(ctx)
+	report := &Report{Status: StatusOK, Connection: conn, Checks: make(map[string]*Check)}
+	if conn.Status != qbt.StatusConnected {
+		report.Status = StatusFail
+		return report
+	}
+
+	if c.config.MinFreeSpace > 0 {
+		report.Checks[CheckFreeSpace] = c.checkFreeSpace()
+	}
+	if c.config.MinDHTNodes > 0 || c.config.RequireConnectable {
+		info, err := c.api.GetTransferInfo()
+		if c.config.MinDHTNodes > 0 {
+			report.Checks[CheckDHTNodes] = c.checkDHTNodes(info, err)
+		}
+		if c.config.RequireConnectable {
+			report.Checks[CheckConnectable] = checkConnectable(info, err)
+		}
+	}
+
+	for _, check := range report.Checks {
+		if check.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (c *Checker) checkFreeSpace() *Check {
+	data, err := c.api.GetMainData()
+	if err != nil {
+		return failed(fmt.Errorf("failed to get free space: %w", err))
+	}
+	free := int64(data.ServerState.FreeSpaceOnDisk)
+	if free < c.config.MinFreeSpace {
+		return &Check{Status: StatusFail, Value: free, Message: fmt.Sprintf("below %d bytes", c.config.MinFreeSpace)}
+	}
+	return &Check{Status: StatusOK, Value: free}
+}
+
+func (c *Checker) checkDHTNodes(info *qbt.TransferInfoResponse, err error) *Check {
+	if err != nil {
+		return failed(fmt.Errorf("failed to get transfer info: %w", err))
+	}
+	if info.DhtNodes < c.config.MinDHTNodes {
+		return &Check{Status: StatusFail, Value: info.DhtNodes, Message: fmt.Sprintf("below %d nodes", c.config.MinDHTNodes)}
+	}
+	return &Check{Status: StatusOK, Value: info.DhtNodes}
+}
+
+func checkConnectable(info *qbt.TransferInfoResponse, err error) *Check {
+	if err != nil {
+		return failed(fmt.Errorf("failed to get transfer info: %w", err))
+	}
+	if info.ConnectionStatus != connectionConnected {
+		message := "no incoming connections"
+		if info.ConnectionStatus == connectionFirewalled {
+			message = "firewalled"
+		}
+		return &Check{Status: StatusFail, Value: info.ConnectionStatus, Message: message}
+	}
+	return &Check{Status: StatusOK, Value: info.ConnectionStatus}
+}
+
+func failed(err error) *Check {
+	return &Check{Status: StatusFail, Message: err.Error()}
+}
+
+// Liveness returns a handler serving Live: 200 when alive, 503 otherwise.
+func (c *Checker) Liveness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		write(w, c.Live(r.Context()))
+	})
+}
+
+// Readiness returns a handler serving Ready: 200 when ready, 503 otherwise.
+func (c *Checker) Readiness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		write(w, c.Ready(r.Context()))
+	})
+}
+
+func write(w http.ResponseWriter, report *Report) {
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+type fakeAPI struct {
+	status    qbt.ConnectionStatus
+	free      int
+	dhtNodes  int
+	conn      string
+	infoErr   error
+	infoCalls int
+}
+
+func (f *fakeAPI) RefreshConnectionStatus(ctx context.Context) *qbt.ConnectionStatus {
+	status := f.status
+	return &status
+}
+
+func (f *fakeAPI) GetMainData() (*qbt.MainDataResponse, error) {
+	return &qbt.MainDataResponse{ServerState: qbt.MainDataServerStateResponse{FreeSpaceOnDisk: f.free}}, nil
+}
+
+func (f *fakeAPI) GetTransferInfo() (*qbt.TransferInfoResponse, error) {
+	f.infoCalls++
+	if f.infoErr != nil {
+		return nil, f.infoErr
+	}
+	return &qbt.TransferInfoResponse{DhtNodes: f.dhtNodes, ConnectionStatus: f.conn}, nil
+}
+
+func serve(t *testing.T, h http.Handler) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid report %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, report
+}
+
+func TestLiveness(t *testing.T) {
+	testCases := []struct {
+		status   qbt.ConnectionStatus
+		expected int
+	}{
+		{qbt.ConnectionStatus{Status: qbt.StatusConnected}, http.StatusOK},
+		{qbt.ConnectionStatus{Status: qbt.StatusInitializing}, http.StatusOK},
+		{qbt.ConnectionStatus{Status: qbt.StatusUnauthorized}, http.StatusOK},
+		{qbt.ConnectionStatus{Status: qbt.StatusUnauthorized, Permanent: true}, http.StatusServiceUnavailable},
+		{qbt.ConnectionStatus{Status: qbt.StatusUnaccessible}, http.StatusServiceUnavailable},
+	}
+	for _, tc := range testCases {
+		checker := New(&fakeAPI{status: tc.status}, Config{})
+		code, report := serve(t, checker.Liveness())
+		if code != tc.expected || report.Connection.Status != tc.status.Status {
+			t.Errorf("%+v: expected %d, got %d %+v", tc.status, tc.expected, code, report)
+		}
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	config := Config{MinFreeSpace: 100, MinDHTNodes: 5, RequireConnectable: true}
+
+	api := &fakeAPI{status: qbt.ConnectionStatus{Status: qbt.StatusConnected}, free: 200, dhtNodes: 10, conn: "connected"}
+	code, report := serve(t, New(api, config).Readiness())
+	if code != http.StatusOK || len(report.Checks) != 3 || api.infoCalls != 1 {
+		t.Errorf("Expected ready with 3 checks and one transfer info call, got %d %+v (%d calls)", code, report, api.infoCalls)
+	}
+
+	api.conn = "firewalled"
+	code, report = serve(t, New(api, config).Readiness())
+	if code != http.StatusServiceUnavailable || report.Checks[CheckConnectable].Message != "firewalled" || report.Checks[CheckDHTNodes].Status != StatusOK {
+		t.Errorf("Expected firewalled to fail readiness, got %d %+v", code, report.Checks[CheckConnectable])
+	}
+
+	api.conn, api.free, api.dhtNodes = "connected", 50, 0
+	code, report = serve(t, New(api, config).Readiness())
+	if code != http.StatusServiceUnavailable || report.Checks[CheckFreeSpace].Status != StatusFail || report.Checks[CheckDHTNodes].Status != StatusFail {
+		t.Errorf("Expected free space and DHT checks to fail, got %d %+v", code, report)
+	}
+
+	api.infoErr = fmt.Errorf("timeout")
+	api.free = 200
+	code, report = serve(t, New(api, Config{RequireConnectable: true}).Readiness())
+	if code != http.StatusServiceUnavailable || report.Checks[CheckConnectable].Message == "" {
+		t.Errorf("Expected an upstream error to fail readiness, got %d %+v", code, report)
+	}
+
+	// Not connected: ready fails without running the checks
+	api = &fakeAPI{status: qbt.ConnectionStatus{Status: qbt.StatusInitializing}}
+	code, report = serve(t, New(api, config).Readiness())
+	if code != http.StatusServiceUnavailable || len(report.Checks) != 0 || api.infoCalls != 0 {
+		t.Errorf("Expected 503 without checks, got %d %+v", code, report)
+	}
+}
@@ -0,0 +1,85 @@
+package qbt
+
+import (
+	"log/slog"
+	"net/url"
+	"os"
+)
+
+// Attribute keys used for structured log records emitted by the client.
+const (
+	LogKeyOperation = "operation"
+	LogKeyAttempt   = "attempt"
+	LogKeyAttempts  = "max_attempts"
+	LogKeyDelay     = "delay"
+	LogKeyErrorCode = "error_code"
+	LogKeyError     = "error"
+	LogKeyBaseURL   = "base_url"
+)
+
+const redacted = "[REDACTED]"
+
+// discardLogger is used whenever logging is disabled or the client was built
+// without going through New (e.g. in tests).
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// newLogger builds the logger used by the client. An explicit Config.Logger
+// always wins; otherwise Debug enables debug-level text output on stderr and
+// everything else is discarded.
+func newLogger(config Config) *slog.Logger {
+	logger := config.Logger
+	if logger == nil {
+		if !config.Debug {
+			return discardLogger
+		}
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	return logger.With(slog.String(LogKeyBaseURL, redactURL(config.BaseURL)))
+}
+
+// log returns the client logger, never nil.
+func (qb *Client) log() *slog.Logger {
+	qb.mu.RLock()
+	defer qb.mu.RUnlock()
+	if qb.logger == nil {
+		return discardLogger
+	}
+	return qb.logger
+}
+
+// errorAttrs returns the error and its classification code as log attributes.
+func errorAttrs(err error) []any {
+	if err == nil {
+		return nil
+	}
+	return []any{
+		slog.String(LogKeyErrorCode, string(GetErrorCode(err))),
+		slog.String(LogKeyError, err.Error()),
+	}
+}
+
+// redactURL strips any password embedded in the URL userinfo.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+// LogValue implements slog.LogValuer so a Config can be logged without leaking credentials.
+func (c Config) LogValue() slog.Value {
+	password := ""
+	if c.Password != "" {
+		password = redacted
+	}
+	return slog.GroupValue(
+		slog.String(LogKeyBaseURL, redactURL(c.BaseURL)),
+		slog.String("username", c.Username),
+		slog.String("password", password),
+		slog.Duration("request_timeout", c.RequestTimeout),
+		slog.Int("max_retries", c.MaxRetries),
+		slog.Duration("retry_backoff", c.RetryBackoff),
+		slog.Int("max_login_retries", c.MaxLoginRetries),
+	)
+}
@@ -0,0 +1,106 @@
+package metainfo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// maxDepth bounds the nesting of lists and dictionaries.
+const maxDepth = 64
+
+var errUnexpectedEnd = errors.New("unexpected end of data")
+
+// decoder reads bencoded values. Integers decode to int64, strings to
+// string, lists to []interface{} and dictionaries to map[string]interface{}.
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) value(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("nesting deeper than %d at offset %d", maxDepth, d.pos)
+	}
+	if d.pos >= len(d.data) {
+		return nil, errUnexpectedEnd
+	}
+
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		d.pos++
+		return d.integer('e')
+	case c == 'l':
+		d.pos++
+		var list []interface{}
+		for {
+			if d.pos >= len(d.data) {
+				return nil, errUnexpectedEnd
+			}
+			if d.data[d.pos] == 'e' {
+				d.pos++
+				return list, nil
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+	case c == 'd':
+		d.pos++
+		dict := make(map[string]interface{})
+		for {
+			if d.pos >= len(d.data) {
+				return nil, errUnexpectedEnd
+			}
+			if d.data[d.pos] == 'e' {
+				d.pos++
+				return dict, nil
+			}
+			key, err := d.string()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = v
+		}
+	case c >= '0' && c <= '9':
+		return d.string()
+	default:
+		return nil, fmt.Errorf("invalid character %q at offset %d", c, d.pos)
+	}
+}
+
+func (d *decoder) string() (string, error) {
+	n, err := d.integer(':')
+	if err != nil {
+		return "", err
+	}
+	if n < 0 || n > int64(len(d.data)-d.pos) {
+		return "", fmt.Errorf("invalid string length %d at offset %d", n, d.pos)
+	}
+	s := string(d.data[d.pos : d.pos+int(n)])
+	d.pos += int(n)
+	return s, nil
+}
+
+// integer reads digits up to the terminator.
+func (d *decoder) integer(terminator byte) (int64, error) {
+	start := d.pos
+	for d.pos < len(d.data) && d.data[d.pos] != terminator {
+		d.pos++
+	}
+	if d.pos >= len(d.data) {
+		return 0, errUnexpectedEnd
+	}
+	n, err := strconv.ParseInt(string(d.data[start:d.pos]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer at offset %d: %w", start, err)
+	}
+	d.pos++
+	return n, nil
+}
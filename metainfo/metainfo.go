@@ -0,0 +1,268 @@
+/*
+Package metainfo parses .torrent files.
+
+Only the parts needed to match torrents against existing data are read:
+the name, the files with their sizes, the piece length and the v1 piece
+hashes. File paths use the layout qBittorrent reports in ListTorrentFiles,
+so multi-file torrents are prefixed with their name:
+
+	info, err := metainfo.Parse(data)
+	fmt.Println(info.InfoHash, info.Name, info.TotalLength())
+*/
+package metainfo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// File is a file in a torrent.
+type File struct {
+	Path    string // Slash-separated, including the torrent name for multi-file torrents
+	Length  int64
+	Padding bool // BEP 47 padding file; not stored on disk or listed by qBittorrent
+}
+
+// MetaInfo is a parsed .torrent file.
+type MetaInfo struct {
+	InfoHash    string     // Hex-encoded SHA-1 of the info dictionary (v1 info hash)
+	Name        string     // Suggested name of the file or top-level directory
+	Announce    string     // Primary tracker URL
+	Private     bool       // Private flag (BEP 27)
+	PieceLength int64      // Bytes per piece
+	Pieces      [][20]byte // SHA-1 of each piece
+	Files       []File     // Files in the order they appear in the pieces
+}
+
+// ParseFile reads and parses a .torrent file.
+func ParseFile(name string) (*MetaInfo, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read torrent file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses the contents of a .torrent file. Torrents without v1 piece
+// hashes (pure v2 torrents) are rejected.
+func Parse(data []byte) (*MetaInfo, error) {
+	if len(data) == 0 || data[0] != 'd' {
+		return nil, errors.New("invalid torrent: not a bencoded dictionary")
+	}
+
+	// Walk the top-level dictionary by hand to keep the raw info bytes,
+	// which the info hash is computed from
+	d := &decoder{data: data, pos: 1}
+	var info map[string]interface{}
+	mi := &MetaInfo{}
+	for {
+		if d.pos >= len(data) {
+			return nil, fmt.Errorf("invalid torrent: %w", errUnexpectedEnd)
+		}
+		if data[d.pos] == 'e' {
+			break
+		}
+		key, err := d.string()
+		if err != nil {
+			return nil, fmt.Errorf("invalid torrent: %w", err)
+		}
+		start := d.pos
+		v, err := d.value(1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid torrent: %w", err)
+		}
+
+		switch key {
+		case "info":
+			dict, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("invalid torrent: info is not a dictionary")
+			}
+			info = dict
+			sum := sha1.Sum(data[start:d.pos])
+			mi.InfoHash = hex.EncodeToString(sum[:])
+		case "announce":
+			mi.Announce, _ = v.(string)
+		}
+	}
+	if info == nil {
+		return nil, errors.New("invalid torrent: missing info dictionary")
+	}
+
+	if err := mi.parseInfo(info); err != nil {
+		return nil, fmt.Errorf("invalid torrent: %w", err)
+	}
+	return mi, nil
+}
+
+func (mi *MetaInfo) parseInfo(info map[string]interface{}) error {
+	name, ok := info["name"].(string)
+	if !ok || name == "" {
+		return errors.New("missing name")
+	}
+	// The name is the file or directory created under the save path
+	if !validPathElement(name) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	mi.Name = name
+
+	private, _ := info["private"].(int64)
+	mi.Private = private == 1
+
+	pieceLength, ok := info["piece length"].(int64)
+	if !ok || pieceLength <= 0 {
+		return errors.New("missing piece length")
+	}
+	mi.PieceLength = pieceLength
+
+	pieces, ok := info["pieces"].(string)
+	if !ok {
+		return errors.New("missing v1 piece hashes")
+	}
+	if len(pieces)%sha1.Size != 0 {
+		return fmt.Errorf("pieces length %d is not a multiple of %d", len(pieces), sha1.Size)
+	}
+	mi.Pieces = make([][20]byte, len(pieces)/sha1.Size)
+	for i := range mi.Pieces {
+		copy(mi.Pieces[i][:], pieces[i*sha1.Size:])
+	}
+
+	if length, ok := info["length"].(int64); ok {
+		if length < 0 {
+			return fmt.Errorf("invalid length %d", length)
+		}
+		mi.Files = []File{{Path: name, Length: length}}
+	} else {
+		files, ok := info["files"].([]interface{})
+		if !ok || len(files) == 0 {
+			return errors.New("missing length and files")
+		}
+		for i, f := range files {
+			file, err := parseFile(name, f)
+			if err != nil {
+				return fmt.Errorf("file %d: %w", i, err)
+			}
+			mi.Files = append(mi.Files, file)
+		}
+	}
+
+	expected := (mi.TotalLength() + mi.PieceLength - 1) / mi.PieceLength
+	if int64(len(mi.Pieces)) != expected {
+		return fmt.Errorf("%d piece hashes for %d pieces", len(mi.Pieces), expected)
+	}
+	return nil
+}
+
+func parseFile(name string, v interface{}) (File, error) {
+	dict, ok := v.(map[string]interface{})
+	if !ok {
+		return File{}, errors.New("not a dictionary")
+	}
+	length, ok := dict["length"].(int64)
+	if !ok || length < 0 {
+		return File{}, errors.New("missing length")
+	}
+	elements, ok := dict["path"].([]interface{})
+	if !ok || len(elements) == 0 {
+		return File{}, errors.New("missing path")
+	}
+
+	parts := []string{name}
+	for _, e := range elements {
+		s, ok := e.(string)
+		if !ok || !validPathElement(s) {
+			return File{}, fmt.Errorf("invalid path element %q", e)
+		}
+		parts = append(parts, s)
+	}
+	attr, _ := dict["attr"].(string)
+	return File{Path: path.Join(parts...), Length: length, Padding: strings.Contains(attr, "p")}, nil
+}
+
+// validPathElement reports whether s is a single file name that stays inside
+// the directory it is joined to.
+func validPathElement(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, "/\\\x00") && filepath.VolumeName(s) == ""
+}
+
+// TotalLength returns the size of all files.
+func (mi *MetaInfo) TotalLength() int64 {
+	var total int64
+	for _, f := range mi.Files {
+		total += f.Length
+	}
+	return total
+}
+
+// VerifyPiece reports whether piece index of the data under dir, the
+// directory the torrent was saved to, matches its hash. A missing or short
+// file is a mismatch, not an error.
+func (mi *MetaInfo) VerifyPiece(dir string, index int) (bool, error) {
+	if index < 0 || index >= len(mi.Pieces) {
+		return false, fmt.Errorf("piece %d out of range", index)
+	}
+	start := int64(index) * mi.PieceLength
+	end := min(start+mi.PieceLength, mi.TotalLength())
+
+	h := sha1.New()
+	var offset int64
+	for _, f := range mi.Files {
+		fileStart, fileEnd := offset, offset+f.Length
+		offset = fileEnd
+		if fileEnd <= start || fileStart >= end {
+			continue
+		}
+
+		from := max(start, fileStart) - fileStart
+		n := min(end, fileEnd) - fileStart - from
+		if f.Padding {
+			// Streamed: the piece length comes from an untrusted file
+			io.CopyN(h, zeroReader{}, n)
+			continue
+		}
+
+		ok, err := readInto(h, filepath.Join(dir, filepath.FromSlash(f.Path)), from, n)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	var sum [20]byte
+	copy(sum[:], h.Sum(nil))
+	return sum == mi.Pieces[index], nil
+}
+
+// zeroReader reads an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// readInto copies n bytes at offset from the file at name to w. It returns
+// false if the file does not exist or is too short.
+func readInto(w io.Writer, name string, offset, n int64) (bool, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	copied, err := io.Copy(w, io.NewSectionReader(f, offset, n))
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return copied == n, nil
+}
@@ -0,0 +1,163 @@
+package metainfo
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// encode bencodes strings, ints, lists and dictionaries for test fixtures.
+func encode(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%d:%s", len(v), v)
+	case int:
+		return fmt.Sprintf("i%de", v)
+	case []interface{}:
+		var b strings.Builder
+		b.WriteByte('l')
+		for _, e := range v {
+			b.WriteString(encode(e))
+		}
+		b.WriteByte('e')
+		return b.String()
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var b strings.Builder
+		b.WriteByte('d')
+		for _, k := range keys {
+			b.WriteString(encode(k) + encode(v[k]))
+		}
+		b.WriteByte('e')
+		return b.String()
+	}
+	panic(fmt.Sprintf("cannot encode %T", v))
+}
+
+func pieceHashes(data []byte, pieceLength int) string {
+	var b strings.Builder
+	for i := 0; i < len(data); i += pieceLength {
+		sum := sha1.Sum(data[i:min(i+pieceLength, len(data))])
+		b.Write(sum[:])
+	}
+	return b.String()
+}
+
+func TestParse(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefgh"), 5) // 40 bytes: 30 in a.bin, 10 in sub/b.bin
+	info := map[string]interface{}{
+		"name":         "Album",
+		"piece length": 16,
+		"pieces":       pieceHashes(content, 16),
+		"private":      1,
+		"files": []interface{}{
+			map[string]interface{}{"length": 30, "path": []interface{}{"a.bin"}},
+			map[string]interface{}{"length": 10, "path": []interface{}{"sub", "b.bin"}},
+		},
+	}
+	data := encode(map[string]interface{}{"announce": "https://tracker.example.org/announce", "info": info})
+
+	mi, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sum := sha1.Sum([]byte(encode(info)))
+	if mi.InfoHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected info hash %x, got %s", sum, mi.InfoHash)
+	}
+	if mi.Name != "Album" || !mi.Private || mi.Announce != "https://tracker.example.org/announce" || len(mi.Pieces) != 3 {
+		t.Errorf("Unexpected metainfo: %+v", mi)
+	}
+	expected := []File{{Path: "Album/a.bin", Length: 30}, {Path: "Album/sub/b.bin", Length: 10}}
+	if fmt.Sprint(mi.Files) != fmt.Sprint(expected) || mi.TotalLength() != 40 {
+		t.Errorf("Expected files %v, got %v", expected, mi.Files)
+	}
+
+	// Verify against data on disk; piece 1 spans both files
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "Album", "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "Album", "a.bin"), content[:30], 0o644)
+	os.WriteFile(filepath.Join(dir, "Album", "sub", "b.bin"), content[30:], 0o644)
+	for i := range mi.Pieces {
+		if ok, err := mi.VerifyPiece(dir, i); !ok || err != nil {
+			t.Errorf("Expected piece %d to verify, got %v %v", i, ok, err)
+		}
+	}
+
+	os.WriteFile(filepath.Join(dir, "Album", "sub", "b.bin"), []byte("corrupted!"), 0o644)
+	if ok, _ := mi.VerifyPiece(dir, 2); ok {
+		t.Error("Expected corrupted piece to fail")
+	}
+	os.Remove(filepath.Join(dir, "Album", "a.bin"))
+	if ok, err := mi.VerifyPiece(dir, 0); ok || err != nil {
+		t.Errorf("Expected missing file to fail without error, got %v %v", ok, err)
+	}
+}
+
+func TestParseSingleFileWithPadding(t *testing.T) {
+	single := encode(map[string]interface{}{"info": map[string]interface{}{
+		"name": "file.iso", "length": 5, "piece length": 16, "pieces": pieceHashes([]byte("hello"), 16),
+	}})
+	mi, err := Parse([]byte(single))
+	if err != nil || len(mi.Files) != 1 || mi.Files[0].Path != "file.iso" {
+		t.Fatalf("Unexpected result: %+v %v", mi, err)
+	}
+
+	content := append([]byte("hello"), make([]byte, 11)...)
+	padded := encode(map[string]interface{}{"info": map[string]interface{}{
+		"name": "Pack", "piece length": 16, "pieces": pieceHashes(append(content, []byte("world")...), 16),
+		"files": []interface{}{
+			map[string]interface{}{"length": 5, "path": []interface{}{"a"}},
+			map[string]interface{}{"length": 11, "path": []interface{}{".pad", "11"}, "attr": "p"},
+			map[string]interface{}{"length": 5, "path": []interface{}{"b"}},
+		},
+	}})
+	mi, err = Parse([]byte(padded))
+	if err != nil || !mi.Files[1].Padding {
+		t.Fatalf("Expected padding file, got %+v %v", mi, err)
+	}
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "Pack"), 0o755)
+	os.WriteFile(filepath.Join(dir, "Pack", "a"), []byte("hello"), 0o644)
+	os.WriteFile(filepath.Join(dir, "Pack", "b"), []byte("world"), 0o644)
+	for i := range mi.Pieces {
+		if ok, err := mi.VerifyPiece(dir, i); !ok || err != nil {
+			t.Errorf("Expected piece %d to verify with padding, got %v %v", i, ok, err)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"empty":          "",
+		"not a dict":     "li1ee",
+		"truncated":      "d4:infod4:name",
+		"no info":        "d8:announce3:urle",
+		"no pieces":      encode(map[string]interface{}{"info": map[string]interface{}{"name": "x", "length": 1, "piece length": 16}}),
+		"piece count":    encode(map[string]interface{}{"info": map[string]interface{}{"name": "x", "length": 40, "piece length": 16, "pieces": strings.Repeat("a", 20)}}),
+		"path traversal": encode(map[string]interface{}{"info": map[string]interface{}{"name": "x", "piece length": 16, "pieces": strings.Repeat("a", 20), "files": []interface{}{map[string]interface{}{"length": 1, "path": []interface{}{".."}}}}}),
+		"traversal name": encode(map[string]interface{}{"info": map[string]interface{}{"name": "../x", "length": 1, "piece length": 16, "pieces": strings.Repeat("a", 20)}}),
+		"dot name":       encode(map[string]interface{}{"info": map[string]interface{}{"name": "..", "length": 1, "piece length": 16, "pieces": strings.Repeat("a", 20)}}),
+		"absolute name":  encode(map[string]interface{}{"info": map[string]interface{}{"name": "/etc", "length": 1, "piece length": 16, "pieces": strings.Repeat("a", 20)}}),
+		"negative size":  encode(map[string]interface{}{"info": map[string]interface{}{"name": "x", "length": -1, "piece length": 16, "pieces": ""}}),
+		"huge string":    "d4:info99999999:xe",
+		"deep nesting":   "d4:info" + strings.Repeat("l", 100) + strings.Repeat("e", 100) + "e",
+	}
+	for name, data := range testCases {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Package migrate moves torrents from one qBittorrent instance to another.
+
+Each torrent is exported from the source, added to the target with the same
+category, tags, limits and file priorities under a mapped save path, and
+deleted from the source (keeping its files) only once the target reports it
+complete and seeding. With SkipChecking the torrent is added without a hash
+check, so a recheck is forced before it is accepted; otherwise a target
+that never read the data would pass as seeding:
+
+	report, err := migrate.Migrate(ctx, oldBox, newBox, migrate.Options{
+		Select:       qbt.ListOptions{Category: "movies"},
+		PathMap:      map[string]string{"/downloads": "/data/torrents"},
+		SkipChecking: true,
+	})
+
+Migrations are resumable: the state of each torrent is read from both
+instances, so running Migrate again after an interruption continues where it
+stopped. A torrent already on the target may have been added without a
+check or left stopped, so it is rechecked, started, and removed from the
+source once verified, without being added again.
+*/
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+	"github.com/jfxdev/go-qbt/backup"
+)
+
+// Default values for Options
+const (
+	DefaultSeedTimeout  = 30 * time.Minute
+	DefaultPollInterval = 5 * time.Second
+)
+
+// Source is the part of *qbt.Client used on the instance torrents leave.
+type Source interface {
+	backup.TorrentSource
+	DeleteTorrents(hash string, deleteFiles bool) error
+}
+
+// Target is the part of *qbt.Client used on the instance torrents move to.
+type Target interface {
+	backup.TorrentTarget
+	StopTorrents(hash string) error
+	ForceRecheck(hash string) error
+}
+
+// Options control a migration.
+type Options struct {
+	Select       qbt.ListOptions   // Torrents to migrate (default: all)
+	PathMap      map[string]string // Source to target save path prefixes; the longest match wins
+	SkipChecking bool              // Add to the target without checking, then force a recheck before deleting the source (default: false)
+	DryRun       bool              // Report the planned moves without changing anything (default: false)
+	SeedTimeout  time.Duration     // How long to wait for a torrent to seed on the target (default: 30m)
+	PollInterval time.Duration     // Interval between state checks on the target (default: 5s)
+	Progress     func(Event)       // Called as each torrent advances (optional)
+}
+
+// Stage is the step a torrent has reached.
+type Stage string
+
+const (
+	StagePlanned  Stage = "planned"  // Dry run: the torrent would be migrated
+	StageAdded    Stage = "added"    // Added to the target
+	StageVerified Stage = "verified" // Complete and seeding on the target
+	StageDeleted  Stage = "deleted"  // Removed from the source; the migration is done
+	StageSkipped  Stage = "skipped"  // Not migrated, see Event.Err
+	StageFailed   Stage = "failed"   // Migration failed, see Event.Err; the source is untouched
+)
+
+// Event reports the progress of one torrent.
+type Event struct {
+	Hash     string
+	Name     string
+	Stage    Stage
+	FromPath string
+	ToPath   string
+	Index    int // 1-based position in the migration
+	Total    int
+	Err      error
+}
+
+// Report summarizes a migration.
+type Report struct {
+	Planned  []string          `json:"planned,omitempty"` // Hashes that would move (dry run)
+	Migrated []string          `json:"migrated"`
+	Skipped  map[string]string `json:"skipped"` // Reasons by hash
+	Failed   map[string]string `json:"failed"`  // Errors by hash
+}
+
+// Migrate moves the selected torrents from src to dst. Torrents are processed
+// one at a time; a failure is recorded and the migration moves on. Torrents
+// that are not complete on the source are skipped, since they could not be
+// verified on the target. Cancelling ctx stops the migration between steps.
+func Migrate(ctx context.Context, src Source, dst Target, opts Options) (*Report, error) {
+	if opts.SeedTimeout <= 0 {
+		opts.SeedTimeout = DefaultSeedTimeout
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+
+	torrents, err := src.ListTorrents(opts.Select)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source torrents: %w", err)
+	}
+
+	report := &Report{Skipped: make(map[string]string), Failed: make(map[string]string)}
+	for i, t := range torrents {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		event := Event{
+			Hash:     t.Hash,
+			Name:     t.Name,
+			FromPath: t.SavePath,
+			ToPath:   qbt.MapPath(t.SavePath, opts.PathMap),
+			Index:    i + 1,
+			Total:    len(torrents),
+		}
+		progress := func(stage Stage, err error) {
+			event.Stage, event.Err = stage, err
+			if opts.Progress != nil {
+				opts.Progress(event)
+			}
+		}
+
+		if t.Progress < 1 {
+			report.Skipped[t.Hash] = "not complete on the source"
+			progress(StageSkipped, fmt.Errorf("%s", report.Skipped[t.Hash]))
+			continue
+		}
+
+		if opts.DryRun {
+			report.Planned = append(report.Planned, t.Hash)
+			progress(StagePlanned, nil)
+			continue
+		}
+
+		if err := migrateTorrent(ctx, src, dst, t, event.ToPath, opts, progress); err != nil {
+			report.Failed[t.Hash] = err.Error()
+			progress(StageFailed, err)
+			continue
+		}
+		report.Migrated = append(report.Migrated, t.Hash)
+	}
+
+	if len(report.Failed) > 0 {
+		return report, fmt.Errorf("failed to migrate %d of %d torrents", len(report.Failed), len(torrents))
+	}
+	return report, nil
+}
+
+func migrateTorrent(ctx context.Context, src Source, dst Target, t *qbt.TorrentResponse, toPath string, opts Options, progress func(Stage, error)) error {
+	torrent, data, err := backup.CaptureTorrent(src, t)
+	if err != nil {
+		return err
+	}
+	torrent.SavePath = toPath
+
+	// Stopped torrents are not checked, so add it running and stop it once verified
+	stopped := torrent.Stopped
+	torrent.Stopped = false
+
+	existing, err := dst.ListTorrents(qbt.ListOptions{Hashes: []string{t.Hash}})
+	if err != nil {
+		return fmt.Errorf("failed to check target: %w", err)
+	}
+
+	// Skipping the check reports the torrent complete without reading a byte,
+	// so the data is verified before the source copy goes away. A leftover
+	// from an interrupted run may have been added either way.
+	leftover := len(existing) > 0
+	rechecking := opts.SkipChecking || leftover
+
+	if !leftover {
+		err = backup.AddTorrent(dst, torrent, data, backup.ImportOptions{
+			SkipChecking: opts.SkipChecking,
+			PollInterval: opts.PollInterval,
+		})
+	} else {
+		// The settings may not have been applied
+		err = backup.ApplySettings(dst, torrent)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add to target: %w", err)
+	}
+	progress(StageAdded, nil)
+
+	if rechecking {
+		if err := dst.ForceRecheck(t.Hash); err != nil {
+			return fmt.Errorf("failed to recheck on target: %w", err)
+		}
+	}
+	if leftover {
+		// Added stopped and interrupted before it was started
+		if err := dst.StartTorrents(t.Hash); err != nil {
+			return fmt.Errorf("failed to start on target: %w", err)
+		}
+	}
+	if err := waitForSeeding(ctx, dst, t.Hash, rechecking, opts); err != nil {
+		return err
+	}
+	if stopped {
+		if err := dst.StopTorrents(t.Hash); err != nil {
+			return fmt.Errorf("failed to stop on target: %w", err)
+		}
+	}
+	progress(StageVerified, nil)
+
+	if err := src.DeleteTorrents(t.Hash, false); err != nil {
+		return fmt.Errorf("failed to delete from source: %w", err)
+	}
+	progress(StageDeleted, nil)
+	return nil
+}
+
+// seedingStates are the states of a complete, running torrent. Stopped
+// states do not count: the torrent is added running, so a stopped torrent
+// may never have been checked.
+var seedingStates = map[string]bool{
+	"uploading": true,
+	"stalledUP": true,
+	"queuedUP":  true,
+	"forcedUP":  true,
+}
+
+// recheckPolls is how many polls in a row a torrent must report seeding
+// after ForceRecheck when the recheck itself was never seen.
+const recheckPolls = 3
+
+// waitForSeeding polls the target until the torrent is complete and seeding.
+// With rechecking set, the state reported right after ForceRecheck may still
+// be the unverified one, so seeding only counts once the recheck has been
+// seen in progress, or after recheckPolls polls in a row for a recheck that
+// finished between two polls.
+func waitForSeeding(ctx context.Context, dst Target, hash string, rechecking bool, opts Options) error {
+	ctx, cancel := context.WithTimeout(ctx, opts.SeedTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	state, seeding := "", 0
+	for {
+		torrents, err := dst.ListTorrents(qbt.ListOptions{Hashes: []string{hash}})
+		if err != nil {
+			return fmt.Errorf("failed to check target: %w", err)
+		}
+		if len(torrents) > 0 {
+			t := torrents[0]
+			state = t.State
+			if strings.HasPrefix(t.State, "checking") || t.Progress < 1 {
+				rechecking = false
+			}
+			if t.Progress >= 1 && seedingStates[t.State] {
+				if seeding++; !rechecking || seeding >= recheckPolls {
+					return nil
+				}
+			} else {
+				seeding = 0
+			}
+			if t.State == "error" || t.State == "missingFiles" {
+				return fmt.Errorf("torrent is in state %s on the target", t.State)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("torrent did not reach a seeding state on the target (last state %q): %w", state, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
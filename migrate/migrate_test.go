@@ -0,0 +1,287 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// fakeInstance is an in-memory instance. Torrents added to it become
+// complete after checksLeft polls.
+type fakeInstance struct {
+	torrents   map[string]*qbt.TorrentResponse
+	order      []string
+	checksLeft int
+	deleted    map[string]bool
+	failAdd    bool
+	rechecked  []string
+}
+
+func newFakeInstance(torrents ...*qbt.TorrentResponse) *fakeInstance {
+	f := &fakeInstance{torrents: map[string]*qbt.TorrentResponse{}, deleted: map[string]bool{}}
+	for _, t := range torrents {
+		f.torrents[t.Hash] = t
+		f.order = append(f.order, t.Hash)
+	}
+	return f
+}
+
+func (f *fakeInstance) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	var out []*qbt.TorrentResponse
+	for _, hash := range f.order {
+		t, ok := f.torrents[hash]
+		if !ok || (len(opts.Hashes) > 0 && opts.Hashes[0] != hash) {
+			continue
+		}
+		if t.State == "checkingUP" {
+			if f.checksLeft--; f.checksLeft < 0 {
+				t.State, t.Progress = "stalledUP", 1
+			}
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (f *fakeInstance) ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error) {
+	return []*qbt.TorrentFile{{Priority: 1}}, nil
+}
+
+func (f *fakeInstance) ExportTorrent(hash string) ([]byte, error) {
+	return []byte("d4:infodee"), nil
+}
+
+func (f *fakeInstance) DeleteTorrents(hash string, deleteFiles bool) error {
+	if deleteFiles {
+		return fmt.Errorf("files must be kept")
+	}
+	delete(f.torrents, hash)
+	f.deleted[hash] = true
+	return nil
+}
+
+func (f *fakeInstance) AddTorrentFile(filename string, torrent []byte, opts qbt.TorrentConfig) error {
+	if f.failAdd {
+		return fmt.Errorf("disk full")
+	}
+	hash := filename[:len(filename)-len(".torrent")]
+	t := &qbt.TorrentResponse{Hash: hash, SavePath: opts.Directory, Category: opts.Category, State: "checkingUP"}
+	if opts.SkipChecking {
+		// Reported complete without reading the data
+		t.State, t.Progress = "stalledUP", 1
+	}
+	if opts.Paused {
+		t.State = "stoppedDL"
+	}
+	f.torrents[hash] = t
+	f.order = append(f.order, hash)
+	return nil
+}
+
+func (f *fakeInstance) ForceRecheck(hash string) error {
+	f.rechecked = append(f.rechecked, hash)
+	if f.checksLeft == 0 {
+		// A small torrent finishes its recheck before the first poll
+		f.torrents[hash].State, f.torrents[hash].Progress = "stalledUP", 1
+		return nil
+	}
+	f.torrents[hash].State, f.torrents[hash].Progress = "checkingUP", 0
+	return nil
+}
+
+func (f *fakeInstance) SetTorrentDownloadLimit(hash string, limit int) error {
+	f.torrents[hash].DlLimit = limit
+	return nil
+}
+
+func (f *fakeInstance) SetTorrentUploadLimit(hash string, limit int) error {
+	f.torrents[hash].UpLimit = limit
+	return nil
+}
+
+func (f *fakeInstance) SetTorrentShareLimit(hash string, ratioLimit float64, seedingTimeLimit int, inactiveSeedingTimeLimit int) error {
+	f.torrents[hash].RatioLimit = ratioLimit
+	return nil
+}
+
+func (f *fakeInstance) StopTorrents(hash string) error {
+	f.torrents[hash].State = "stoppedUP"
+	return nil
+}
+
+func (f *fakeInstance) StartTorrents(hash string) error {
+	t := f.torrents[hash]
+	t.State = "checkingUP"
+	if t.Progress >= 1 {
+		t.State = "stalledUP"
+	}
+	return nil
+}
+
+func (f *fakeInstance) SetFilePriority(hash string, fileIDs []int, priority int) error {
+	return nil
+}
+
+func newSource() *fakeInstance {
+	return newFakeInstance(
+		&qbt.TorrentResponse{Hash: "aaa", Name: "Movie", SavePath: "/downloads/movies", Category: "movies", State: "stoppedUP", Progress: 1, UpLimit: 500, RatioLimit: 2, SeedingTimeLimit: -2, InactiveSeedingTimeLimit: -2},
+		&qbt.TorrentResponse{Hash: "bbb", Name: "Partial", SavePath: "/downloads", State: "downloading", Progress: 0.5},
+	)
+}
+
+func TestMigrate(t *testing.T) {
+	src, dst := newSource(), newFakeInstance()
+	dst.checksLeft = 2
+
+	var stages []Stage
+	report, err := Migrate(context.Background(), src, dst, Options{
+		PathMap:      map[string]string{"/downloads": "/data"},
+		PollInterval: time.Millisecond,
+		Progress:     func(e Event) { stages = append(stages, e.Stage) },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.Migrated) != 1 || report.Skipped["bbb"] == "" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	moved := dst.torrents["aaa"]
+	if moved == nil || moved.SavePath != "/data/movies" || moved.Category != "movies" || moved.UpLimit != 500 || moved.RatioLimit != 2 || moved.State != "stoppedUP" {
+		t.Errorf("Torrent not moved with its settings: %+v", moved)
+	}
+	if !src.deleted["aaa"] || src.deleted["bbb"] {
+		t.Errorf("Only the verified torrent should be deleted from the source: %v", src.deleted)
+	}
+
+	expected := []Stage{StageAdded, StageVerified, StageDeleted, StageSkipped}
+	if fmt.Sprint(stages) != fmt.Sprint(expected) {
+		t.Errorf("Expected stages %v, got %v", expected, stages)
+	}
+}
+
+func TestMigrateSkipCheckingRechecks(t *testing.T) {
+	src, dst := newSource(), newFakeInstance()
+	dst.checksLeft = 2
+
+	var verifiedAfterRecheck bool
+	report, err := Migrate(context.Background(), src, dst, Options{
+		SkipChecking: true,
+		PollInterval: time.Millisecond,
+		Progress: func(e Event) {
+			if e.Stage == StageVerified {
+				verifiedAfterRecheck = len(dst.rechecked) == 1 && dst.checksLeft < 0
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Migrated) != 1 || !verifiedAfterRecheck {
+		t.Errorf("Expected the torrent to be verified by a recheck, got %+v rechecked=%v", report, dst.rechecked)
+	}
+}
+
+func TestMigrateSkipCheckingFastRecheck(t *testing.T) {
+	src, dst := newSource(), newFakeInstance()
+
+	report, err := Migrate(context.Background(), src, dst, Options{
+		SkipChecking: true,
+		SeedTimeout:  time.Second,
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Migrated) != 1 || len(dst.rechecked) != 1 || !src.deleted["aaa"] {
+		t.Errorf("Expected a recheck finished before the first poll to count, got %+v rechecked=%v", report, dst.rechecked)
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	src, dst := newSource(), newFakeInstance()
+
+	var events []Event
+	report, err := Migrate(context.Background(), src, dst, Options{
+		DryRun:   true,
+		PathMap:  map[string]string{"/downloads": "/data"},
+		Progress: func(e Event) { events = append(events, e) },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Planned) != 1 || len(dst.torrents) != 0 || len(src.deleted) != 0 {
+		t.Errorf("Dry run should not change anything: %+v", report)
+	}
+	if events[0].Stage != StagePlanned || events[0].ToPath != "/data/movies" || events[0].Total != 2 {
+		t.Errorf("Unexpected event: %+v", events[0])
+	}
+}
+
+func TestMigrateKeepsSourceOnFailure(t *testing.T) {
+	src, dst := newSource(), newFakeInstance()
+	dst.failAdd = true
+
+	report, err := Migrate(context.Background(), src, dst, Options{PollInterval: time.Millisecond})
+	if err == nil || report.Failed["aaa"] == "" {
+		t.Fatalf("Expected the migration to fail, got %v %+v", err, report)
+	}
+	if len(src.deleted) != 0 {
+		t.Error("The source must be untouched when adding fails")
+	}
+
+	// Not seeding in time
+	src, dst = newSource(), newFakeInstance()
+	dst.checksLeft = 1000
+	_, err = Migrate(context.Background(), src, dst, Options{SeedTimeout: 20 * time.Millisecond, PollInterval: time.Millisecond})
+	if err == nil || len(src.deleted) != 0 {
+		t.Errorf("The source must be kept until the target seeds, got %v", err)
+	}
+}
+
+func TestMigrateResumes(t *testing.T) {
+	src, dst := newSource(), newFakeInstance()
+	// Interrupted after the add: the torrent is on both instances
+	dst.AddTorrentFile("aaa.torrent", []byte("d4:infodee"), qbt.TorrentConfig{Directory: "/downloads/movies"})
+
+	report, err := Migrate(context.Background(), src, dst, Options{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Migrated) != 1 || !src.deleted["aaa"] || dst.torrents["aaa"].UpLimit != 500 {
+		t.Errorf("Expected the migration to resume, got %+v", report)
+	}
+}
+
+func TestMigrateResumesStoppedLeftover(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config qbt.TorrentConfig
+	}{
+		{"interrupted before the start", qbt.TorrentConfig{Paused: true}},
+		{"added without a check", qbt.TorrentConfig{SkipChecking: true}},
+	}
+
+	for _, tc := range testCases {
+		src, dst := newSource(), newFakeInstance()
+		dst.checksLeft = 2
+		dst.AddTorrentFile("aaa.torrent", []byte("d4:infodee"), tc.config)
+		if tc.config.SkipChecking {
+			dst.StopTorrents("aaa")
+		}
+
+		report, err := Migrate(context.Background(), src, dst, Options{SeedTimeout: time.Second, PollInterval: time.Millisecond})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if len(report.Migrated) != 1 || len(dst.rechecked) != 1 || dst.checksLeft >= 0 {
+			t.Errorf("%s: expected the leftover to be rechecked before the source is deleted, got %+v rechecked=%v", tc.name, report, dst.rechecked)
+		}
+		if dst.torrents["aaa"].State != "stoppedUP" {
+			t.Errorf("%s: expected the torrent stopped again like on the source, got %s", tc.name, dst.torrents["aaa"].State)
+		}
+	}
+}
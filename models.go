@@ -1,9 +1,12 @@
 package qbt
 
 import (
+	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,6 +25,7 @@ type ConnectionStatus struct {
 	ErrorCode ErrorCode `json:"error_code,omitempty"`
 	Message   string    `json:"message,omitempty"`
 	Permanent bool      `json:"permanent,omitempty"`
+	Circuit   string    `json:"circuit,omitempty"` // Circuit breaker state (empty when the breaker is disabled)
 }
 
 // Client is a high-level qBittorrent API client with cookie cache and retries.
@@ -29,6 +33,9 @@ type Client struct {
 	mu              sync.RWMutex
 	config          Config
 	client          *http.Client
+	transport       *http.Transport // Shared by every request (nil means http.DefaultTransport)
+	logger          *slog.Logger
+	auth            Authenticator
 	MaxLoginRetries int
 	RetryDelay      time.Duration
 
@@ -39,6 +46,10 @@ type Client struct {
 	cookieValid   bool
 	cookieValidMu sync.RWMutex
 	status        string
+	// Set when a stored session was loaded and has not been validated yet
+	sessionRestored atomic.Bool
+	// Set when CookieAuth found that the server bypasses authentication
+	authBypassed atomic.Bool
 
 	// Error tracking for detailed status
 	lastError    *ClientError
@@ -49,19 +60,46 @@ type Client struct {
 	// Consecutive login failure counter (threshold before authFailed is set)
 	loginFailCount   int
 	loginFailCountMu sync.Mutex
+
+	// Client-side request throttling (nil when disabled)
+	rateLimiter *rateLimiter
+	inFlight    inFlightLimiter
+
+	// Fails fast while the instance is down (nil when disabled)
+	breaker *circuitBreaker
+
+	// Server versions, detected on first use (nil until then). capsGen
+	// changes on every reset so a detection that raced with it is dropped.
+	caps    *Capabilities
+	capsGen uint64
+	capsMu  sync.Mutex
+
+	// Serializes SetSpeedLimitsMode so concurrent callers do not undo each other
+	speedModeMu sync.Mutex
 }
 
 // Config contains runtime client settings and credentials.
 type Config struct {
-	BaseURL         string
-	Username        string
-	Password        string
-	jar             *cookiejar.Jar
-	RequestTimeout  time.Duration
-	MaxRetries      int
-	RetryBackoff    time.Duration
-	MaxLoginRetries int  // Max consecutive auth failures before permanent lockout (default: 5)
-	Debug           bool // Enable debug logging for session management
+	BaseURL          string
+	Username         string
+	Password         string
+	jar              *cookiejar.Jar
+	socketPath       string // Unix socket dialed instead of the host when BaseURL is unix://
+	RequestTimeout   time.Duration
+	MaxRetries       int
+	RetryBackoff     time.Duration
+	MaxLoginRetries  int           // Max consecutive auth failures before permanent lockout (default: 5)
+	Debug            bool          // Enable debug logging to stderr when Logger is nil
+	Logger           *slog.Logger  // Structured logger for client events (default: discard, or stderr when Debug is set)
+	RateLimit        float64       // Max requests per second sent by the client (default: 0, unlimited)
+	RateBurst        int           // Requests allowed in a burst above RateLimit (default: RateLimit rounded up)
+	MaxInFlight      int           // Max concurrent requests awaiting a response (default: 0, unlimited)
+	BreakerThreshold int           // Consecutive connection failures before the circuit opens (default: 0, disabled)
+	BreakerCooldown  time.Duration // Time an open circuit fails fast before probing again (default: 30s)
+	TLS              *TLSConfig    // CA bundle, client certificate and pinning for HTTPS (default: nil, system roots)
+	DialContext      DialFunc      // Dials every connection, e.g. through a SOCKS5 proxy (default: net.Dialer)
+	Authenticator    Authenticator // How the client authenticates (default: CookieAuth with bypass detection)
+	SessionStore     SessionStore  // Persists the session cookie across restarts (default: nil, login on every start)
 }
 
 // CookieCache stores session cookies to reduce validation requests.
@@ -84,6 +122,13 @@ type RetryConfig struct {
 // ListOptions filters listing endpoints.
 type ListOptions struct {
 	Category string
+	Filter   string   // State filter (all, downloading, seeding, completed, stopped, active, inactive, stalled, errored, ...)
+	Tag      string   // Only torrents with this tag
+	Hashes   []string // Only torrents with these hashes
+	Sort     string   // Field to sort by (e.g. "name", "added_on", "ratio")
+	Reverse  bool     // Reverse the sort order
+	Limit    int      // Max number of torrents to return (0 = no limit)
+	Offset   int      // Number of torrents to skip
 }
 
 // ListFilter is deprecated; use ListOptions instead.
@@ -93,13 +138,23 @@ type ListFilter struct {
 
 // TorrentConfig configures new torrent creation.
 type TorrentConfig struct {
-	MagnetURI    string
-	Directory    string
-	Category     string
-	Paused       bool
-	SkipChecking bool
+	MagnetURI     string // Magnet link or HTTP(S) URL of a .torrent file
+	Directory     string
+	Category      string
+	Tags          []string
+	Paused        bool
+	SkipChecking  bool
+	ContentLayout string // ContentLayoutOriginal, ContentLayoutSubfolder or ContentLayoutNoSubfolder (default: the server's setting)
+	AutoTMM       *bool  // Automatic torrent management (nil uses the server's setting)
 }
 
+// Content layouts for TorrentConfig.ContentLayout
+const (
+	ContentLayoutOriginal    = "Original"    // Keep the layout of the torrent
+	ContentLayoutSubfolder   = "Subfolder"   // Always create a subfolder
+	ContentLayoutNoSubfolder = "NoSubfolder" // Strip the root folder
+)
+
 // TorrentResponse is a subset of torrent info returned by qBittorrent.
 type TorrentResponse struct {
 	AddedOn                  int         `json:"added_on"`
@@ -131,11 +186,31 @@ type TorrentResponse struct {
 	Upspeed                  int         `json:"upspeed"`
 	Uploaded                 int         `json:"uploaded"`
 	Tags                     string      `json:"tags"`
+	DlLimit                  int         `json:"dl_limit"`                    // Download speed limit in bytes/s (-1 or 0 = no limit)
+	UpLimit                  int         `json:"up_limit"`                    // Upload speed limit in bytes/s (-1 or 0 = no limit)
+	Tracker                  string      `json:"tracker"`                     // URL of the first working tracker
+	LastActivity             int64       `json:"last_activity"`               // Last time a chunk was downloaded or uploaded (Unix time)
+	SeedingTime              int         `json:"seeding_time"`                // Time spent seeding in seconds
+	AmountLeft               int64       `json:"amount_left"`                 // Bytes left to download
+	Availability             float64     `json:"availability"`                // Distributed copies available in the swarm (-1 if unknown)
+	DownloadPath             string      `json:"download_path"`               // Location of incomplete files (empty if not used)
 	RatioLimit               float64     `json:"ratio_limit"`                 // Ratio limit (-2 = use global, -1 = no limit)
 	MaxRatio                 float64     `json:"max_ratio"`                   // Max ratio (alternative field name)
 	SeedingTimeLimit         int         `json:"seeding_time_limit"`          // Seeding time limit in minutes
 	MaxSeedingTime           int         `json:"max_seeding_time"`            // Max seeding time (alternative field name)
-	InactiveSeedingTimeLimit int         `json:"inactive_seeding_time_limit"` // Inactive seeding time limit in minutes
+	InactiveSeedingTimeLimit int         `json:"inactive_seeding_time_limit"` // Inactive seeding time limit in minutes (-2 = use global, also when the server has no such limit)
+}
+
+// UnmarshalJSON decodes a torrent, defaulting InactiveSeedingTimeLimit to -2
+// for servers older than qBittorrent 4.6, which do not report it.
+func (t *TorrentResponse) UnmarshalJSON(data []byte) error {
+	type plain TorrentResponse
+	decoded := plain{InactiveSeedingTimeLimit: -2}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*t = TorrentResponse(decoded)
+	return nil
 }
 
 // MainDataResponse represents a subset of sync/maindata response.
@@ -152,6 +227,7 @@ type MainDataServerStateResponse struct {
 	GlobalRatio           string `json:"global_ratio"`
 	LastExternalAddressV4 string `json:"last_external_address_v4"`
 	LastExternalAddressV6 string `json:"last_external_address_v6"`
+	UseAltSpeedLimits     bool   `json:"use_alt_speed_limits"`
 }
 
 // TransferInfoResponse represents global transfer information.
@@ -166,15 +242,22 @@ type TransferInfoResponse struct {
 	ConnectionStatus string `json:"connection_status"`
 }
 
+// BuildInfoResponse represents the library versions qBittorrent was built with
 type BuildInfoResponse struct {
-	DlInfoSpeed      int    `json:"dl_info_speed"`
-	DlInfoData       int    `json:"dl_info_data"`
-	UpInfoSpeed      int    `json:"up_info_speed"`
-	UpInfoData       int    `json:"up_info_data"`
-	DlRateLimit      int    `json:"dl_rate_limit"`
-	UpRateLimit      int    `json:"up_rate_limit"`
-	DhtNodes         int    `json:"dht_nodes"`
-	ConnectionStatus string `json:"connection_status"`
+	Qt         string `json:"qt"`         // Qt version
+	Libtorrent string `json:"libtorrent"` // libtorrent-rasterbar version
+	Boost      string `json:"boost"`      // Boost version
+	OpenSSL    string `json:"openssl"`    // OpenSSL version
+	Zlib       string `json:"zlib"`       // zlib version
+	Bitness    int    `json:"bitness"`    // Application bitness (32 or 64)
+}
+
+// ServerInfo is a snapshot of a qBittorrent instance
+type ServerInfo struct {
+	AppVersion      string             `json:"app_version"`       // qBittorrent version
+	APIVersion      string             `json:"api_version"`       // Web API version
+	BuildInfo       *BuildInfoResponse `json:"build_info"`        // Library versions (nil before Web API 2.3)
+	DefaultSavePath string             `json:"default_save_path"` // Default save path for new torrents
 }
 
 // MagnetLink represents the data extracted from a magnet link
@@ -334,6 +417,9 @@ type NetworkInfo struct {
 	UpRateLimit      int    `json:"up_rate_limit"`     // Upload rate limit
 }
 
+// RSSPathSeparator separates the folders of an RSS item path
+const RSSPathSeparator = `\`
+
 // RSSFeed represents an RSS feed
 type RSSFeed struct {
 	URL       string       `json:"url"`       // Feed URL
@@ -344,6 +430,23 @@ type RSSFeed struct {
 	Articles  []RSSArticle `json:"articles"`  // Articles
 }
 
+// RSSRule represents an RSS auto-downloading rule
+type RSSRule struct {
+	Enabled                   bool     `json:"enabled"`                             // Rule is enabled
+	MustContain               string   `json:"mustContain"`                         // Substring or regex the title must contain
+	MustNotContain            string   `json:"mustNotContain"`                      // Substring or regex the title must not contain
+	UseRegex                  bool     `json:"useRegex"`                            // Treat filters as regular expressions
+	EpisodeFilter             string   `json:"episodeFilter"`                       // Episode filter, e.g. "1x01-;"
+	SmartFilter               bool     `json:"smartFilter"`                         // Skip episodes already downloaded
+	AffectedFeeds             []string `json:"affectedFeeds"`                       // URLs of the feeds the rule applies to
+	IgnoreDays                int      `json:"ignoreDays"`                          // Days to ignore subsequent matches
+	AssignedCategory          string   `json:"assignedCategory"`                    // Category for downloaded torrents
+	SavePath                  string   `json:"savePath"`                            // Save path for downloaded torrents
+	AddPaused                 *bool    `json:"addPaused,omitempty"`                 // Add torrents stopped (nil uses the global setting)
+	LastMatch                 string   `json:"lastMatch,omitempty"`                 // Time of the last match (read-only)
+	PreviouslyMatchedEpisodes []string `json:"previouslyMatchedEpisodes,omitempty"` // Episodes already matched (read-only)
+}
+
 // RSSArticle represents an RSS article
 type RSSArticle struct {
 	ID          string `json:"id"`          // Article ID
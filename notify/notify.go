@@ -0,0 +1,357 @@
+/*
+Package notify posts torrent events to webhooks, Discord, Slack and Apprise.
+
+A Notifier polls the torrent list and the client's ConnectionStatus, turns
+changes into Events and sends each to the targets subscribed to its type:
+
+	notifier, err := notify.New(client, notify.Config{
+		Targets: []notify.Target{
+			{URL: "https://discord.com/api/webhooks/123/abc", Format: notify.FormatDiscord,
+				Events: []notify.EventType{notify.EventCompleted, notify.EventErrored}},
+			{URL: "https://hooks.example.org/qbt", Template: `{"hash": {{json .Torrent.Hash}}}`},
+		},
+		RatioThreshold: 2,
+	})
+	go notifier.Run(ctx)
+
+Targets can also be written as Apprise-style URLs with ParseURL, e.g.
+"discord://id/token", "slack://A/B/C", "json://host/path" or
+"apprise://host/notify/key".
+
+The first poll only records the current torrents, so existing torrents are
+not reported as added. Failed deliveries are retried with the library's
+backoff (qbt.RetryConfig), and identical torrent events within DedupWindow
+are delivered to each target once. Connection events are state transitions
+and are always sent, so a flapping instance reports every outage.
+*/
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// Default values for Config
+const (
+	DefaultInterval    = 30 * time.Second
+	DefaultDedupWindow = 10 * time.Minute
+	DefaultTimeout     = 10 * time.Second
+)
+
+// EventType identifies what happened.
+type EventType string
+
+const (
+	EventAdded        EventType = "added"         // A torrent appeared
+	EventCompleted    EventType = "completed"     // A torrent finished downloading
+	EventErrored      EventType = "errored"       // A torrent entered the error or missingFiles state
+	EventRemoved      EventType = "removed"       // A torrent disappeared
+	EventRatioReached EventType = "ratio_reached" // A torrent's ratio crossed RatioThreshold
+	EventConnection   EventType = "connection"    // The client's connection status changed
+)
+
+// API is the part of *qbt.Client used by the notifier.
+type API interface {
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	GetConnectionStatus() *qbt.ConnectionStatus
+}
+
+// Event is a change worth notifying. It is the data of target templates.
+type Event struct {
+	Type       EventType             `json:"type"`
+	Time       time.Time             `json:"time"`
+	Instance   string                `json:"instance,omitempty"`
+	Torrent    *qbt.TorrentResponse  `json:"torrent,omitempty"`    // Nil for EventConnection
+	Connection *qbt.ConnectionStatus `json:"connection,omitempty"` // Set for EventConnection
+	Previous   string                `json:"previous,omitempty"`   // Previous torrent state or connection status
+}
+
+// Title is a short description of the event.
+func (e Event) Title() string {
+	switch e.Type {
+	case EventAdded:
+		return "Torrent added"
+	case EventCompleted:
+		return "Torrent completed"
+	case EventErrored:
+		return "Torrent errored"
+	case EventRemoved:
+		return "Torrent removed"
+	case EventRatioReached:
+		return "Ratio reached"
+	case EventConnection:
+		return "qBittorrent " + e.Connection.Status
+	}
+	return string(e.Type)
+}
+
+// Text is the default message of the event.
+func (e Event) Text() string {
+	prefix := ""
+	if e.Instance != "" {
+		prefix = "[" + e.Instance + "] "
+	}
+	switch {
+	case e.Type == EventConnection && e.Connection.Message != "":
+		return fmt.Sprintf("%s%s: %s (was %s)", prefix, e.Title(), e.Connection.Message, e.Previous)
+	case e.Type == EventConnection:
+		return fmt.Sprintf("%s%s (was %s)", prefix, e.Title(), e.Previous)
+	case e.Type == EventRatioReached:
+		return fmt.Sprintf("%s%s: %s (%.2f)", prefix, e.Title(), e.Torrent.Name, e.Torrent.Ratio)
+	case e.Torrent != nil:
+		return fmt.Sprintf("%s%s: %s", prefix, e.Title(), e.Torrent.Name)
+	}
+	return prefix + e.Title()
+}
+
+// failure reports whether the event is bad news.
+func (e Event) failure() bool {
+	return e.Type == EventErrored || (e.Type == EventConnection && e.Connection.Status != qbt.StatusConnected)
+}
+
+// dedupKey identifies identical events. Connection events return "" and are
+// never deduplicated: connectionEvents only reports changes, and a second
+// outage within the window is news, not a duplicate.
+func (e Event) dedupKey() string {
+	switch {
+	case e.Type == EventConnection:
+		return ""
+	case e.Torrent != nil:
+		return string(e.Type) + "|" + e.Torrent.Hash
+	}
+	return string(e.Type)
+}
+
+// Config configures a Notifier.
+type Config struct {
+	Targets        []Target
+	Instance       string           // Name reported in events (optional)
+	Interval       time.Duration    // Time between polls in Run (default: 30s)
+	RatioThreshold float64          // Ratio that triggers EventRatioReached (default: 0, disabled)
+	DedupWindow    time.Duration    // Identical torrent events within this window are sent once per target (default: 10m, negative disables)
+	Retry          *qbt.RetryConfig // Delivery retries and backoff (default: qbt.DefaultRetryConfig())
+	HTTPClient     *http.Client     // Client used for deliveries (default: 10s timeout)
+	Logger         *slog.Logger     // Receives delivery failures (default: discard)
+	Now            func() time.Time // Clock for events and dedup (default: time.Now)
+}
+
+// Notifier watches one instance and dispatches its events.
+type Notifier struct {
+	api     API
+	config  Config
+	logger  *slog.Logger
+	targets []*target
+
+	mu       sync.Mutex
+	primed   bool
+	torrents map[string]*qbt.TorrentResponse
+	conn     string
+	sent     map[string]time.Time
+}
+
+// New creates a notifier for the instance behind api. It fails if a target
+// is invalid or its template does not parse.
+func New(api API, config Config) (*Notifier, error) {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.DedupWindow == 0 {
+		config.DedupWindow = DefaultDedupWindow
+	}
+	if config.Retry == nil {
+		config.Retry = qbt.DefaultRetryConfig()
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	n := &Notifier{api: api, config: config, logger: logger, sent: make(map[string]time.Time)}
+	for i, t := range config.Targets {
+		compiled, err := compile(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %d: %w", i, err)
+		}
+		n.targets = append(n.targets, compiled)
+	}
+	return n, nil
+}
+
+// Run polls every Interval until ctx is cancelled. Failed polls are logged
+// and retried on the next tick.
+func (n *Notifier) Run(ctx context.Context) error {
+	ticker := time.NewTicker(n.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := n.Poll(ctx); err != nil {
+			n.logger.Warn("notifier poll failed", qbt.LogKeyError, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll compares the instance with the previous poll and dispatches the
+// resulting events, which it returns. Delivery failures are logged, not
+// returned.
+func (n *Notifier) Poll(ctx context.Context) ([]Event, error) {
+	torrents, listErr := n.api.ListTorrents(qbt.ListOptions{})
+	// Listing refreshes the connection status, so read it afterwards
+	conn := n.api.GetConnectionStatus()
+
+	n.mu.Lock()
+	events := n.connectionEvents(conn)
+	if listErr == nil {
+		events = append(events, n.torrentEvents(torrents)...)
+	}
+	n.mu.Unlock()
+
+	for _, e := range events {
+		if err := n.Notify(ctx, e); err != nil {
+			n.logger.Warn("notification failed", "event", string(e.Type), qbt.LogKeyError, err)
+		}
+	}
+	if listErr != nil {
+		return events, fmt.Errorf("failed to list torrents: %w", listErr)
+	}
+	return events, nil
+}
+
+func (n *Notifier) event(t EventType) Event {
+	return Event{Type: t, Time: n.config.Now(), Instance: n.config.Instance}
+}
+
+func (n *Notifier) connectionEvents(conn *qbt.ConnectionStatus) []Event {
+	// Transient states are not reported
+	if conn.Status == qbt.StatusPending || conn.Status == qbt.StatusInitializing {
+		return nil
+	}
+	previous := n.conn
+	n.conn = conn.Status
+	if previous == "" || previous == conn.Status {
+		return nil
+	}
+	e := n.event(EventConnection)
+	e.Connection = conn
+	e.Previous = previous
+	return []Event{e}
+}
+
+func (n *Notifier) torrentEvents(torrents []*qbt.TorrentResponse) []Event {
+	current := make(map[string]*qbt.TorrentResponse, len(torrents))
+	for _, t := range torrents {
+		current[t.Hash] = t
+	}
+	previous := n.torrents
+	n.torrents = current
+	if !n.primed {
+		n.primed = true
+		return nil
+	}
+
+	var events []Event
+	add := func(typ EventType, t *qbt.TorrentResponse, prevState string) {
+		e := n.event(typ)
+		e.Torrent = t
+		e.Previous = prevState
+		events = append(events, e)
+	}
+	for _, t := range torrents {
+		prev, ok := previous[t.Hash]
+		if !ok {
+			add(EventAdded, t, "")
+			continue
+		}
+		if prev.Progress < 1 && t.Progress >= 1 {
+			add(EventCompleted, t, prev.State)
+		}
+		if !errored(prev) && errored(t) {
+			add(EventErrored, t, prev.State)
+		}
+		if threshold := n.config.RatioThreshold; threshold > 0 && prev.Ratio < threshold && t.Ratio >= threshold {
+			add(EventRatioReached, t, prev.State)
+		}
+	}
+	for hash, prev := range previous {
+		if _, ok := current[hash]; !ok {
+			add(EventRemoved, prev, prev.State)
+		}
+	}
+	return events
+}
+
+func errored(t *qbt.TorrentResponse) bool {
+	return t.State == "error" || t.State == "missingFiles"
+}
+
+// Notify sends e to every target subscribed to its type, unless an
+// identical event was delivered to that target within DedupWindow. A failed
+// delivery is not recorded, so the next identical event is sent again.
+func (n *Notifier) Notify(ctx context.Context, e Event) error {
+	key := e.dedupKey()
+
+	var errs []error
+	for i, t := range n.targets {
+		if len(t.Events) > 0 && !slices.Contains(t.Events, e.Type) {
+			continue
+		}
+		targetKey := fmt.Sprintf("%d|%s", i, key)
+		if key != "" && n.recentlySent(targetKey) {
+			continue
+		}
+		if err := n.send(ctx, t, e); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if key != "" {
+			n.markSent(targetKey)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recentlySent reports whether key was delivered within DedupWindow.
+func (n *Notifier) recentlySent(key string) bool {
+	if n.config.DedupWindow < 0 {
+		return false
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := n.config.Now()
+	for k, at := range n.sent {
+		if now.Sub(at) >= n.config.DedupWindow {
+			delete(n.sent, k)
+		}
+	}
+	_, ok := n.sent[key]
+	return ok
+}
+
+func (n *Notifier) markSent(key string) {
+	if n.config.DedupWindow < 0 {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent[key] = n.config.Now()
+}
@@ -0,0 +1,253 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+type fakeAPI struct {
+	torrents []*qbt.TorrentResponse
+	status   qbt.ConnectionStatus
+}
+
+func (f *fakeAPI) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	// Copy so later changes in the test do not alter the notifier's snapshot
+	var result []*qbt.TorrentResponse
+	for _, t := range f.torrents {
+		c := *t
+		result = append(result, &c)
+	}
+	return result, nil
+}
+
+func (f *fakeAPI) GetConnectionStatus() *qbt.ConnectionStatus {
+	status := f.status
+	return &status
+}
+
+// receiver is a local endpoint recording the bodies it receives.
+type receiver struct {
+	mu     sync.Mutex
+	bodies []string
+	fail   int // Requests to answer with 503 before succeeding
+	server *httptest.Server
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.fail > 0 {
+			r.fail--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		r.bodies = append(r.bodies, req.URL.Path+" "+string(body))
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *receiver) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := append([]string(nil), r.bodies...)
+	sort.Strings(result)
+	return result
+}
+
+var fastRetry = &qbt.RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, BackoffFactor: 2, RetryableCodes: []int{503}}
+
+func TestEvents(t *testing.T) {
+	api := &fakeAPI{
+		status: qbt.ConnectionStatus{Status: qbt.StatusConnected},
+		torrents: []*qbt.TorrentResponse{
+			{Hash: "a", Name: "Alpha", Progress: 0.5, State: "downloading"},
+			{Hash: "b", Name: "Beta", Progress: 1, Ratio: 1.5, State: "uploading"},
+			{Hash: "c", Name: "Gamma", Progress: 1, State: "uploading"},
+		},
+	}
+	n, err := New(api, Config{RatioThreshold: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := n.Poll(context.Background())
+	if err != nil || len(events) != 0 {
+		t.Fatalf("Expected the first poll to only record torrents, got %v %v", events, err)
+	}
+
+	api.torrents[0].Progress, api.torrents[0].State = 1, "uploading"
+	api.torrents[1].Ratio = 2.1
+	api.torrents[2].State = "missingFiles"
+	api.torrents = append(api.torrents[:2], &qbt.TorrentResponse{Hash: "d", Name: "Delta"})
+	api.torrents[0].State = "error"
+	api.status = qbt.ConnectionStatus{Status: qbt.StatusUnaccessible, Message: "connection refused"}
+
+	events, err = n.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Text())
+	}
+	expected := []string{
+		"qBittorrent unaccessible: connection refused (was connected)",
+		"Torrent completed: Alpha",
+		"Torrent errored: Alpha",
+		"Ratio reached: Beta (2.10)",
+		"Torrent added: Delta",
+		"Torrent removed: Gamma",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected events:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// Transient states are not transitions
+	api.status = qbt.ConnectionStatus{Status: qbt.StatusPending}
+	if events, _ := n.Poll(context.Background()); len(events) != 0 {
+		t.Errorf("Expected no events, got %v", events)
+	}
+	api.status = qbt.ConnectionStatus{Status: qbt.StatusConnected}
+	if events, _ := n.Poll(context.Background()); len(events) != 1 || events[0].Previous != qbt.StatusUnaccessible {
+		t.Errorf("Expected a reconnection event, got %v", events)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	r := newReceiver(t)
+	n, err := New(&fakeAPI{}, Config{
+		Instance: "seedbox",
+		Retry:    fastRetry,
+		Targets: []Target{
+			{URL: r.server.URL + "/hook"},
+			{URL: r.server.URL + "/custom", Template: `{"hash": {{json .Torrent.Hash}}, "type": "{{.Type}}"}`},
+			{URL: r.server.URL + "/discord", Format: FormatDiscord, Events: []EventType{EventCompleted}},
+			{URL: r.server.URL + "/slack", Format: FormatSlack, Template: "{{.Torrent.Name}} is done"},
+			{URL: r.server.URL + "/apprise", Format: FormatApprise, Events: []EventType{EventErrored}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := Event{Type: EventCompleted, Instance: "seedbox", Time: time.Unix(0, 0).UTC(), Torrent: &qbt.TorrentResponse{Hash: "abc", Name: "Alpha"}}
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	got := r.received()
+	if len(got) != 4 {
+		t.Fatalf("Expected 4 deliveries, got %q", got)
+	}
+	expected := []string{
+		`/custom {"hash": "abc", "type": "completed"}`,
+		`/discord {"content":"[seedbox] Torrent completed: Alpha"}`,
+	}
+	for i, e := range expected {
+		if got[i] != e {
+			t.Errorf("Expected %s, got %s", e, got[i])
+		}
+	}
+	var webhook Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(got[2], "/hook ")), &webhook); err != nil || webhook.Torrent.Hash != "abc" || webhook.Type != EventCompleted {
+		t.Errorf("Expected the event as JSON, got %s (%v)", got[2], err)
+	}
+	if got[3] != `/slack {"text":"Alpha is done"}` {
+		t.Errorf("Expected the rendered Slack text, got %s", got[3])
+	}
+
+	// Identical events are sent once
+	if err := n.Notify(context.Background(), event); err != nil || len(r.received()) != 4 {
+		t.Errorf("Expected the duplicate to be dropped, got %d deliveries (%v)", len(r.received()), err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	r := newReceiver(t)
+	r.fail = 2
+	n, err := New(&fakeAPI{}, Config{Retry: fastRetry, Targets: []Target{{URL: r.server.URL, Format: FormatApprise}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := Event{Type: EventErrored, Torrent: &qbt.TorrentResponse{Hash: "abc", Name: "Alpha"}}
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Expected delivery after retries, got %v", err)
+	}
+	if got := r.received(); len(got) != 1 || !strings.Contains(got[0], `"type":"failure"`) {
+		t.Errorf("Expected one failure notification, got %q", got)
+	}
+
+	r.fail = 3
+	event.Torrent = &qbt.TorrentResponse{Hash: "def"}
+	if err := n.Notify(context.Background(), event); err == nil || !strings.Contains(err.Error(), "unexpected status 503") {
+		t.Errorf("Expected delivery to fail after retries, got %v", err)
+	}
+
+	// A failed delivery does not suppress the next identical event
+	if err := n.Notify(context.Background(), event); err != nil || len(r.received()) != 2 {
+		t.Errorf("Expected the event to be delivered after a failure, got %d deliveries (%v)", len(r.received()), err)
+	}
+}
+
+func TestConnectionFlapping(t *testing.T) {
+	r := newReceiver(t)
+	api := &fakeAPI{status: qbt.ConnectionStatus{Status: qbt.StatusConnected}}
+	n, err := New(api, Config{Retry: fastRetry, Targets: []Target{{URL: r.server.URL}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, status := range []string{qbt.StatusConnected, qbt.StatusUnaccessible, qbt.StatusConnected, qbt.StatusUnaccessible, qbt.StatusConnected} {
+		api.status.Status = status
+		if _, err := n.Poll(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := r.received(); len(got) != 4 {
+		t.Errorf("Expected every transition to be reported, got %d: %q", len(got), got)
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	testCases := []struct {
+		raw    string
+		url    string
+		format Format
+	}{
+		{"discord://123/abc", "https://discord.com/api/webhooks/123/abc", FormatDiscord},
+		{"slack://T0/B0/xyz", "https://hooks.slack.com/services/T0/B0/xyz", FormatSlack},
+		{"json://localhost:8080/hook?x=1", "http://localhost:8080/hook?x=1", ""},
+		{"jsons://example.org/hook", "https://example.org/hook", ""},
+		{"apprises://apprise.local/notify/key", "https://apprise.local/notify/key", FormatApprise},
+		{"https://example.org/hook", "https://example.org/hook", ""},
+	}
+	for _, tc := range testCases {
+		target, err := ParseURL(tc.raw)
+		if err != nil || target.URL != tc.url || target.Format != tc.format {
+			t.Errorf("%s: expected %s (%s), got %+v %v", tc.raw, tc.url, tc.format, target, err)
+		}
+	}
+	for _, raw := range []string{"discord://123", "mailto://x", "slack://a/b"} {
+		if _, err := ParseURL(raw); err == nil {
+			t.Errorf("%s: expected an error", raw)
+		}
+	}
+
+	if _, err := New(&fakeAPI{}, Config{Targets: []Target{{URL: "http://x", Template: "{{"}}}); err == nil {
+		t.Error("Expected an invalid template to be rejected")
+	}
+}
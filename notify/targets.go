@@ -0,0 +1,209 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Format is the payload shape a target expects.
+type Format string
+
+const (
+	FormatWebhook Format = "webhook" // The Event as JSON, or the rendered template as is
+	FormatDiscord Format = "discord" // {"content": text}
+	FormatSlack   Format = "slack"   // {"text": text}
+	FormatApprise Format = "apprise" // Apprise API {"title", "body", "type"}
+)
+
+// Target is a destination for notifications.
+type Target struct {
+	Name     string            // Identifies the target in errors (default: the URL host)
+	URL      string            // Endpoint receiving a POST per event
+	Format   Format            // Payload shape (default: FormatWebhook)
+	Events   []EventType       // Events to send (default: all)
+	Template string            // text/template over Event; the body for webhooks, the message text otherwise
+	Headers  map[string]string // Extra request headers, e.g. Authorization
+}
+
+// templateFuncs are available in target templates.
+var templateFuncs = template.FuncMap{
+	"json": toJSON,
+}
+
+type target struct {
+	Target
+	tmpl *template.Template
+}
+
+func compile(t Target) (*target, error) {
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", t.URL)
+	}
+	if t.Name == "" {
+		t.Name = u.Host
+	}
+	switch t.Format {
+	case "":
+		t.Format = FormatWebhook
+	case FormatWebhook, FormatDiscord, FormatSlack, FormatApprise:
+	default:
+		return nil, fmt.Errorf("unknown format %q", t.Format)
+	}
+
+	compiled := &target{Target: t}
+	if t.Template != "" {
+		compiled.tmpl, err = template.New(t.Name).Funcs(templateFuncs).Parse(t.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+	}
+	return compiled, nil
+}
+
+// ParseURL converts an Apprise-style URL into a Target:
+//
+//	discord://{id}/{token}         Discord webhook
+//	slack://{A}/{B}/{C}            Slack incoming webhook
+//	json://host/path, jsons://...  generic webhook over HTTP or HTTPS
+//	apprise://host/notify/{key}    Apprise API server (apprises:// for HTTPS)
+//
+// Plain http(s) URLs are generic webhooks.
+func ParseURL(raw string) (Target, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid notification URL: %w", err)
+	}
+	parts := strings.FieldsFunc(u.Host+u.Path, func(r rune) bool { return r == '/' })
+
+	switch u.Scheme {
+	case "http", "https":
+		return Target{URL: raw}, nil
+	case "json", "jsons":
+		return Target{URL: httpURL(u, u.Scheme == "jsons"), Name: u.Host}, nil
+	case "apprise", "apprises":
+		return Target{URL: httpURL(u, u.Scheme == "apprises"), Name: u.Host, Format: FormatApprise}, nil
+	case "discord":
+		if len(parts) != 2 {
+			return Target{}, fmt.Errorf("discord URL must be discord://id/token")
+		}
+		return Target{URL: "https://discord.com/api/webhooks/" + parts[0] + "/" + parts[1], Name: "discord", Format: FormatDiscord}, nil
+	case "slack":
+		if len(parts) != 3 {
+			return Target{}, fmt.Errorf("slack URL must be slack://A/B/C")
+		}
+		return Target{URL: "https://hooks.slack.com/services/" + strings.Join(parts, "/"), Name: "slack", Format: FormatSlack}, nil
+	}
+	return Target{}, fmt.Errorf("unsupported notification URL scheme %q", u.Scheme)
+}
+
+func httpURL(u *url.URL, secure bool) string {
+	converted := *u
+	converted.Scheme = "http"
+	if secure {
+		converted.Scheme = "https"
+	}
+	return converted.String()
+}
+
+// render builds the request body for e.
+func (t *target) render(e Event) ([]byte, error) {
+	text := e.Text()
+	if t.tmpl != nil {
+		var buf bytes.Buffer
+		if err := t.tmpl.Execute(&buf, e); err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+		if t.Format == FormatWebhook {
+			return buf.Bytes(), nil
+		}
+		text = buf.String()
+	}
+
+	switch t.Format {
+	case FormatDiscord:
+		return json.Marshal(map[string]string{"content": text})
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": text})
+	case FormatApprise:
+		kind := "info"
+		switch {
+		case e.failure():
+			kind = "failure"
+		case e.Type == EventCompleted:
+			kind = "success"
+		}
+		return json.Marshal(map[string]string{"title": e.Title(), "body": text, "type": kind})
+	}
+	return json.Marshal(e)
+}
+
+// send delivers e to t, retrying network errors and retryable statuses with
+// the configured backoff.
+func (n *Notifier) send(ctx context.Context, t *target, e Event) error {
+	body, err := t.render(e)
+	if err != nil {
+		return fmt.Errorf("failed to notify %s: %w", t.Name, err)
+	}
+
+	retry := n.config.Retry
+	var lastErr error
+	for attempt := 0; attempt <= retry.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(retry.Delay(attempt - 1)):
+			case <-ctx.Done():
+				return fmt.Errorf("failed to notify %s: %w", t.Name, ctx.Err())
+			}
+		}
+
+		retryable, err := n.post(ctx, t, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable {
+			break
+		}
+	}
+	return fmt.Errorf("failed to notify %s: %w", t.Name, lastErr)
+}
+
+// post makes one delivery attempt and reports whether a failure is worth
+// retrying.
+func (n *Notifier) post(ctx context.Context, t *target, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := n.config.HTTPClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return slices.Contains(n.config.Retry.RetryableCodes, resp.StatusCode), fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
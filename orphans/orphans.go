@@ -0,0 +1,253 @@
+/*
+Package orphans compares the save paths of an instance with the files its
+torrents reference.
+
+The default save path, the category save paths and the download path for
+incomplete torrents are walked on the local disk. Files that no torrent
+references are reported as orphaned, for example leftovers of torrents
+deleted without their data; files that torrents reference but that are not
+on disk are reported as missing:
+
+	report, err := orphans.Scan(client, orphans.Options{
+		// qBittorrent runs in a container that mounts /srv/torrents as /downloads
+		PathMap: map[string]string{"/downloads": "/srv/torrents"},
+	})
+	for _, f := range report.Orphaned {
+		fmt.Println(f.Path, f.Size)
+	}
+
+Scan only reads; removing orphaned files is left to the caller.
+*/
+package orphans
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// IncompleteExt is the extension qBittorrent appends to incomplete files
+// when "Append .!qB extension to incomplete files" is enabled.
+const IncompleteExt = ".!qB"
+
+// DefaultIgnore are base-name patterns never reported as orphaned.
+var DefaultIgnore = []string{".DS_Store", "Thumbs.db", "desktop.ini", ".stfolder", "@eaDir"}
+
+// API is the part of *qbt.Client used by Scan.
+type API interface {
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error)
+	GetCategories() (map[string]qbt.Category, error)
+	GetDefaultSavePath() (string, error)
+	GetPreferences() (map[string]interface{}, error)
+}
+
+// Options configure Scan.
+type Options struct {
+	PathMap    map[string]string // Server path prefixes to local paths (default: paths are the same)
+	ExtraRoots []string          // Additional server paths to walk (optional)
+	Ignore     []string          // filepath.Match patterns for base names to skip (default: DefaultIgnore)
+}
+
+// File is a file on the local disk.
+type File struct {
+	Path string `json:"path"` // Local path
+	Size int64  `json:"size"`
+}
+
+// MissingFile is a torrent file that is not on the local disk.
+type MissingFile struct {
+	Path string `json:"path"` // Expected local path
+	Hash string `json:"hash"`
+	Name string `json:"name"` // Torrent name
+}
+
+// Report is the outcome of a scan.
+type Report struct {
+	Roots        []string      `json:"roots"`         // Local directories walked
+	Skipped      []string      `json:"skipped"`       // Local roots that do not exist
+	Orphaned     []File        `json:"orphaned"`      // Files no torrent references
+	OrphanedSize int64         `json:"orphaned_size"` // Total size of the orphaned files
+	Missing      []MissingFile `json:"missing"`       // Referenced files that are not on disk
+}
+
+// Scan walks the save paths of the instance behind api and reports orphaned
+// and missing files.
+func Scan(api API, opts Options) (*Report, error) {
+	if opts.Ignore == nil {
+		opts.Ignore = DefaultIgnore
+	}
+
+	roots, err := serverRoots(api)
+	if err != nil {
+		return nil, err
+	}
+	roots = append(roots, opts.ExtraRoots...)
+
+	torrents, err := api.ListTorrents(qbt.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list torrents: %w", err)
+	}
+
+	report := &Report{}
+	referenced := make(map[string]bool)
+	for _, t := range torrents {
+		files, err := api.ListTorrentFiles(t.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files of %s: %w", t.Hash, err)
+		}
+
+		bases := []string{t.SavePath}
+		if t.DownloadPath != "" {
+			bases = append(bases, t.DownloadPath)
+		}
+		for _, f := range files {
+			found := false
+			for _, base := range bases {
+				p := localPath(path.Join(base, f.Name), opts.PathMap)
+				referenced[p] = true
+				referenced[p+IncompleteExt] = true
+				if !found && (exists(p) || exists(p+IncompleteExt)) {
+					found = true
+				}
+			}
+			// Files not downloaded at all (e.g. skipped with priority 0) are
+			// not expected on disk
+			if !found && f.Progress > 0 {
+				report.Missing = append(report.Missing, MissingFile{
+					Path: localPath(path.Join(bases[0], f.Name), opts.PathMap),
+					Hash: t.Hash,
+					Name: t.Name,
+				})
+			}
+		}
+	}
+
+	for _, root := range nestedRemoved(roots, opts.PathMap) {
+		info, err := os.Stat(root)
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.IsDir()) {
+			report.Skipped = append(report.Skipped, root)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", root, err)
+		}
+		report.Roots = append(report.Roots, root)
+
+		err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ignored(d.Name(), opts.Ignore) && p != root {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || referenced[p] {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			report.Orphaned = append(report.Orphaned, File{Path: p, Size: info.Size()})
+			report.OrphanedSize += info.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+		}
+	}
+
+	sort.Slice(report.Missing, func(i, j int) bool { return report.Missing[i].Path < report.Missing[j].Path })
+	return report, nil
+}
+
+// serverRoots returns the default save path, the category save paths and the
+// download path, as the server sees them.
+func serverRoots(api API) ([]string, error) {
+	defaultPath, err := api.GetDefaultSavePath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default save path: %w", err)
+	}
+	roots := []string{defaultPath}
+
+	categories, err := api.GetCategories()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	for name, c := range categories {
+		switch {
+		case c.SavePath == "":
+			// qBittorrent saves into a subdirectory named after the category
+			roots = append(roots, path.Join(defaultPath, name))
+		case path.IsAbs(c.SavePath):
+			roots = append(roots, c.SavePath)
+		default:
+			roots = append(roots, path.Join(defaultPath, c.SavePath))
+		}
+	}
+
+	prefs, err := api.GetPreferences()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	if enabled, _ := prefs["temp_path_enabled"].(bool); enabled {
+		if p, _ := prefs["temp_path"].(string); p != "" {
+			roots = append(roots, p)
+		}
+	}
+	return roots, nil
+}
+
+// nestedRemoved maps roots to local paths and drops duplicates and roots
+// inside other roots, so no file is walked twice.
+func nestedRemoved(roots []string, pathMap map[string]string) []string {
+	local := make([]string, 0, len(roots))
+	for _, r := range roots {
+		if r != "" {
+			local = append(local, localPath(r, pathMap))
+		}
+	}
+	sort.Strings(local)
+
+	var result []string
+	for _, r := range local {
+		if !slices.ContainsFunc(result, func(parent string) bool { return within(r, parent) }) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// within reports whether p is dir or inside it.
+func within(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+func localPath(serverPath string, pathMap map[string]string) string {
+	return filepath.Clean(filepath.FromSlash(qbt.MapPath(path.Clean(serverPath), pathMap)))
+}
+
+func exists(p string) bool {
+	_, err := os.Lstat(p)
+	return err == nil
+}
+
+func ignored(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package orphans
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// fakeAPI serves torrents whose paths are under /downloads, as seen from a
+// container.
+type fakeAPI struct {
+	torrents   []*qbt.TorrentResponse
+	files      map[string][]*qbt.TorrentFile
+	categories map[string]qbt.Category
+	prefs      map[string]interface{}
+}
+
+func (f *fakeAPI) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	return f.torrents, nil
+}
+
+func (f *fakeAPI) ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error) {
+	files, ok := f.files[hash]
+	if !ok {
+		return nil, fmt.Errorf("torrent not found with hash: %s", hash)
+	}
+	return files, nil
+}
+
+func (f *fakeAPI) GetCategories() (map[string]qbt.Category, error) {
+	return f.categories, nil
+}
+
+func (f *fakeAPI) GetDefaultSavePath() (string, error) {
+	return "/downloads", nil
+}
+
+func (f *fakeAPI) GetPreferences() (map[string]interface{}, error) {
+	return f.prefs, nil
+}
+
+func writeFiles(t *testing.T, root string, files map[string]int) {
+	t.Helper()
+	for name, size := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	host := t.TempDir()
+	writeFiles(t, host, map[string]int{
+		"movies/Film/film.mkv":        10,
+		"movies/Old/old.mkv":          7,
+		"tv/Show/e01.mkv":             5,
+		"tv/.DS_Store":                1,
+		"incomplete/Show/e02.mkv.!qB": 3,
+		"leftover.iso":                4,
+	})
+
+	api := &fakeAPI{
+		torrents: []*qbt.TorrentResponse{
+			{Hash: "a", Name: "Film", SavePath: "/downloads/movies"},
+			{Hash: "b", Name: "Show", SavePath: "/downloads/tv", DownloadPath: "/downloads/incomplete"},
+		},
+		files: map[string][]*qbt.TorrentFile{
+			"a": {{Name: "Film/film.mkv", Progress: 1}, {Name: "Film/extras.mkv", Progress: 0}},
+			"b": {{Name: "Show/e01.mkv", Progress: 1}, {Name: "Show/e02.mkv", Progress: 0.5}, {Name: "Show/e03.mkv", Progress: 1}},
+		},
+		categories: map[string]qbt.Category{
+			"movies": {Name: "movies", SavePath: "/downloads/movies"},
+			"tv":     {Name: "tv"},
+			"music":  {Name: "music", SavePath: "/music"},
+		},
+		prefs: map[string]interface{}{"temp_path_enabled": true, "temp_path": "/downloads/incomplete"},
+	}
+
+	report, err := Scan(api, Options{PathMap: map[string]string{"/downloads": host}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.Roots) != 1 || report.Roots[0] != host {
+		t.Errorf("Expected nested roots to collapse into %s, got %v", host, report.Roots)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0] != "/music" {
+		t.Errorf("Expected the unmapped music root to be skipped, got %v", report.Skipped)
+	}
+
+	orphaned := map[string]int64{}
+	for _, f := range report.Orphaned {
+		orphaned[f.Path] = f.Size
+	}
+	expected := map[string]int64{
+		filepath.Join(host, "movies", "Old", "old.mkv"): 7,
+		filepath.Join(host, "leftover.iso"):             4,
+	}
+	if fmt.Sprint(orphaned) != fmt.Sprint(expected) || report.OrphanedSize != 11 {
+		t.Errorf("Expected orphans %v, got %v (%d bytes)", expected, orphaned, report.OrphanedSize)
+	}
+
+	if len(report.Missing) != 1 || report.Missing[0].Path != filepath.Join(host, "tv", "Show", "e03.mkv") || report.Missing[0].Hash != "b" {
+		t.Errorf("Expected e03.mkv to be missing, got %+v", report.Missing)
+	}
+}
+
+func TestScanFailsOnListError(t *testing.T) {
+	api := &fakeAPI{torrents: []*qbt.TorrentResponse{{Hash: "gone"}}}
+	if _, err := Scan(api, Options{}); err == nil {
+		t.Error("Expected an error when torrent files cannot be listed")
+	}
+}
+
+func TestNestedRemoved(t *testing.T) {
+	roots := nestedRemoved([]string{"/data/tv", "/data", "/data tv", "/data", "", "/other/x"}, nil)
+	expected := []string{"/data", "/data tv", "/other/x"}
+	if fmt.Sprint(roots) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, roots)
+	}
+}
@@ -0,0 +1,29 @@
+package qbt
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// MapPath rewrites the longest matching prefix of p according to pathMap,
+// e.g. to translate the save paths of a containerized instance. Prefixes
+// match whole path elements, so "/data" does not match "/database".
+func MapPath(p string, pathMap map[string]string) string {
+	prefixes := make([]string, 0, len(pathMap))
+	for prefix := range pathMap {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	for _, prefix := range prefixes {
+		trimmed := strings.TrimSuffix(prefix, "/")
+		if p == trimmed || p == prefix {
+			return pathMap[prefix]
+		}
+		if rest, ok := strings.CutPrefix(p, trimmed+"/"); ok {
+			return path.Join(pathMap[prefix], rest)
+		}
+	}
+	return p
+}
@@ -0,0 +1,19 @@
+package qbt
+
+import "testing"
+
+func TestMapPath(t *testing.T) {
+	pathMap := map[string]string{"/data": "/mnt/a", "/data/tv/": "/mnt/tv"}
+	testCases := map[string]string{
+		"/data":           "/mnt/a",
+		"/data/movies":    "/mnt/a/movies",
+		"/data/tv/show":   "/mnt/tv/show",
+		"/database/files": "/database/files",
+		"/other":          "/other",
+	}
+	for input, expected := range testCases {
+		if got := MapPath(input, pathMap); got != expected {
+			t.Errorf("MapPath(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+/*
+Package policy enforces share-ratio and seeding-time rules across torrents.
+
+A Rule selects torrents by category, tag and tracker domain, triggers when
+any of its thresholds is reached, and then stops, deletes, retags or limits
+the torrent:
+
+	rules := []policy.Rule{
+		{
+			Name:       "private tracker: seed 14 days then stop",
+			Trackers:   []string{"tracker.example.org"},
+			MinSeeding: 14 * 24 * time.Hour,
+			Action:     policy.Action{Type: policy.ActionStop},
+		},
+		{
+			Name:            "public: delete at ratio 2 or 7 days",
+			ExcludeTrackers: []string{"tracker.example.org"},
+			MinRatio:        2,
+			MinSeeding:      7 * 24 * time.Hour,
+			Action:          policy.Action{Type: policy.ActionDelete},
+		},
+		{
+			Name:        "tv: remove files after 30 days inactive",
+			Categories:  []string{"tv"},
+			MinInactive: 30 * 24 * time.Hour,
+			Action:      policy.Action{Type: policy.ActionDelete, DeleteFiles: true},
+		},
+	}
+
+	enforcer := policy.New(client, rules, policy.Options{DryRun: true, Audit: auditFile})
+	decisions, err := enforcer.Run()
+
+Rules are checked in order and the first rule that selects a torrent
+decides for it, even when its thresholds are not reached yet, so specific
+rules go before general ones. A torrent whose tracker domains cannot be
+determined is never selected by a rule that has Trackers or ExcludeTrackers,
+so a private torrent whose tracker is down does not fall through to a
+broader rule.
+*/
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// API is the part of *qbt.Client used by the enforcer.
+type API interface {
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	GetTorrentProperties(hash string) (*qbt.TorrentProperties, error)
+	GetTorrentTrackers(hash string) ([]*qbt.TorrentTracker, error)
+	StopTorrents(hash string) error
+	DeleteTorrents(hash string, deleteFiles bool) error
+	AddTorrentTags(hash string, tags []string) error
+	DeleteTorrentTags(hash string, tags []string) error
+	SetTorrentUploadLimit(hash string, limit int) error
+}
+
+// ActionType is what a rule does to a torrent.
+type ActionType string
+
+const (
+	ActionStop   ActionType = "stop"
+	ActionDelete ActionType = "delete"
+	ActionRetag  ActionType = "retag"
+	ActionLimit  ActionType = "limit"
+)
+
+// Action is the effect of a triggered rule.
+type Action struct {
+	Type        ActionType `json:"type"`
+	DeleteFiles bool       `json:"delete_files,omitempty"` // Delete: also remove the downloaded data
+	AddTags     []string   `json:"add_tags,omitempty"`     // Retag: tags to add
+	RemoveTags  []string   `json:"remove_tags,omitempty"`  // Retag: tags to remove
+	UploadLimit int        `json:"upload_limit,omitempty"` // Limit: upload limit in bytes/s
+}
+
+// Rule selects torrents and acts on them once a threshold is reached.
+type Rule struct {
+	Name string
+
+	// Selectors; a torrent must match every selector that is set
+	Categories      []string // Any of these categories
+	Tags            []string // Any of these tags
+	Trackers        []string // A tracker domain is one of these (subdomains included)
+	ExcludeTrackers []string // No tracker domain is one of these
+	CompletedOnly   bool     // Skip torrents that are still downloading
+
+	// Thresholds; the rule triggers when any threshold that is set is reached
+	MinRatio    float64       // Share ratio
+	MinSeeding  time.Duration // Time spent seeding
+	MinInactive time.Duration // Time since the last upload or download
+
+	// HoldFor is a guard: the rule never triggers before the torrent has
+	// seeded this long, e.g. to honour hit-and-run rules
+	HoldFor time.Duration
+
+	Action Action
+}
+
+// Decision records what a rule decided for a torrent.
+type Decision struct {
+	Time    time.Time `json:"time"`
+	Hash    string    `json:"hash"`
+	Name    string    `json:"name"`
+	Rule    string    `json:"rule"`
+	Action  Action    `json:"action"`
+	Reason  string    `json:"reason"`
+	DryRun  bool      `json:"dry_run"`
+	Applied bool      `json:"applied"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Options configure an Enforcer.
+type Options struct {
+	DryRun bool             // Record decisions without acting (default: false)
+	Select qbt.ListOptions  // Torrents to evaluate (default: all)
+	Audit  io.Writer        // Receives every decision as a JSON line (optional)
+	Now    func() time.Time // Clock used for inactivity (default: time.Now)
+}
+
+// Enforcer applies rules to the torrents of an instance.
+type Enforcer struct {
+	api   API
+	rules []Rule
+	opts  Options
+
+	auditMu sync.Mutex
+}
+
+// New creates an enforcer for rules.
+func New(api API, rules []Rule, opts Options) *Enforcer {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Enforcer{api: api, rules: rules, opts: opts}
+}
+
+// Run evaluates every selected torrent and acts on the triggered rules. A
+// torrent that cannot be evaluated or a failing action is recorded in its
+// decision and does not stop the run.
+func (e *Enforcer) Run() ([]Decision, error) {
+	torrents, err := e.api.ListTorrents(e.opts.Select)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list torrents: %w", err)
+	}
+
+	var decisions []Decision
+	failed := 0
+	for _, t := range torrents {
+		rule := e.match(t, e.trackerDomains(t))
+		if rule == nil {
+			continue
+		}
+
+		props, err := e.api.GetTorrentProperties(t.Hash)
+		if err != nil {
+			decision := Decision{
+				Time:   e.opts.Now(),
+				Hash:   t.Hash,
+				Name:   t.Name,
+				Rule:   rule.Name,
+				Action: rule.Action,
+				DryRun: e.opts.DryRun,
+				Error:  fmt.Sprintf("failed to get properties: %v", err),
+			}
+			failed++
+			e.audit(decision)
+			decisions = append(decisions, decision)
+			continue
+		}
+
+		decision, ok := e.decide(rule, t, props)
+		if !ok {
+			continue
+		}
+
+		if !e.opts.DryRun {
+			if err := e.apply(t, rule.Action); err != nil {
+				decision.Error = err.Error()
+				failed++
+			} else {
+				decision.Applied = true
+			}
+		}
+
+		e.audit(decision)
+		decisions = append(decisions, decision)
+	}
+
+	if failed > 0 {
+		return decisions, fmt.Errorf("%d of %d decisions failed", failed, len(decisions))
+	}
+	return decisions, nil
+}
+
+// Evaluate returns the decision of the first rule that selects t, if that rule
+// triggers and its action would change anything. props may be nil, in which
+// case the seeding time comes from t. The tracker domain comes from
+// t.Tracker only; when it is empty, rules with tracker selectors are skipped.
+func (e *Enforcer) Evaluate(t *qbt.TorrentResponse, props *qbt.TorrentProperties) (Decision, bool) {
+	var domains []string
+	if domain := TrackerDomain(t.Tracker); domain != "" {
+		domains = []string{domain}
+	}
+	rule := e.match(t, domains)
+	if rule == nil {
+		return Decision{}, false
+	}
+	return e.decide(rule, t, props)
+}
+
+// match returns the first rule whose selectors match t.
+func (e *Enforcer) match(t *qbt.TorrentResponse, domains []string) *Rule {
+	for i := range e.rules {
+		if e.rules[i].selects(t, domains) {
+			return &e.rules[i]
+		}
+	}
+	return nil
+}
+
+// trackerDomains returns the tracker domains of t. t.Tracker is empty while
+// no tracker is working, so the full tracker list is fetched then; nil means
+// the domains are unknown.
+func (e *Enforcer) trackerDomains(t *qbt.TorrentResponse) []string {
+	if domain := TrackerDomain(t.Tracker); domain != "" {
+		return []string{domain}
+	}
+	if !slices.ContainsFunc(e.rules, func(r Rule) bool { return r.filtersTrackers() }) {
+		return nil
+	}
+
+	trackers, err := e.api.GetTorrentTrackers(t.Hash)
+	if err != nil {
+		return nil
+	}
+	var domains []string
+	for _, tr := range trackers {
+		// DHT, PeX and LSD are listed as pseudo-trackers without a host
+		if domain := TrackerDomain(tr.URL); domain != "" && !slices.Contains(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+func (e *Enforcer) decide(rule *Rule, t *qbt.TorrentResponse, props *qbt.TorrentProperties) (Decision, bool) {
+	seeding := time.Duration(t.SeedingTime) * time.Second
+	if props != nil {
+		seeding = time.Duration(props.SeedingTime) * time.Second
+	}
+	now := e.opts.Now()
+	inactive := now.Sub(time.Unix(t.LastActivity, 0))
+
+	if seeding < rule.HoldFor {
+		return Decision{}, false
+	}
+
+	var reasons []string
+	if rule.MinRatio > 0 && t.Ratio >= rule.MinRatio {
+		reasons = append(reasons, fmt.Sprintf("ratio %.2f >= %.2f", t.Ratio, rule.MinRatio))
+	}
+	if rule.MinSeeding > 0 && seeding >= rule.MinSeeding {
+		reasons = append(reasons, fmt.Sprintf("seeding %s >= %s", seeding.Round(time.Minute), rule.MinSeeding))
+	}
+	if rule.MinInactive > 0 && t.LastActivity > 0 && inactive >= rule.MinInactive {
+		reasons = append(reasons, fmt.Sprintf("inactive %s >= %s", inactive.Round(time.Minute), rule.MinInactive))
+	}
+	if len(reasons) == 0 || !changes(t, rule.Action) {
+		return Decision{}, false
+	}
+
+	return Decision{
+		Time:   now,
+		Hash:   t.Hash,
+		Name:   t.Name,
+		Rule:   rule.Name,
+		Action: rule.Action,
+		Reason: strings.Join(reasons, ", "),
+		DryRun: e.opts.DryRun,
+	}, true
+}
+
+func (r *Rule) filtersTrackers() bool {
+	return len(r.Trackers) > 0 || len(r.ExcludeTrackers) > 0
+}
+
+func (r *Rule) selects(t *qbt.TorrentResponse, domains []string) bool {
+	if len(r.Categories) > 0 && !slices.Contains(r.Categories, t.Category) {
+		return false
+	}
+	if len(r.Tags) > 0 && !slices.ContainsFunc(torrentTags(t), func(tag string) bool { return slices.Contains(r.Tags, tag) }) {
+		return false
+	}
+	if r.filtersTrackers() && len(domains) == 0 {
+		return false
+	}
+	if len(r.Trackers) > 0 && !slices.ContainsFunc(domains, func(d string) bool { return matchesDomain(d, r.Trackers) }) {
+		return false
+	}
+	if len(r.ExcludeTrackers) > 0 && slices.ContainsFunc(domains, func(d string) bool { return matchesDomain(d, r.ExcludeTrackers) }) {
+		return false
+	}
+	if r.CompletedOnly && t.Progress < 1 {
+		return false
+	}
+	return true
+}
+
+// changes reports whether applying action to t would have an effect, so
+// that repeated runs do not record the same stop or retag again.
+func changes(t *qbt.TorrentResponse, action Action) bool {
+	switch action.Type {
+	case ActionStop:
+		return !strings.HasPrefix(t.State, "stopped") && !strings.HasPrefix(t.State, "paused")
+	case ActionRetag:
+		tags := torrentTags(t)
+		for _, tag := range action.AddTags {
+			if !slices.Contains(tags, tag) {
+				return true
+			}
+		}
+		for _, tag := range action.RemoveTags {
+			if slices.Contains(tags, tag) {
+				return true
+			}
+		}
+		return false
+	case ActionLimit:
+		return t.UpLimit != action.UploadLimit
+	default:
+		return true
+	}
+}
+
+func (e *Enforcer) apply(t *qbt.TorrentResponse, action Action) error {
+	switch action.Type {
+	case ActionStop:
+		return e.api.StopTorrents(t.Hash)
+	case ActionDelete:
+		return e.api.DeleteTorrents(t.Hash, action.DeleteFiles)
+	case ActionRetag:
+		if len(action.RemoveTags) > 0 {
+			if err := e.api.DeleteTorrentTags(t.Hash, action.RemoveTags); err != nil {
+				return err
+			}
+		}
+		if len(action.AddTags) > 0 {
+			return e.api.AddTorrentTags(t.Hash, action.AddTags)
+		}
+		return nil
+	case ActionLimit:
+		return e.api.SetTorrentUploadLimit(t.Hash, action.UploadLimit)
+	default:
+		return fmt.Errorf("unknown action %q", action.Type)
+	}
+}
+
+func (e *Enforcer) audit(decision Decision) {
+	if e.opts.Audit == nil {
+		return
+	}
+	data, err := json.Marshal(decision)
+	if err != nil {
+		return
+	}
+
+	e.auditMu.Lock()
+	defer e.auditMu.Unlock()
+	e.opts.Audit.Write(append(data, '\n'))
+}
+
+// TrackerDomain returns the host of a tracker URL without its port.
+func TrackerDomain(tracker string) string {
+	u, err := url.Parse(tracker)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func matchesDomain(domain string, domains []string) bool {
+	if domain == "" {
+		return false
+	}
+	for _, d := range domains {
+		d = strings.ToLower(d)
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func torrentTags(t *qbt.TorrentResponse) []string {
+	var tags []string
+	for _, tag := range strings.Split(t.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
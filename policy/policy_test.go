@@ -0,0 +1,232 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+const day = 24 * time.Hour
+
+var testNow = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeAPI records the actions taken.
+type fakeAPI struct {
+	torrents []*qbt.TorrentResponse
+	trackers map[string][]*qbt.TorrentTracker
+	noProps  string // Hash whose properties cannot be fetched
+	actions  []string
+}
+
+func (f *fakeAPI) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	return f.torrents, nil
+}
+
+func (f *fakeAPI) GetTorrentProperties(hash string) (*qbt.TorrentProperties, error) {
+	for _, t := range f.torrents {
+		if t.Hash == hash && hash != f.noProps {
+			return &qbt.TorrentProperties{SeedingTime: t.SeedingTime}, nil
+		}
+	}
+	return nil, fmt.Errorf("torrent not found with hash: %s", hash)
+}
+
+func (f *fakeAPI) GetTorrentTrackers(hash string) ([]*qbt.TorrentTracker, error) {
+	if trackers, ok := f.trackers[hash]; ok {
+		return trackers, nil
+	}
+	return nil, fmt.Errorf("torrent not found with hash: %s", hash)
+}
+
+func (f *fakeAPI) StopTorrents(hash string) error {
+	f.actions = append(f.actions, "stop "+hash)
+	return nil
+}
+
+func (f *fakeAPI) DeleteTorrents(hash string, deleteFiles bool) error {
+	f.actions = append(f.actions, fmt.Sprintf("delete %s files=%v", hash, deleteFiles))
+	return nil
+}
+
+func (f *fakeAPI) AddTorrentTags(hash string, tags []string) error {
+	f.actions = append(f.actions, fmt.Sprintf("tag %s %v", hash, tags))
+	return nil
+}
+
+func (f *fakeAPI) DeleteTorrentTags(hash string, tags []string) error {
+	f.actions = append(f.actions, fmt.Sprintf("untag %s %v", hash, tags))
+	return nil
+}
+
+func (f *fakeAPI) SetTorrentUploadLimit(hash string, limit int) error {
+	return fmt.Errorf("limit unavailable")
+}
+
+func testRules() []Rule {
+	return []Rule{
+		{
+			Name:       "private",
+			Trackers:   []string{"private.example"},
+			MinSeeding: 14 * day,
+			Action:     Action{Type: ActionStop},
+		},
+		{
+			Name:        "tv",
+			Categories:  []string{"tv"},
+			MinInactive: 30 * day,
+			Action:      Action{Type: ActionDelete, DeleteFiles: true},
+		},
+		{
+			Name:     "archive",
+			Tags:     []string{"archive"},
+			MinRatio: 1,
+			Action:   Action{Type: ActionRetag, AddTags: []string{"done"}, RemoveTags: []string{"archive"}},
+		},
+		{
+			Name:       "public",
+			MinRatio:   2,
+			MinSeeding: 7 * day,
+			HoldFor:    day,
+			Action:     Action{Type: ActionDelete},
+		},
+	}
+}
+
+func seconds(d time.Duration) int { return int(d / time.Second) }
+
+func TestEvaluate(t *testing.T) {
+	enforcer := New(&fakeAPI{}, testRules(), Options{Now: func() time.Time { return testNow }})
+
+	testCases := []struct {
+		name     string
+		torrent  *qbt.TorrentResponse
+		expected string // Rule name, or empty when nothing should happen
+	}{
+		{"private seeded long enough", &qbt.TorrentResponse{Tracker: "https://tracker.private.example:443/announce", SeedingTime: seconds(15 * day), Ratio: 5, State: "uploading"}, "private"},
+		{"private too early, ratio ignored", &qbt.TorrentResponse{Tracker: "https://private.example/a", SeedingTime: seconds(3 * day), Ratio: 5, State: "uploading"}, ""},
+		{"private already stopped", &qbt.TorrentResponse{Tracker: "https://private.example/a", SeedingTime: seconds(20 * day), State: "stoppedUP"}, ""},
+		{"tv inactive", &qbt.TorrentResponse{Tracker: "udp://open.example:1337", Category: "tv", LastActivity: testNow.Add(-31 * day).Unix()}, "tv"},
+		{"tv active", &qbt.TorrentResponse{Category: "tv", LastActivity: testNow.Add(-day).Unix(), Ratio: 9, SeedingTime: seconds(9 * day)}, ""},
+		{"archive", &qbt.TorrentResponse{Tags: "keep, archive", Ratio: 1.5, SeedingTime: seconds(2 * day)}, "archive"},
+		{"public ratio", &qbt.TorrentResponse{Tracker: "udp://open.example:1337", Ratio: 2.1, SeedingTime: seconds(2 * day)}, "public"},
+		{"public held", &qbt.TorrentResponse{Tracker: "udp://open.example:1337", Ratio: 3, SeedingTime: seconds(time.Hour)}, ""},
+		{"public neither", &qbt.TorrentResponse{Ratio: 1, SeedingTime: seconds(3 * day)}, ""},
+	}
+
+	for _, tc := range testCases {
+		decision, ok := enforcer.Evaluate(tc.torrent, nil)
+		if tc.expected == "" && ok {
+			t.Errorf("%s: expected no decision, got %+v", tc.name, decision)
+		}
+		if tc.expected != "" && (!ok || decision.Rule != tc.expected) {
+			t.Errorf("%s: expected rule %q, got %+v (ok=%v)", tc.name, tc.expected, decision, ok)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	api := &fakeAPI{torrents: []*qbt.TorrentResponse{
+		{Hash: "a", Tracker: "https://private.example/a", SeedingTime: seconds(15 * day), State: "uploading"},
+		{Hash: "b", Category: "tv", LastActivity: testNow.Add(-40 * day).Unix()},
+		{Hash: "c", Tags: "archive", Ratio: 1},
+		{Hash: "d", Ratio: 0.1},
+	}}
+
+	var audit bytes.Buffer
+	dryRun := New(api, testRules(), Options{DryRun: true, Audit: &audit, Now: func() time.Time { return testNow }})
+	decisions, err := dryRun.Run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(decisions) != 3 || len(api.actions) != 0 {
+		t.Fatalf("Dry run should decide without acting: %+v %v", decisions, api.actions)
+	}
+
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 audit lines, got %q", audit.String())
+	}
+	var logged Decision
+	if err := json.Unmarshal([]byte(lines[1]), &logged); err != nil {
+		t.Fatalf("Audit line is not JSON: %v", err)
+	}
+	if logged.Hash != "b" || !logged.DryRun || logged.Applied || !strings.Contains(logged.Reason, "inactive") {
+		t.Errorf("Unexpected audit entry: %+v", logged)
+	}
+
+	enforcer := New(api, testRules(), Options{Now: func() time.Time { return testNow }})
+	decisions, err = enforcer.Run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"stop a", "delete b files=true", "untag c [archive]", "tag c [done]"}
+	if fmt.Sprint(api.actions) != fmt.Sprint(expected) {
+		t.Errorf("Expected actions %v, got %v", expected, api.actions)
+	}
+	for _, d := range decisions {
+		if !d.Applied {
+			t.Errorf("Expected decision to be applied: %+v", d)
+		}
+	}
+}
+
+func TestRunRecordsFailures(t *testing.T) {
+	api := &fakeAPI{torrents: []*qbt.TorrentResponse{{Hash: "a", Category: "slow", Ratio: 3}}}
+	rules := []Rule{{Name: "throttle", Categories: []string{"slow"}, MinRatio: 2, Action: Action{Type: ActionLimit, UploadLimit: 1024}}}
+
+	decisions, err := New(api, rules, Options{}).Run()
+	if err == nil || len(decisions) != 1 || decisions[0].Applied || decisions[0].Error == "" {
+		t.Errorf("Expected the failed action to be recorded, got %v %+v", err, decisions)
+	}
+}
+
+func TestRunTrackerDown(t *testing.T) {
+	rules := []Rule{
+		{Name: "private", Trackers: []string{"private.example"}, MinSeeding: 14 * day, Action: Action{Type: ActionStop}},
+		{Name: "public", ExcludeTrackers: []string{"private.example"}, MinRatio: 2, Action: Action{Type: ActionDelete}},
+	}
+	api := &fakeAPI{
+		// Tracker is empty while no tracker is working
+		torrents: []*qbt.TorrentResponse{
+			{Hash: "a", Ratio: 3, SeedingTime: seconds(2 * day), State: "stalledUP"},
+			{Hash: "b", Ratio: 3, SeedingTime: seconds(2 * day), State: "stalledUP"},
+			{Hash: "c", Ratio: 3, SeedingTime: seconds(2 * day), State: "stalledUP"},
+		},
+		trackers: map[string][]*qbt.TorrentTracker{
+			"a": {{URL: "** [DHT] **"}, {URL: "https://tracker.private.example/announce", Status: 4}},
+			"b": {{URL: "udp://open.example:1337", Status: 4}},
+		},
+	}
+
+	decisions, err := New(api, rules, Options{Now: func() time.Time { return testNow }}).Run()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// a is private and not seeded long enough, c has unknown trackers
+	if len(decisions) != 1 || decisions[0].Hash != "b" || decisions[0].Rule != "public" {
+		t.Errorf("Expected only b to be deleted, got %+v", decisions)
+	}
+}
+
+func TestRunRecordsPropertiesFailure(t *testing.T) {
+	api := &fakeAPI{torrents: []*qbt.TorrentResponse{
+		{Hash: "gone", Category: "tv", LastActivity: testNow.Add(-40 * day).Unix()},
+		{Hash: "b", Category: "tv", LastActivity: testNow.Add(-40 * day).Unix()},
+	}, noProps: "gone"}
+
+	decisions, err := New(api, testRules(), Options{Now: func() time.Time { return testNow }}).Run()
+	if err == nil || len(decisions) != 2 {
+		t.Fatalf("Expected both torrents to be recorded and an error, got %v %+v", err, decisions)
+	}
+	if decisions[0].Hash != "gone" || decisions[0].Applied || !strings.Contains(decisions[0].Error, "properties") {
+		t.Errorf("Expected the properties failure to be recorded, got %+v", decisions[0])
+	}
+	if decisions[1].Hash != "b" || !decisions[1].Applied {
+		t.Errorf("Expected the run to continue, got %+v", decisions[1])
+	}
+}
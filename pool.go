@@ -0,0 +1,414 @@
+package qbt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RouteStrategy selects the instance that receives newly added torrents.
+type RouteStrategy int
+
+const (
+	// RouteRoundRobin cycles through instances in name order
+	RouteRoundRobin RouteStrategy = iota
+	// RouteLeastTorrents picks the instance with the fewest torrents
+	RouteLeastTorrents
+	// RouteMostFreeSpace picks the instance reporting the most free disk space
+	RouteMostFreeSpace
+	// RouteCategoryAffinity picks an instance that already has the torrent's category,
+	// falling back to the fewest torrents
+	RouteCategoryAffinity
+)
+
+func (s RouteStrategy) String() string {
+	switch s {
+	case RouteRoundRobin:
+		return "round-robin"
+	case RouteLeastTorrents:
+		return "least-torrents"
+	case RouteMostFreeSpace:
+		return "most-free-space"
+	case RouteCategoryAffinity:
+		return "category-affinity"
+	default:
+		return fmt.Sprintf("RouteStrategy(%d)", int(s))
+	}
+}
+
+// Pool manages several named qBittorrent instances. Reads fan out to every
+// instance concurrently and are merged; adds are routed to a single instance.
+type Pool struct {
+	mu       sync.RWMutex
+	clients  map[string]*Client
+	strategy RouteStrategy
+	next     int // Round-robin cursor
+}
+
+// InstanceTorrent is a torrent tagged with the instance it lives on.
+type InstanceTorrent struct {
+	Instance string `json:"instance"`
+	*TorrentResponse
+}
+
+// InstanceCategory is a category tagged with the instance it is defined on.
+type InstanceCategory struct {
+	Instance string `json:"instance"`
+	Category
+}
+
+// InstanceTransferInfo is the transfer info of a single instance.
+type InstanceTransferInfo struct {
+	Instance string `json:"instance"`
+	*TransferInfoResponse
+}
+
+// PoolTransferInfo aggregates transfer info across instances.
+type PoolTransferInfo struct {
+	Instances []*InstanceTransferInfo `json:"instances"`
+	Total     TransferInfoResponse    `json:"total"` // Speeds, data and DHT nodes summed over instances
+}
+
+// PoolError collects the per-instance failures of a fan-out call. Results from
+// the instances that succeeded are still returned alongside it.
+type PoolError struct {
+	Errors map[string]error
+}
+
+func (e *PoolError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %v", name, e.Errors[name]))
+	}
+	return fmt.Sprintf("%d instance(s) failed: %s", len(names), strings.Join(parts, "; "))
+}
+
+func (e *PoolError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// NewPool creates an empty pool that routes adds with the given strategy.
+func NewPool(strategy RouteStrategy) *Pool {
+	return &Pool{
+		clients:  make(map[string]*Client),
+		strategy: strategy,
+	}
+}
+
+// Add registers a client under name.
+func (p *Pool) Add(name string, client *Client) error {
+	if name == "" {
+		return errors.New("instance name is required")
+	}
+	if client == nil {
+		return fmt.Errorf("client for instance %s is nil", name)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.clients[name]; exists {
+		return fmt.Errorf("instance %s already exists", name)
+	}
+	p.clients[name] = client
+	return nil
+}
+
+// Remove unregisters an instance and returns its client, or nil if unknown.
+func (p *Pool) Remove(name string) *Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	client := p.clients[name]
+	delete(p.clients, name)
+	return client
+}
+
+// Client returns the client registered under name.
+func (p *Pool) Client(name string) (*Client, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	client, ok := p.clients[name]
+	return client, ok
+}
+
+// Names returns the registered instance names in sorted order.
+func (p *Pool) Names() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	names := make([]string, 0, len(p.clients))
+	for name := range p.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetStrategy changes the routing strategy used by AddTorrentLink.
+func (p *Pool) SetStrategy(strategy RouteStrategy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.strategy = strategy
+}
+
+// Close logs out of every instance.
+func (p *Pool) Close() error {
+	_, err := fanOut(p, func(name string, client *Client) (struct{}, error) {
+		return struct{}{}, client.Close()
+	})
+	return err
+}
+
+// fanOut calls fn concurrently for every instance. The returned error is a
+// *PoolError when at least one instance failed.
+func fanOut[T any](p *Pool, fn func(name string, client *Client) (T, error)) (map[string]T, error) {
+	p.mu.RLock()
+	clients := make(map[string]*Client, len(p.clients))
+	for name, client := range p.clients {
+		clients[name] = client
+	}
+	p.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]T, len(clients))
+	failures := make(map[string]error)
+
+	for name, client := range clients {
+		wg.Add(1)
+		go func(name string, client *Client) {
+			defer wg.Done()
+			result, err := fn(name, client)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures[name] = err
+				return
+			}
+			results[name] = result
+		}(name, client)
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return results, &PoolError{Errors: failures}
+	}
+	return results, nil
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ListTorrents lists torrents on every instance, tagging each with its instance.
+func (p *Pool) ListTorrents(opts ListOptions) ([]*InstanceTorrent, error) {
+	results, err := fanOut(p, func(name string, client *Client) ([]*TorrentResponse, error) {
+		return client.ListTorrents(opts)
+	})
+
+	var torrents []*InstanceTorrent
+	for _, name := range sortedKeys(results) {
+		for _, torrent := range results[name] {
+			torrents = append(torrents, &InstanceTorrent{Instance: name, TorrentResponse: torrent})
+		}
+	}
+	return torrents, err
+}
+
+// GetTransferInfo returns the transfer info of every instance and their sum.
+func (p *Pool) GetTransferInfo() (*PoolTransferInfo, error) {
+	results, err := fanOut(p, func(name string, client *Client) (*TransferInfoResponse, error) {
+		return client.GetTransferInfo()
+	})
+
+	info := &PoolTransferInfo{}
+	for _, name := range sortedKeys(results) {
+		result := results[name]
+		info.Instances = append(info.Instances, &InstanceTransferInfo{Instance: name, TransferInfoResponse: result})
+		info.Total.DlInfoSpeed += result.DlInfoSpeed
+		info.Total.DlInfoData += result.DlInfoData
+		info.Total.UpInfoSpeed += result.UpInfoSpeed
+		info.Total.UpInfoData += result.UpInfoData
+		info.Total.DlRateLimit += result.DlRateLimit
+		info.Total.UpRateLimit += result.UpRateLimit
+		info.Total.DhtNodes += result.DhtNodes
+	}
+	return info, err
+}
+
+// GetCategories returns the categories of every instance, tagged with their instance.
+func (p *Pool) GetCategories() ([]*InstanceCategory, error) {
+	results, err := fanOut(p, func(name string, client *Client) (map[string]Category, error) {
+		return client.GetCategories()
+	})
+
+	var categories []*InstanceCategory
+	for _, name := range sortedKeys(results) {
+		for _, key := range sortedKeys(results[name]) {
+			category := results[name][key]
+			if category.Name == "" {
+				category.Name = key
+			}
+			categories = append(categories, &InstanceCategory{Instance: name, Category: category})
+		}
+	}
+	return categories, err
+}
+
+// Status returns the cached connection status of every instance.
+func (p *Pool) Status() map[string]*ConnectionStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	statuses := make(map[string]*ConnectionStatus, len(p.clients))
+	for name, client := range p.clients {
+		statuses[name] = client.GetConnectionStatus()
+	}
+	return statuses
+}
+
+// RefreshStatus refreshes the connection status of every instance concurrently.
+func (p *Pool) RefreshStatus(ctx context.Context) map[string]*ConnectionStatus {
+	statuses, _ := fanOut(p, func(name string, client *Client) (*ConnectionStatus, error) {
+		return client.RefreshConnectionStatus(ctx), nil
+	})
+	return statuses
+}
+
+// AddTorrentLink routes the torrent to an instance chosen by the pool strategy
+// and returns the name of that instance.
+func (p *Pool) AddTorrentLink(opts TorrentConfig) (string, error) {
+	name, err := p.Route(opts)
+	if err != nil {
+		return "", err
+	}
+
+	client, ok := p.Client(name)
+	if !ok {
+		return "", fmt.Errorf("instance %s was removed while routing", name)
+	}
+	if err := client.AddTorrentLink(opts); err != nil {
+		return name, fmt.Errorf("instance %s: %w", name, err)
+	}
+	return name, nil
+}
+
+// Route returns the instance that would receive a torrent added with opts.
+// Instances with permanently failed authentication or an open circuit are skipped.
+func (p *Pool) Route(opts TorrentConfig) (string, error) {
+	p.mu.RLock()
+	strategy := p.strategy
+	candidates := make(map[string]*Client)
+	for name, client := range p.clients {
+		if !client.IsAuthFailed() && client.circuitState() != CircuitOpen {
+			candidates[name] = client
+		}
+	}
+	p.mu.RUnlock()
+
+	if len(candidates) == 0 {
+		return "", errors.New("no available instance to route to")
+	}
+
+	switch strategy {
+	case RouteLeastTorrents:
+		return routeLeastTorrents(candidates)
+	case RouteMostFreeSpace:
+		return routeMostFreeSpace(candidates)
+	case RouteCategoryAffinity:
+		return routeCategoryAffinity(candidates, opts.Category)
+	default:
+		return p.routeRoundRobin(candidates), nil
+	}
+}
+
+func (p *Pool) routeRoundRobin(candidates map[string]*Client) string {
+	names := sortedKeys(candidates)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	name := names[p.next%len(names)]
+	p.next++
+	return name
+}
+
+// pickBest returns the key with the highest score, breaking ties by name.
+func pickBest[T int | int64](scores map[string]T, better func(a, b T) bool) (string, bool) {
+	best := ""
+	for _, name := range sortedKeys(scores) {
+		if best == "" || better(scores[name], scores[best]) {
+			best = name
+		}
+	}
+	return best, best != ""
+}
+
+func routeLeastTorrents(candidates map[string]*Client) (string, error) {
+	counts, err := fanOut(&Pool{clients: candidates}, func(name string, client *Client) (int, error) {
+		torrents, err := client.ListTorrents(ListOptions{})
+		return len(torrents), err
+	})
+
+	name, ok := pickBest(counts, func(a, b int) bool { return a < b })
+	if !ok {
+		return "", fmt.Errorf("failed to count torrents on any instance: %w", err)
+	}
+	return name, nil
+}
+
+func routeMostFreeSpace(candidates map[string]*Client) (string, error) {
+	space, err := fanOut(&Pool{clients: candidates}, func(name string, client *Client) (int, error) {
+		data, err := client.GetMainData()
+		if err != nil {
+			return 0, err
+		}
+		return data.ServerState.FreeSpaceOnDisk, nil
+	})
+
+	name, ok := pickBest(space, func(a, b int) bool { return a > b })
+	if !ok {
+		return "", fmt.Errorf("failed to get free space from any instance: %w", err)
+	}
+	return name, nil
+}
+
+func routeCategoryAffinity(candidates map[string]*Client, category string) (string, error) {
+	if category != "" {
+		categories, _ := fanOut(&Pool{clients: candidates}, func(name string, client *Client) (map[string]Category, error) {
+			return client.GetCategories()
+		})
+
+		matching := make(map[string]*Client)
+		for name, instanceCategories := range categories {
+			if _, ok := instanceCategories[category]; ok {
+				matching[name] = candidates[name]
+			}
+		}
+		if len(matching) > 0 {
+			candidates = matching
+		}
+	}
+	return routeLeastTorrents(candidates)
+}
@@ -0,0 +1,180 @@
+package qbt
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newPoolInstance starts a fake instance with the given torrents, categories and free space.
+func newPoolInstance(t *testing.T, torrents int, categories []string, freeSpace int) (*Client, *[]string) {
+	t.Helper()
+
+	var added []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/torrents/info", func(w http.ResponseWriter, r *http.Request) {
+		items := make([]string, torrents)
+		for i := range items {
+			items[i] = fmt.Sprintf(`{"hash":"h%d","name":"t%d","magnet_uri":"magnet:?xt=urn:btih:h%d"}`, i, i, i)
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(items, ","))
+	})
+	mux.HandleFunc("/api/v2/torrents/categories", func(w http.ResponseWriter, r *http.Request) {
+		items := make([]string, len(categories))
+		for i, name := range categories {
+			items[i] = fmt.Sprintf(`%q:{"name":%q,"savePath":"/data/%s"}`, name, name, name)
+		}
+		fmt.Fprintf(w, "{%s}", strings.Join(items, ","))
+	})
+	mux.HandleFunc("/api/v2/sync/maindata", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"server_state":{"free_space_on_disk":%d}}`, freeSpace)
+	})
+	mux.HandleFunc("/api/v2/transfer/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"dl_info_speed":100,"up_info_speed":10,"dht_nodes":5}`))
+	})
+	mux.HandleFunc("/api/v2/torrents/add", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := url.ParseQuery(readBody(r))
+		added = append(added, body.Get("urls"))
+	})
+
+	return newFakeClient(t, newFakeServer(t, mux), Config{}), &added
+}
+
+func readBody(r *http.Request) string {
+	var sb strings.Builder
+	buf := make([]byte, 1024)
+	for {
+		n, err := r.Body.Read(buf)
+		sb.Write(buf[:n])
+		if err != nil {
+			return sb.String()
+		}
+	}
+}
+
+func TestPoolFanOut(t *testing.T) {
+	a, _ := newPoolInstance(t, 2, []string{"movies"}, 100)
+	b, _ := newPoolInstance(t, 1, []string{"tv", "movies"}, 200)
+
+	pool := NewPool(RouteRoundRobin)
+	if err := pool.Add("a", a); err != nil {
+		t.Fatalf("Failed to add instance: %v", err)
+	}
+	if err := pool.Add("b", b); err != nil {
+		t.Fatalf("Failed to add instance: %v", err)
+	}
+	if err := pool.Add("a", b); err == nil {
+		t.Error("Adding a duplicate instance name should fail")
+	}
+
+	torrents, err := pool.ListTorrents(ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(torrents) != 3 {
+		t.Fatalf("Expected 3 torrents, got %d", len(torrents))
+	}
+	if torrents[0].Instance != "a" || torrents[2].Instance != "b" {
+		t.Errorf("Torrents should be tagged and ordered by instance, got %s..%s", torrents[0].Instance, torrents[2].Instance)
+	}
+
+	info, err := pool.GetTransferInfo()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(info.Instances) != 2 || info.Total.DlInfoSpeed != 200 || info.Total.DhtNodes != 10 {
+		t.Errorf("Unexpected aggregated transfer info: %+v", info.Total)
+	}
+
+	categories, err := pool.GetCategories()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(categories) != 3 {
+		t.Errorf("Expected 3 categories, got %d", len(categories))
+	}
+
+	statuses := pool.Status()
+	if statuses["a"].Status != StatusConnected || statuses["b"].Status != StatusConnected {
+		t.Errorf("Expected both instances connected, got %+v %+v", statuses["a"], statuses["b"])
+	}
+}
+
+func TestPoolPartialFailure(t *testing.T) {
+	a, _ := newPoolInstance(t, 2, nil, 0)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/torrents/info", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	broken := newFakeClient(t, newFakeServer(t, mux), Config{MaxRetries: 1})
+
+	pool := NewPool(RouteRoundRobin)
+	pool.Add("a", a)
+	pool.Add("broken", broken)
+
+	torrents, err := pool.ListTorrents(ListOptions{})
+	if len(torrents) != 2 {
+		t.Errorf("Expected results from the healthy instance, got %d", len(torrents))
+	}
+
+	var poolErr *PoolError
+	if !errors.As(err, &poolErr) {
+		t.Fatalf("Expected *PoolError, got %v", err)
+	}
+	if _, ok := poolErr.Errors["broken"]; !ok || len(poolErr.Errors) != 1 {
+		t.Errorf("Expected only the broken instance to fail, got %v", poolErr.Errors)
+	}
+}
+
+func TestPoolRouting(t *testing.T) {
+	a, addedA := newPoolInstance(t, 5, []string{"movies"}, 500)
+	b, addedB := newPoolInstance(t, 1, []string{"tv"}, 100)
+
+	pool := NewPool(RouteRoundRobin)
+	pool.Add("a", a)
+	pool.Add("b", b)
+
+	testCases := []struct {
+		strategy RouteStrategy
+		category string
+		expected string
+	}{
+		{RouteLeastTorrents, "", "b"},
+		{RouteMostFreeSpace, "", "a"},
+		{RouteCategoryAffinity, "movies", "a"},
+		{RouteCategoryAffinity, "tv", "b"},
+		{RouteCategoryAffinity, "music", "b"},
+	}
+
+	for _, tc := range testCases {
+		pool.SetStrategy(tc.strategy)
+		name, err := pool.Route(TorrentConfig{Category: tc.category})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.strategy, err)
+		}
+		if name != tc.expected {
+			t.Errorf("%s (category %q): expected %s, got %s", tc.strategy, tc.category, tc.expected, name)
+		}
+	}
+
+	pool.SetStrategy(RouteRoundRobin)
+	for i := 0; i < 4; i++ {
+		if _, err := pool.AddTorrentLink(TorrentConfig{MagnetURI: fmt.Sprintf("magnet:?xt=urn:btih:%d", i)}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if len(*addedA) != 2 || len(*addedB) != 2 {
+		t.Errorf("Round robin should spread adds evenly, got a=%d b=%d", len(*addedA), len(*addedB))
+	}
+}
+
+func TestPoolRouteEmpty(t *testing.T) {
+	pool := NewPool(RouteLeastTorrents)
+	if _, err := pool.Route(TorrentConfig{}); err == nil {
+		t.Error("Routing on an empty pool should fail")
+	}
+}
@@ -0,0 +1,205 @@
+package qbt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket refilled at rate tokens per second, holding at most burst tokens.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a token is available or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	// Reserve the token up front so concurrent waiters queue behind each other
+	l.tokens--
+	if l.tokens >= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give the reservation back so it doesn't delay other callers
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// inFlightLimiter caps the number of concurrent requests.
+type inFlightLimiter chan struct{}
+
+func newInFlightLimiter(max int) inFlightLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return make(inFlightLimiter, max)
+}
+
+func (s inFlightLimiter) acquire(ctx context.Context) error {
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s inFlightLimiter) release() {
+	<-s
+}
+
+// throttle waits for both the rate limiter and an in-flight slot. The returned
+// release func must be called once the response is no longer in use. Waiting is
+// bounded by ctx and the configured request timeout.
+func (qb *Client) throttle(ctx context.Context) (func(), error) {
+	qb.mu.RLock()
+	limiter, slots, timeout := qb.rateLimiter, qb.inFlight, qb.config.RequestTimeout
+	qb.mu.RUnlock()
+
+	noop := func() {}
+	if limiter == nil && slots == nil {
+		return noop, nil
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if limiter != nil {
+		if err := limiter.wait(ctx); err != nil {
+			return noop, NewClientError(ErrorCodeTimeout, "Timed out waiting for rate limiter", err, false)
+		}
+	}
+
+	if slots == nil {
+		return noop, nil
+	}
+	if err := slots.acquire(ctx); err != nil {
+		return noop, NewClientError(ErrorCodeTimeout, "Timed out waiting for a free request slot", err, false)
+	}
+
+	var once sync.Once
+	return func() { once.Do(slots.release) }, nil
+}
+
+// releasingBody releases the in-flight slot when the response body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// retryAfterError carries the server-requested delay from a Retry-After header.
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+// withRetryAfter attaches the Retry-After hint of a 429/503 response to err.
+func withRetryAfter(resp *http.Response, err error) error {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return err
+	}
+	delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return err
+	}
+	return &retryAfterError{err: err, delay: delay}
+}
+
+// parseRetryAfter parses a Retry-After value given either in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// retryDelay returns the wait before the next attempt: the exponential backoff,
+// stretched to honor a Retry-After hint but never beyond MaxDelay.
+func (qb *Client) retryDelay(attempt int, err error) time.Duration {
+	delay := qb.calculateBackoffDelay(attempt)
+
+	var retryAfter *retryAfterError
+	if errors.As(err, &retryAfter) && retryAfter.delay > delay {
+		delay = retryAfter.delay
+		if qb.retryConfig.MaxDelay > 0 && delay > qb.retryConfig.MaxDelay {
+			delay = qb.retryConfig.MaxDelay
+		}
+	}
+	return delay
+}
+
+// retryableStatusError builds the error returned for a retryable HTTP status.
+func retryableStatusError(resp *http.Response) error {
+	return withRetryAfter(resp, fmt.Errorf("retryable status code: %d", resp.StatusCode))
+}
@@ -0,0 +1,155 @@
+package qbt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurstThenWait(t *testing.T) {
+	limiter := newRateLimiter(20, 2)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.wait(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Two tokens are free, the remaining two need 1/20s each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected limiter to delay requests beyond the burst, took %v", elapsed)
+	}
+}
+
+func TestRateLimiterHonorsContext(t *testing.T) {
+	limiter := newRateLimiter(0.1, 1)
+	if err := limiter.wait(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	if newRateLimiter(0, 5) != nil {
+		t.Error("Rate limiter should be nil when rate is zero")
+	}
+	if newInFlightLimiter(0) != nil {
+		t.Error("In-flight limiter should be nil when max is zero")
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	var current, peak int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/transfer/info", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"dl_info_speed": 1}`))
+	})
+
+	client := newFakeClient(t, newFakeServer(t, mux), Config{MaxInFlight: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetTransferInfo(); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, saw %d", peak)
+	}
+}
+
+func TestRetryAfterHonored(t *testing.T) {
+	var calls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/app/webapiVersion", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("2.11.2"))
+	})
+
+	client := newFakeClient(t, newFakeServer(t, mux), Config{})
+
+	start := time.Now()
+	version, err := client.GetAPIVersion()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if version != "2.11.2" {
+		t.Errorf("Expected version 2.11.2, got %q", version)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected retry to wait for Retry-After (1s), took %v", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"Wed, 01 Jan 2025 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 Jan 2025 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tc := range testCases {
+		delay, ok := parseRetryAfter(tc.value, now)
+		if ok != tc.ok || delay != tc.expected {
+			t.Errorf("%q: expected (%v, %v), got (%v, %v)", tc.value, tc.expected, tc.ok, delay, ok)
+		}
+	}
+}
+
+func TestRetryDelayCapsRetryAfter(t *testing.T) {
+	client := &Client{
+		retryConfig: &RetryConfig{
+			BaseDelay:     100 * time.Millisecond,
+			MaxDelay:      time.Second,
+			BackoffFactor: 2.0,
+		},
+	}
+
+	err := &retryAfterError{err: errors.New("retryable status code: 503"), delay: time.Hour}
+	if delay := client.retryDelay(0, err); delay != time.Second {
+		t.Errorf("Expected Retry-After capped at MaxDelay, got %v", delay)
+	}
+
+	if delay := client.retryDelay(0, errors.New("other")); delay != 100*time.Millisecond {
+		t.Errorf("Expected plain backoff, got %v", delay)
+	}
+}
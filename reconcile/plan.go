@@ -0,0 +1,494 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// API is the part of *qbt.Client used by reconciliation.
+type API interface {
+	GetCategories() (map[string]qbt.Category, error)
+	CreateCategory(name, savePath string) error
+	EditCategory(name, savePath string) error
+	DeleteCategory(name string) error
+	GetTags() ([]string, error)
+	CreateTags(tags []string) error
+	DeleteTags(tags []string) error
+	ListRSSItems() (map[string]qbt.RSSFeed, []string, error)
+	AddRSSFolder(path string) error
+	AddRSSFeed(feedURL, path string) error
+	RemoveRSSFeed(path string) error
+	GetRSSRules() (map[string]qbt.RSSRule, error)
+	SetRSSRule(name string, rule qbt.RSSRule) error
+	RemoveRSSRule(name string) error
+	GetPreferences() (map[string]interface{}, error)
+	SetPreferences(prefs map[string]interface{}) error
+}
+
+// Kinds of managed items
+const (
+	KindCategory   = "category"
+	KindTag        = "tag"
+	KindRSSFolder  = "rss_folder"
+	KindRSSFeed    = "rss_feed"
+	KindRSSRule    = "rss_rule"
+	KindPreference = "preference"
+)
+
+// Action is what a change does to an item.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change is a single step of a Plan. From and To hold the current and
+// desired values; From is empty on create and To on delete.
+type Change struct {
+	Kind   string      `json:"kind"`
+	Action Action      `json:"action"`
+	Name   string      `json:"name"`
+	From   interface{} `json:"from,omitempty"`
+	To     interface{} `json:"to,omitempty"`
+
+	apply func(API) error
+}
+
+func (c Change) String() string {
+	switch c.Action {
+	case ActionCreate:
+		return fmt.Sprintf("+ %s %s: %s", c.Kind, c.Name, formatValue(c.To))
+	case ActionDelete:
+		return fmt.Sprintf("- %s %s", c.Kind, c.Name)
+	default:
+		return fmt.Sprintf("~ %s %s: %s -> %s", c.Kind, c.Name, formatValue(c.From), formatValue(c.To))
+	}
+}
+
+// Plan is the ordered list of changes that converges an instance. Warnings
+// name desired settings that cannot be applied, such as preferences the
+// server does not know; they are left out of Changes.
+type Plan struct {
+	Changes  []Change `json:"changes"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Empty reports whether the instance already matches the desired state.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Count returns the number of changes with the given action.
+func (p *Plan) Count(action Action) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// String renders the warnings and the plan one change per line, followed by
+// a summary.
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, w := range p.Warnings {
+		fmt.Fprintf(&b, "! %s\n", w)
+	}
+	if p.Empty() {
+		b.WriteString("No changes. The instance matches the desired state.\n")
+		return b.String()
+	}
+
+	for _, c := range p.Changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete.\n",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionDelete))
+	return b.String()
+}
+
+// Options control reconciliation.
+type Options struct {
+	Prune  bool // Delete items of managed sections that are not in the desired state (default: false)
+	DryRun bool // Compute the plan without applying it (default: false)
+}
+
+// Reconcile computes the plan for desired and applies it unless
+// opts.DryRun is set. The plan is returned in both cases.
+func Reconcile(api API, desired *State, opts Options) (*Plan, error) {
+	plan, err := Diff(api, desired, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return plan, nil
+	}
+	return plan, Apply(api, plan)
+}
+
+// Diff compares desired with the current state of the instance. Creates and
+// updates come first, in dependency order (categories and feeds before the
+// rules that reference them); deletes follow in reverse order.
+func Diff(api API, desired *State, opts Options) (*Plan, error) {
+	var upserts, deletes [][]Change
+
+	if desired.Categories != nil {
+		u, d, err := diffCategories(api, desired.Categories, opts.Prune)
+		if err != nil {
+			return nil, err
+		}
+		upserts, deletes = append(upserts, u), append(deletes, d)
+	}
+
+	if desired.Tags != nil {
+		u, d, err := diffTags(api, desired.Tags, opts.Prune)
+		if err != nil {
+			return nil, err
+		}
+		upserts, deletes = append(upserts, u), append(deletes, d)
+	}
+
+	if desired.RSS != nil && desired.RSS.Feeds != nil {
+		u, d, err := diffFeeds(api, desired.RSS.Feeds, opts.Prune)
+		if err != nil {
+			return nil, err
+		}
+		upserts, deletes = append(upserts, u), append(deletes, d)
+	}
+
+	if desired.RSS != nil && desired.RSS.Rules != nil {
+		u, d, err := diffRules(api, desired.RSS.Rules, opts.Prune)
+		if err != nil {
+			return nil, err
+		}
+		upserts, deletes = append(upserts, u), append(deletes, d)
+	}
+
+	var warnings []string
+	if desired.Preferences != nil {
+		u, w, err := diffPreferences(api, desired.Preferences)
+		if err != nil {
+			return nil, err
+		}
+		upserts, warnings = append(upserts, u), w
+	}
+
+	plan := &Plan{Changes: []Change{}, Warnings: warnings}
+	for _, changes := range upserts {
+		plan.Changes = append(plan.Changes, changes...)
+	}
+	for i := len(deletes) - 1; i >= 0; i-- {
+		plan.Changes = append(plan.Changes, deletes[i]...)
+	}
+	return plan, nil
+}
+
+// Apply executes the changes of a plan returned by Diff in order and stops
+// at the first failure. Consecutive preference changes are sent in a single
+// call, since qBittorrent applies preferences together.
+func Apply(api API, plan *Plan) error {
+	changes := plan.Changes
+	for len(changes) > 0 {
+		c := changes[0]
+		if c.apply == nil {
+			return fmt.Errorf("cannot apply %s %s %s: plan was not computed by Diff", c.Action, c.Kind, c.Name)
+		}
+		if c.Kind != KindPreference {
+			if err := c.apply(api); err != nil {
+				return fmt.Errorf("failed to %s %s %s: %w", c.Action, c.Kind, c.Name, err)
+			}
+			changes = changes[1:]
+			continue
+		}
+
+		prefs := make(map[string]interface{})
+		var names []string
+		for len(changes) > 0 && changes[0].Kind == KindPreference && changes[0].apply != nil {
+			prefs[changes[0].Name] = changes[0].To
+			names = append(names, changes[0].Name)
+			changes = changes[1:]
+		}
+		if err := api.SetPreferences(prefs); err != nil {
+			return fmt.Errorf("failed to update preferences %s: %w", strings.Join(names, ", "), err)
+		}
+	}
+	return nil
+}
+
+func diffCategories(api API, desired map[string]Category, prune bool) (upserts, deletes []Change, err error) {
+	current, err := api.GetCategories()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, name := range sortedKeys(desired) {
+		name, want := name, desired[name]
+		have, ok := current[name]
+		switch {
+		case !ok:
+			upserts = append(upserts, Change{
+				Kind: KindCategory, Action: ActionCreate, Name: name, To: want,
+				apply: func(api API) error { return api.CreateCategory(name, want.SavePath) },
+			})
+		case have.SavePath != want.SavePath:
+			upserts = append(upserts, Change{
+				Kind: KindCategory, Action: ActionUpdate, Name: name, From: Category{SavePath: have.SavePath}, To: want,
+				apply: func(api API) error { return api.EditCategory(name, want.SavePath) },
+			})
+		}
+	}
+
+	if prune {
+		for _, name := range sortedKeys(current) {
+			if _, ok := desired[name]; ok {
+				continue
+			}
+			name := name
+			deletes = append(deletes, Change{
+				Kind: KindCategory, Action: ActionDelete, Name: name, From: Category{SavePath: current[name].SavePath},
+				apply: func(api API) error { return api.DeleteCategory(name) },
+			})
+		}
+	}
+	return upserts, deletes, nil
+}
+
+func diffTags(api API, desired []string, prune bool) (upserts, deletes []Change, err error) {
+	current, err := api.GetTags()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	have := make(map[string]bool, len(current))
+	for _, tag := range current {
+		have[tag] = true
+	}
+	want := make(map[string]bool, len(desired))
+	for _, tag := range desired {
+		want[tag] = true
+	}
+
+	for _, tag := range sortedKeys(want) {
+		if have[tag] {
+			continue
+		}
+		tag := tag
+		upserts = append(upserts, Change{
+			Kind: KindTag, Action: ActionCreate, Name: tag, To: tag,
+			apply: func(api API) error { return api.CreateTags([]string{tag}) },
+		})
+	}
+
+	if prune {
+		for _, tag := range sortedKeys(have) {
+			if want[tag] {
+				continue
+			}
+			tag := tag
+			deletes = append(deletes, Change{
+				Kind: KindTag, Action: ActionDelete, Name: tag, From: tag,
+				apply: func(api API) error { return api.DeleteTags([]string{tag}) },
+			})
+		}
+	}
+	return upserts, deletes, nil
+}
+
+func diffFeeds(api API, desired map[string]string, prune bool) (upserts, deletes []Change, err error) {
+	current, folders, err := api.ListRSSItems()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Feeds can only be added to existing folders, so create the missing
+	// ones first, parents before children. Folders are never pruned.
+	existing := make(map[string]bool, len(folders))
+	for _, folder := range folders {
+		existing[folder] = true
+	}
+	for _, path := range sortedKeys(desired) {
+		elements := strings.Split(path, qbt.RSSPathSeparator)
+		for i := 1; i < len(elements); i++ {
+			folder := strings.Join(elements[:i], qbt.RSSPathSeparator)
+			if existing[folder] {
+				continue
+			}
+			existing[folder] = true
+			upserts = append(upserts, Change{
+				Kind: KindRSSFolder, Action: ActionCreate, Name: folder, To: folder,
+				apply: func(api API) error { return api.AddRSSFolder(folder) },
+			})
+		}
+	}
+
+	for _, path := range sortedKeys(desired) {
+		path, want := path, desired[path]
+		feed, ok := current[path]
+		have := feed.URL
+		switch {
+		case !ok:
+			upserts = append(upserts, Change{
+				Kind: KindRSSFeed, Action: ActionCreate, Name: path, To: want,
+				apply: func(api API) error { return api.AddRSSFeed(want, path) },
+			})
+		case have != want:
+			// A feed's URL cannot be edited on older servers, so replace the feed
+			upserts = append(upserts, Change{
+				Kind: KindRSSFeed, Action: ActionUpdate, Name: path, From: have, To: want,
+				apply: func(api API) error {
+					if err := api.RemoveRSSFeed(path); err != nil {
+						return err
+					}
+					return api.AddRSSFeed(want, path)
+				},
+			})
+		}
+	}
+
+	if prune {
+		for _, path := range sortedKeys(current) {
+			if _, ok := desired[path]; ok {
+				continue
+			}
+			path := path
+			deletes = append(deletes, Change{
+				Kind: KindRSSFeed, Action: ActionDelete, Name: path, From: current[path].URL,
+				apply: func(api API) error { return api.RemoveRSSFeed(path) },
+			})
+		}
+	}
+	return upserts, deletes, nil
+}
+
+func diffRules(api API, desired map[string]qbt.RSSRule, prune bool) (upserts, deletes []Change, err error) {
+	current, err := api.GetRSSRules()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, name := range sortedKeys(desired) {
+		name, want := name, desired[name]
+		have, ok := current[name]
+		switch {
+		case !ok:
+			upserts = append(upserts, Change{
+				Kind: KindRSSRule, Action: ActionCreate, Name: name, To: want,
+				apply: func(api API) error { return api.SetRSSRule(name, want) },
+			})
+		case !sameRule(have, want):
+			upserts = append(upserts, Change{
+				Kind: KindRSSRule, Action: ActionUpdate, Name: name, From: have, To: want,
+				apply: func(api API) error { return api.SetRSSRule(name, want) },
+			})
+		}
+	}
+
+	if prune {
+		for _, name := range sortedKeys(current) {
+			if _, ok := desired[name]; ok {
+				continue
+			}
+			name := name
+			deletes = append(deletes, Change{
+				Kind: KindRSSRule, Action: ActionDelete, Name: name, From: current[name],
+				apply: func(api API) error { return api.RemoveRSSRule(name) },
+			})
+		}
+	}
+	return upserts, deletes, nil
+}
+
+// sameRule compares the configurable fields of two rules, ignoring match
+// history and treating nil and empty feed lists alike.
+func sameRule(have, want qbt.RSSRule) bool {
+	have.LastMatch, want.LastMatch = "", ""
+	have.PreviouslyMatchedEpisodes, want.PreviouslyMatchedEpisodes = nil, nil
+	if len(have.AffectedFeeds) == 0 && len(want.AffectedFeeds) == 0 {
+		have.AffectedFeeds, want.AffectedFeeds = nil, nil
+	}
+	if want.AddPaused == nil {
+		have.AddPaused = nil
+	}
+	return reflect.DeepEqual(have, want)
+}
+
+// diffPreferences compares the desired preferences with the server's. Keys
+// the server does not return are ignored by it when set, so they are
+// reported as warnings instead of changes that would never converge.
+func diffPreferences(api API, desired map[string]interface{}) ([]Change, []string, error) {
+	current, err := api.GetPreferences()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var upserts []Change
+	var warnings []string
+	for _, key := range sortedKeys(desired) {
+		key := key
+		want, err := normalize(desired[key])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid preference %s: %w", key, err)
+		}
+
+		have, ok := current[key]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("preference %s is not known to the server and is skipped", key))
+			continue
+		}
+		if reflect.DeepEqual(have, want) {
+			continue
+		}
+
+		// Apply batches consecutive preference changes into one call
+		upserts = append(upserts, Change{
+			Kind: KindPreference, Action: ActionUpdate, Name: key, From: have, To: want,
+			apply: func(api API) error { return api.SetPreferences(map[string]interface{}{key: want}) },
+		})
+	}
+	return upserts, warnings, nil
+}
+
+// normalize converts v to the form encoding/json decodes it into (e.g. all
+// numbers become float64) so it compares equal to values read from the server.
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case Category:
+		return fmt.Sprintf("save_path=%q", v.SavePath)
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
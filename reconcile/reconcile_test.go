@@ -0,0 +1,244 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// fakeAPI is an in-memory instance.
+type fakeAPI struct {
+	categories map[string]qbt.Category
+	tags       []string
+	feeds      map[string]qbt.RSSFeed // Keyed by full path
+	folders    []string
+	rules      map[string]qbt.RSSRule
+	prefs      map[string]interface{}
+	writes     int
+	prefWrites int
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{
+		categories: map[string]qbt.Category{
+			"movies": {Name: "movies", SavePath: "/old/movies"},
+			"stale":  {Name: "stale", SavePath: "/stale"},
+		},
+		tags: []string{"keep", "unused"},
+		feeds: map[string]qbt.RSSFeed{
+			"showrss":       {URL: "https://example.com/old.rss"},
+			`Folder\nested`: {URL: "https://example.com/nested.rss"},
+		},
+		folders: []string{"Folder"},
+		rules: map[string]qbt.RSSRule{
+			"tv": {Enabled: true, MustContain: "720p", LastMatch: "yesterday"},
+		},
+		prefs: map[string]interface{}{"max_active_downloads": float64(3), "queueing_enabled": true},
+	}
+}
+
+func (f *fakeAPI) GetCategories() (map[string]qbt.Category, error) { return f.categories, nil }
+func (f *fakeAPI) CreateCategory(name, savePath string) error {
+	f.writes++
+	f.categories[name] = qbt.Category{Name: name, SavePath: savePath}
+	return nil
+}
+func (f *fakeAPI) EditCategory(name, savePath string) error { return f.CreateCategory(name, savePath) }
+func (f *fakeAPI) DeleteCategory(name string) error {
+	f.writes++
+	delete(f.categories, name)
+	return nil
+}
+func (f *fakeAPI) GetTags() ([]string, error) { return f.tags, nil }
+func (f *fakeAPI) CreateTags(tags []string) error {
+	f.writes++
+	f.tags = append(f.tags, tags...)
+	return nil
+}
+func (f *fakeAPI) DeleteTags(tags []string) error {
+	f.writes++
+	var kept []string
+	for _, tag := range f.tags {
+		if tag != tags[0] {
+			kept = append(kept, tag)
+		}
+	}
+	f.tags = kept
+	return nil
+}
+func (f *fakeAPI) ListRSSItems() (map[string]qbt.RSSFeed, []string, error) {
+	return f.feeds, f.folders, nil
+}
+func (f *fakeAPI) AddRSSFolder(path string) error {
+	f.writes++
+	f.folders = append(f.folders, path)
+	return nil
+}
+func (f *fakeAPI) AddRSSFeed(feedURL, path string) error {
+	if i := strings.LastIndex(path, qbt.RSSPathSeparator); i >= 0 && !slices.Contains(f.folders, path[:i]) {
+		return fmt.Errorf("parent folder %s does not exist", path[:i])
+	}
+	f.writes++
+	f.feeds[path] = qbt.RSSFeed{URL: feedURL}
+	return nil
+}
+func (f *fakeAPI) RemoveRSSFeed(path string) error {
+	f.writes++
+	delete(f.feeds, path)
+	return nil
+}
+func (f *fakeAPI) GetRSSRules() (map[string]qbt.RSSRule, error) { return f.rules, nil }
+func (f *fakeAPI) SetRSSRule(name string, rule qbt.RSSRule) error {
+	f.writes++
+	f.rules[name] = rule
+	return nil
+}
+func (f *fakeAPI) RemoveRSSRule(name string) error {
+	f.writes++
+	delete(f.rules, name)
+	return nil
+}
+func (f *fakeAPI) GetPreferences() (map[string]interface{}, error) { return f.prefs, nil }
+func (f *fakeAPI) SetPreferences(prefs map[string]interface{}) error {
+	f.writes++
+	f.prefWrites++
+	// Like qBittorrent, unknown keys are ignored
+	for k, v := range prefs {
+		if _, ok := f.prefs[k]; ok {
+			f.prefs[k] = v
+		}
+	}
+	return nil
+}
+
+const testState = `
+categories:
+  movies: {save_path: /data/movies}
+  tv: {save_path: /data/tv}
+tags: [keep, private]
+rss:
+  feeds:
+    showrss: https://example.com/new.rss
+    Folder\nested: https://example.com/nested.rss
+    News\daily: https://example.com/daily.rss
+  rules:
+    tv:
+      enabled: true
+      mustContain: 1080p
+preferences:
+  max_active_downloads: 5
+  max_active_uploads: 2
+  queueing_enabled: true
+`
+
+func TestLoad(t *testing.T) {
+	state, err := Load([]byte(testState))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.Categories["tv"].SavePath != "/data/tv" || len(state.Tags) != 2 {
+		t.Errorf("Unexpected state: %+v", state)
+	}
+	if rule := state.RSS.Rules["tv"]; !rule.Enabled || rule.MustContain != "1080p" {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+
+	fromJSON, err := Load([]byte(`{"tags": ["a"], "preferences": {"dht": false}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(fromJSON.Tags) != 1 || fromJSON.Preferences["dht"] != false || fromJSON.Categories != nil {
+		t.Errorf("Unexpected state: %+v", fromJSON)
+	}
+
+	if _, err := Load([]byte("categories: [")); err == nil {
+		t.Error("Expected an error for invalid YAML")
+	}
+}
+
+func TestDiffAndApply(t *testing.T) {
+	desired, err := Load([]byte(testState))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	api := newFakeAPI()
+
+	plan, err := Reconcile(api, desired, Options{Prune: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if api.writes != 0 {
+		t.Errorf("Dry run should not change anything, got %d writes", api.writes)
+	}
+
+	expected := `~ category movies: save_path="/old/movies" -> save_path="/data/movies"
++ category tv: save_path="/data/tv"
++ tag private: "private"
++ rss_folder News: "News"
++ rss_feed News\daily: "https://example.com/daily.rss"
+~ rss_feed showrss: "https://example.com/old.rss" -> "https://example.com/new.rss"
+~ rss_rule tv: `
+	if !strings.Contains(plan.String(), "\n"+expected) {
+		t.Errorf("Unexpected plan:\n%s", plan)
+	}
+	for _, line := range []string{
+		"~ preference max_active_downloads: 3 -> 5\n",
+		"- tag unused\n- category stale\n",
+		"! preference max_active_uploads is not known to the server and is skipped\n",
+		"Plan: 4 to create, 4 to update, 2 to delete.\n",
+	} {
+		if !strings.Contains(plan.String(), line) {
+			t.Errorf("Expected plan to contain %q:\n%s", line, plan)
+		}
+	}
+
+	var decoded Plan
+	data, _ := json.Marshal(plan)
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.Changes) != len(plan.Changes) {
+		t.Errorf("Plan should round-trip through JSON: %v", err)
+	}
+	if err := Apply(api, &decoded); err == nil {
+		t.Error("A decoded plan should not be applicable")
+	}
+
+	if err := Apply(api, plan); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if api.prefWrites != 1 {
+		t.Errorf("Expected preferences to be set in one call, got %d", api.prefWrites)
+	}
+
+	again, err := Diff(api, desired, Options{Prune: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !again.Empty() {
+		t.Errorf("Expected no changes after apply, got:\n%s", again)
+	}
+	if _, ok := api.feeds[`Folder\nested`]; !ok || !slices.Contains(api.folders, "Folder") {
+		t.Error("Feeds in folders should be matched by their full path, and folders not pruned")
+	}
+}
+
+func TestDiffWithoutPrune(t *testing.T) {
+	api := newFakeAPI()
+	plan, err := Diff(api, &State{Categories: map[string]Category{}, Tags: []string{}}, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("Expected no deletes without Prune, got:\n%s", plan)
+	}
+
+	plan, err = Diff(api, &State{Tags: []string{}}, Options{Prune: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if plan.Count(ActionDelete) != 2 || plan.Changes[0].Kind != KindTag {
+		t.Errorf("Only managed sections should be pruned, got:\n%s", plan)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Package reconcile converges a qBittorrent instance towards a declarative
+desired state: categories, tags, RSS feeds and auto-downloading rules, and
+preference overrides.
+
+A state document is YAML or JSON:
+
+	categories:
+	  movies: {save_path: /data/movies}
+	  tv: {save_path: /data/tv}
+	tags: [private, keep]
+	rss:
+	  feeds:
+	    showrss: https://showrss.info/user/1234.rss
+	  rules:
+	    tv-1080p:
+	      enabled: true
+	      mustContain: 1080p
+	      affectedFeeds: [https://showrss.info/user/1234.rss]
+	      assignedCategory: tv
+	preferences:
+	  max_active_downloads: 5
+	  queueing_enabled: true
+
+Sections that are left out are not managed. Items missing from a managed
+section are only deleted when Options.Prune is set. Preferences use the key
+names of app/preferences and only the listed keys are compared; keys the
+server does not return are skipped with a warning in Plan.Warnings.
+
+Diff computes a Plan, which prints as a readable summary (String) or
+marshals to JSON, and Apply executes it:
+
+	desired, err := reconcile.LoadFile("seedbox.yaml")
+	plan, err := reconcile.Reconcile(client, desired, reconcile.Options{DryRun: true})
+	fmt.Print(plan)
+*/
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	qbt "github.com/jfxdev/go-qbt"
+	"gopkg.in/yaml.v3"
+)
+
+// State is the desired configuration of an instance.
+type State struct {
+	Categories  map[string]Category    `json:"categories,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	RSS         *RSS                   `json:"rss,omitempty"`
+	Preferences map[string]interface{} `json:"preferences,omitempty"`
+}
+
+// Category is the desired configuration of a category.
+type Category struct {
+	SavePath string `json:"save_path"`
+}
+
+// RSS lists the desired feeds, keyed by item path, and auto-downloading rules,
+// keyed by rule name. A nil map leaves that part unmanaged.
+type RSS struct {
+	Feeds map[string]string      `json:"feeds,omitempty"`
+	Rules map[string]qbt.RSSRule `json:"rules,omitempty"`
+}
+
+// Load parses a YAML or JSON state document.
+func Load(data []byte) (*State, error) {
+	// Decode YAML generically and re-encode it as JSON so that one set of
+	// field names (the JSON ones, matching the Web API) serves both formats
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(normalized, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	return &state, nil
+}
+
+// LoadFile reads and parses the state document at path.
+func LoadFile(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	return Load(data)
+}
@@ -16,7 +16,8 @@ type RequestOptions struct {
 	CookieJar      http.CookieJar
 	UpdateCookies  bool
 	PreRequestHook func() error
-	Method         string // HTTP method to use (GET, POST, etc.)
+	Transport      http.RoundTripper // Transport used to send the request (default: http.DefaultTransport)
+	Method         string            // HTTP method to use (GET, POST, etc.)
 }
 
 // Function type used to apply functional options to RequestOptions
@@ -93,6 +94,13 @@ func WithUpdateCookies() RequestOption {
 	}
 }
 
+// WithTransport sets the RoundTripper used to send the request
+func WithTransport(transport http.RoundTripper) RequestOption {
+	return func(o *RequestOptions) {
+		o.Transport = transport
+	}
+}
+
 // WithPreRequestHook sets a hook executed right before the request is sent
 func WithPreRequestHook(hook func() error) RequestOption {
 	return func(o *RequestOptions) {
@@ -116,7 +124,7 @@ func Do(method, url string, opts ...RequestOption) (*http.Response, error) {
 	}
 
 	// Create an HTTP client with the configured timeout
-	client := &http.Client{Timeout: options.Timeout}
+	client := &http.Client{Timeout: options.Timeout, Transport: options.Transport}
 
 	// Attach CookieJar if provided
 	if options.CookieJar != nil {
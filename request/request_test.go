@@ -99,3 +99,26 @@ func TestWithHeaders(t *testing.T) {
 		t.Fatalf("Erro na requisição: %v", err)
 	}
 }
+
+// Testa a opção WithTransport (servidor TLS com o transporte do próprio servidor)
+func TestWithTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// Sem o transporte, o certificado autoassinado é rejeitado
+	if _, err := Do(http.MethodGet, server.URL); err == nil {
+		t.Fatal("Esperado erro de certificado sem o transporte configurado")
+	}
+
+	resp, err := Do(http.MethodGet, server.URL, WithTransport(server.Client().Transport))
+	if err != nil {
+		t.Fatalf("Erro na requisição: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Esperado status 200, mas recebeu %d", resp.StatusCode)
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/jfxdev/go-qbt/request"
 )
@@ -19,6 +23,14 @@ func (qb *Client) doWithRetry(method, endpoint string, body []byte, headers map[
 
 	// Use a simple retry without context to avoid cancellation issues
 	err = qb.retryWithBackoff(func() error {
+		// While the circuit is not closed, go through the accessibility check so
+		// the call either fails fast or becomes the half-open probe
+		if state := qb.circuitState(); state == CircuitOpen || state == CircuitHalfOpen {
+			if err := qb.checkAccessibility(context.Background()); err != nil {
+				return err
+			}
+		}
+
 		// Ensure we are logged in using context.Background() to avoid cancellation
 		if err := qb.ensureLoginWithContext(context.Background()); err != nil {
 			return fmt.Errorf("failed to ensure login: %w", err)
@@ -29,34 +41,65 @@ func (qb *Client) doWithRetry(method, endpoint string, body []byte, headers map[
 			bodyReader = bytes.NewReader(body)
 		}
 
+		// Wait for the rate limiter and a free in-flight slot
+		release, err := qb.throttle(context.Background())
+		if err != nil {
+			return err
+		}
+
 		// Perform the request without context to avoid cancellation issues
 		resp, err = request.Do(method, endpoint,
 			request.WithBody(bodyReader),
+			request.WithHeaders(qb.authHeaders()),
 			request.WithHeaders(headers),
 			request.WithCookieJar(qb.config.jar),
+			request.WithTransport(qb.roundTripper()),
 		)
 
 		if err != nil {
+			release()
+			qb.recordCircuit(ClassifyError(err))
 			return err
 		}
 
+		// Hold the in-flight slot until the caller is done with the body
+		resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+
+		if resp.StatusCode >= 500 {
+			qb.recordCircuit(classifyHTTPStatusCode(resp.StatusCode, ""))
+		} else {
+			qb.recordCircuit(nil)
+		}
+
 		// Check for authentication errors and invalidate cookies
 		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			resp.Body.Close()
+			qb.deleteSession()
 			qb.invalidateCookies()
 			return fmt.Errorf("authentication error: status code %d", resp.StatusCode)
 		}
 
-		// Retry on retryable status codes
+		// Retry on retryable status codes, honoring Retry-After on 429/503
 		if qb.isRetryableStatusCode(resp.StatusCode) {
-			return fmt.Errorf("retryable status code: %d", resp.StatusCode)
+			resp.Body.Close()
+			return retryableStatusError(resp)
 		}
 
 		return nil
-	}, fmt.Sprintf("%s %s", method, endpoint))
+	}, operationName(method, endpoint))
 
 	return resp, err
 }
 
+// operationName identifies a request in logs and errors by method and API path,
+// keeping the host and query string (which may carry credentials or hashes) out.
+func operationName(method, endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Path != "" {
+		return fmt.Sprintf("%s %s", method, u.Path)
+	}
+	return fmt.Sprintf("%s %s", method, endpoint)
+}
+
 func (qb *Client) isRetryableStatusCode(statusCode int) bool {
 	for _, code := range qb.retryConfig.RetryableCodes {
 		if statusCode == code {
@@ -71,6 +114,27 @@ func (qb *Client) ListTorrents(opts ListOptions) ([]*TorrentResponse, error) {
 	if opts.Category != "" {
 		params.Add("category", opts.Category)
 	}
+	if opts.Filter != "" {
+		params.Add("filter", opts.Filter)
+	}
+	if opts.Tag != "" {
+		params.Add("tag", opts.Tag)
+	}
+	if len(opts.Hashes) > 0 {
+		params.Add("hashes", strings.Join(opts.Hashes, "|"))
+	}
+	if opts.Sort != "" {
+		params.Add("sort", opts.Sort)
+	}
+	if opts.Reverse {
+		params.Add("reverse", "true")
+	}
+	if opts.Limit > 0 {
+		params.Add("limit", fmt.Sprintf("%d", opts.Limit))
+	}
+	if opts.Offset > 0 {
+		params.Add("offset", fmt.Sprintf("%d", opts.Offset))
+	}
 
 	endpoint := fmt.Sprintf("%s/api/v2/torrents/info?%s", qb.config.BaseURL, params.Encode())
 
@@ -95,14 +159,32 @@ func (qb *Client) ListTorrents(opts ListOptions) ([]*TorrentResponse, error) {
 	return response, nil
 }
 
-func (qb *Client) AddTorrentLink(opts TorrentConfig) error {
+// torrentConfigValues builds the torrents/add form fields shared by links and
+// files. qBittorrent 5 reads "stopped" and older versions "paused"; each
+// ignores the other, so both are sent.
+func torrentConfigValues(opts TorrentConfig) url.Values {
 	data := url.Values{
-		"urls":          {opts.MagnetURI},
 		"savepath":      {opts.Directory},
 		"category":      {opts.Category},
 		"paused":        {fmt.Sprintf("%v", opts.Paused)},
+		"stopped":       {fmt.Sprintf("%v", opts.Paused)},
 		"skip_checking": {fmt.Sprintf("%v", opts.SkipChecking)},
 	}
+	if len(opts.Tags) > 0 {
+		data.Set("tags", strings.Join(opts.Tags, ","))
+	}
+	if opts.ContentLayout != "" {
+		data.Set("contentLayout", opts.ContentLayout)
+	}
+	if opts.AutoTMM != nil {
+		data.Set("autoTMM", fmt.Sprintf("%v", *opts.AutoTMM))
+	}
+	return data
+}
+
+func (qb *Client) AddTorrentLink(opts TorrentConfig) error {
+	data := torrentConfigValues(opts)
+	data.Set("urls", opts.MagnetURI)
 
 	headers := map[string]string{
 		"Content-Type": "application/x-www-form-urlencoded",
@@ -124,6 +206,53 @@ func (qb *Client) AddTorrentLink(opts TorrentConfig) error {
 	return nil
 }
 
+// AddTorrentFile uploads a .torrent file. MagnetURI in opts is ignored.
+func (qb *Client) AddTorrentFile(filename string, torrent []byte, opts TorrentConfig) error {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	for key, values := range torrentConfigValues(opts) {
+		for _, value := range values {
+			if err := writer.WriteField(key, value); err != nil {
+				return fmt.Errorf("failed to build torrent upload: %w", err)
+			}
+		}
+	}
+
+	part, err := writer.CreateFormFile("torrents", filepath.Base(filename))
+	if err != nil {
+		return fmt.Errorf("failed to build torrent upload: %w", err)
+	}
+	if _, err := part.Write(torrent); err != nil {
+		return fmt.Errorf("failed to build torrent upload: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to build torrent upload: %w", err)
+	}
+
+	headers := map[string]string{
+		"Content-Type": writer.FormDataContentType(),
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v2/torrents/add", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodPost, endpoint, buf.Bytes(), headers)
+	if err != nil {
+		return fmt.Errorf("failed to add torrent file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to add torrent file. Status: %d, Response: %s", resp.StatusCode, body)
+	}
+	if strings.TrimSpace(string(body)) == "Fails." {
+		return fmt.Errorf("failed to add torrent file: torrent rejected by server")
+	}
+
+	return nil
+}
+
 // Reusable pause/resume function
 func (qb *Client) updateTorrentStatus(action, hash string, optional map[string]string) error {
 	data := url.Values{"hashes": {hash}}
@@ -151,12 +280,22 @@ func (qb *Client) updateTorrentStatus(action, hash string, optional map[string]s
 	return nil
 }
 
+// StartTorrents starts torrents, using torrents/resume on servers older than qBittorrent 5
 func (qb *Client) StartTorrents(hash string) error {
-	return qb.updateTorrentStatus("start", hash, nil)
+	action, err := qb.torrentAction("start")
+	if err != nil {
+		return err
+	}
+	return qb.updateTorrentStatus(action, hash, nil)
 }
 
+// StopTorrents stops torrents, using torrents/pause on servers older than qBittorrent 5
 func (qb *Client) StopTorrents(hash string) error {
-	return qb.updateTorrentStatus("stop", hash, nil)
+	action, err := qb.torrentAction("stop")
+	if err != nil {
+		return err
+	}
+	return qb.updateTorrentStatus(action, hash, nil)
 }
 
 func (qb *Client) DeleteTorrents(hash string, deleteFiles bool) error {
@@ -175,10 +314,12 @@ func (qb *Client) DecreaseTorrentsPriority(hash string) error {
 	return qb.updateTorrentStatus("decreasePrio", hash, nil)
 }
 
+// AddTorrentTags adds tags to a torrent. qBittorrent reads a single
+// comma-separated tags parameter, so the tags are joined into one value.
 func (qb *Client) AddTorrentTags(hash string, tags []string) error {
 	data := url.Values{
 		"hashes": {hash},
-		"tags":   tags,
+		"tags":   {strings.Join(tags, ",")},
 	}
 
 	headers := map[string]string{
@@ -201,10 +342,11 @@ func (qb *Client) AddTorrentTags(hash string, tags []string) error {
 	return nil
 }
 
+// DeleteTorrentTags removes tags from a torrent.
 func (qb *Client) DeleteTorrentTags(hash string, tags []string) error {
 	data := url.Values{
 		"hashes": {hash},
-		"tags":   tags,
+		"tags":   {strings.Join(tags, ",")},
 	}
 
 	headers := map[string]string{
@@ -309,6 +451,40 @@ func (qb *Client) ListTorrentFiles(hash string) ([]*TorrentFile, error) {
 	return files, nil
 }
 
+// SetFilePriority sets the priority of files, identified by their index in
+// ListTorrentFiles (0 = do not download, 1 = normal, 6 = high, 7 = maximum)
+func (qb *Client) SetFilePriority(hash string, fileIDs []int, priority int) error {
+	ids := make([]string, len(fileIDs))
+	for i, id := range fileIDs {
+		ids[i] = fmt.Sprintf("%d", id)
+	}
+
+	data := url.Values{
+		"hash":     {hash},
+		"id":       {strings.Join(ids, "|")},
+		"priority": {fmt.Sprintf("%d", priority)},
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v2/torrents/filePrio", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodPost, endpoint, []byte(data.Encode()), headers)
+	if err != nil {
+		return fmt.Errorf("failed to set file priority: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to set file priority. Status: %d, Response: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
 func (qb *Client) ForceRecheck(hash string) error {
 	data := url.Values{
 		"hashes": {hash},
@@ -440,12 +616,14 @@ func (qb *Client) GetTorrentProperties(hash string) (*TorrentProperties, error)
 	return &properties, nil
 }
 
+// StopTorrent is an alias of StopTorrents
 func (qb *Client) StopTorrent(hash string) error {
-	return qb.updateTorrentStatus("pause", hash, nil)
+	return qb.StopTorrents(hash)
 }
 
+// StartTorrent is an alias of StartTorrents
 func (qb *Client) StartTorrent(hash string) error {
-	return qb.updateTorrentStatus("resume", hash, nil)
+	return qb.StartTorrents(hash)
 }
 
 func (qb *Client) ForceStart(hash string) error {
@@ -473,6 +651,35 @@ func (qb *Client) ForceStart(hash string) error {
 	return nil
 }
 
+// ExportTorrent returns the .torrent file of a torrent (qBittorrent 4.5+)
+func (qb *Client) ExportTorrent(hash string) ([]byte, error) {
+	if err := qb.requireFeature(FeatureTorrentExport); err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("hash", hash)
+
+	endpoint := fmt.Sprintf("%s/api/v2/torrents/export?%s", qb.config.BaseURL, params.Encode())
+
+	resp, err := qb.doWithRetry(http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export torrent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to export torrent. Status: %d, Response: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
 func (qb *Client) GetMainData() (*MainDataResponse, error) {
 	// Use a more robust approach without context for the main data call
 	endpoint := fmt.Sprintf("%s/api/v2/sync/maindata", qb.config.BaseURL)
@@ -562,7 +769,12 @@ func (qb *Client) GetAPIVersion() (string, error) {
 	return string(body), nil
 }
 
-func (qb *Client) GetBuildInfo() (*TransferInfoResponse, error) {
+// GetBuildInfo gets the Qt, libtorrent, Boost, OpenSSL and zlib versions of the server
+func (qb *Client) GetBuildInfo() (*BuildInfoResponse, error) {
+	if err := qb.requireFeature(FeatureBuildInfo); err != nil {
+		return nil, err
+	}
+
 	resp, err := qb.doWithRetry(http.MethodGet, fmt.Sprintf("%s/api/v2/app/buildInfo", qb.config.BaseURL), nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get build info: %w", err)
@@ -579,7 +791,7 @@ func (qb *Client) GetBuildInfo() (*TransferInfoResponse, error) {
 		return nil, fmt.Errorf("failed to get build info. Status: %d, Response: %s", resp.StatusCode, string(body))
 	}
 
-	var result TransferInfoResponse
+	var result BuildInfoResponse
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("error decoding response (status: %d, body: %s): %w", resp.StatusCode, string(body), err)
 	}
@@ -587,6 +799,56 @@ func (qb *Client) GetBuildInfo() (*TransferInfoResponse, error) {
 	return &result, nil
 }
 
+// GetDefaultSavePath gets the default save path for new torrents
+func (qb *Client) GetDefaultSavePath() (string, error) {
+	resp, err := qb.doWithRetry(http.MethodGet, fmt.Sprintf("%s/api/v2/app/defaultSavePath", qb.config.BaseURL), nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get default save path: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response body (status: %d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get default save path. Status: %d, Response: %s", resp.StatusCode, string(body))
+	}
+
+	return string(body), nil
+}
+
+// GetServerInfo gets the versions, build information and default save path of
+// the server. BuildInfo is nil on servers that predate app/buildInfo.
+func (qb *Client) GetServerInfo() (*ServerInfo, error) {
+	caps, err := qb.Capabilities()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server info: %w", err)
+	}
+
+	info := &ServerInfo{APIVersion: caps.APIVersion.String()}
+
+	info.AppVersion, err = qb.GetAppVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server info: %w", err)
+	}
+
+	if caps.Supports(FeatureBuildInfo) {
+		info.BuildInfo, err = qb.GetBuildInfo()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get server info: %w", err)
+		}
+	}
+
+	info.DefaultSavePath, err = qb.GetDefaultSavePath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server info: %w", err)
+	}
+
+	return info, nil
+}
+
 // ===== ESSENTIAL FEATURES FOR SEEDBOX =====
 
 // GetTorrentTrackers gets tracker information for a torrent
@@ -708,6 +970,66 @@ func (qb *Client) SetGlobalSettings(settings GlobalSettings) error {
 	return nil
 }
 
+// GetPreferences gets all qBittorrent preferences as raw key/value pairs,
+// including those not modelled by GlobalSettings
+func (qb *Client) GetPreferences() (map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("%s/api/v2/app/preferences", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get preferences. Status: %d, Response: %s", resp.StatusCode, string(body))
+	}
+
+	var prefs map[string]interface{}
+	if err := json.Unmarshal(body, &prefs); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// SetPreferences updates only the given qBittorrent preferences
+func (qb *Client) SetPreferences(prefs map[string]interface{}) error {
+	jsonData, err := json.Marshal(prefs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal preferences: %w", err)
+	}
+
+	// The API requires application/x-www-form-urlencoded with a 'json' field
+	data := url.Values{
+		"json": {string(jsonData)},
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v2/app/setPreferences", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodPost, endpoint, []byte(data.Encode()), headers)
+	if err != nil {
+		return fmt.Errorf("failed to set preferences: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to set preferences. Status: %d, Response: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
 // GetCategories gets all categories
 func (qb *Client) GetCategories() (map[string]Category, error) {
 	endpoint := fmt.Sprintf("%s/api/v2/torrents/categories", qb.config.BaseURL)
@@ -788,6 +1110,112 @@ func (qb *Client) DeleteCategory(name string) error {
 	return nil
 }
 
+// EditCategory changes the save path of an existing category
+func (qb *Client) EditCategory(name, savePath string) error {
+	data := url.Values{
+		"category": {name},
+		"savePath": {savePath},
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v2/torrents/editCategory", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodPost, endpoint, []byte(data.Encode()), headers)
+	if err != nil {
+		return fmt.Errorf("failed to edit category: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to edit category. Status: %d, Response: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+// GetTags gets all tags
+func (qb *Client) GetTags() ([]string, error) {
+	endpoint := fmt.Sprintf("%s/api/v2/torrents/tags", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get tags. Status: %d, Response: %s", resp.StatusCode, string(body))
+	}
+
+	var tags []string
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return tags, nil
+}
+
+// CreateTags creates tags that are not attached to any torrent yet
+func (qb *Client) CreateTags(tags []string) error {
+	data := url.Values{
+		"tags": {strings.Join(tags, ",")},
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v2/torrents/createTags", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodPost, endpoint, []byte(data.Encode()), headers)
+	if err != nil {
+		return fmt.Errorf("failed to create tags: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to create tags. Status: %d, Response: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+// DeleteTags deletes tags and removes them from all torrents
+func (qb *Client) DeleteTags(tags []string) error {
+	data := url.Values{
+		"tags": {strings.Join(tags, ",")},
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v2/torrents/deleteTags", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodPost, endpoint, []byte(data.Encode()), headers)
+	if err != nil {
+		return fmt.Errorf("failed to delete tags: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to delete tags. Status: %d, Response: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
 // GetLogs gets system logs
 func (qb *Client) GetLogs(normal bool, info bool, warning bool, critical bool, lastKnownID int) ([]*LogEntry, error) {
 	params := url.Values{}
@@ -1003,44 +1431,120 @@ func (qb *Client) ToggleSpeedLimits() error {
 	return nil
 }
 
-// SetAlternativeRateLimits sets alternative global download and upload speed limits
-func (qb *Client) SetAlternativeRateLimits(downloadLimit, uploadLimit int) error {
-	// Create a map with only the fields to update
-	updates := map[string]interface{}{
-		"alt_dl_limit": downloadLimit,
-		"alt_up_limit": uploadLimit,
+// GetSpeedLimitsMode reports whether the alternative speed limits are active
+func (qb *Client) GetSpeedLimitsMode() (bool, error) {
+	endpoint := fmt.Sprintf("%s/api/v2/transfer/speedLimitsMode", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to get speed limits mode: %w", err)
 	}
+	defer resp.Body.Close()
 
-	jsonData, err := json.Marshal(updates)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to marshal settings: %w", err)
+		return false, fmt.Errorf("error reading response body: %w", err)
 	}
 
-	// The API requires application/x-www-form-urlencoded with a 'json' field
-	data := url.Values{
-		"json": {string(jsonData)},
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to get speed limits mode. Status: %d, Response: %s", resp.StatusCode, string(body))
 	}
 
-	headers := map[string]string{
-		"Content-Type": "application/x-www-form-urlencoded",
+	switch strings.TrimSpace(string(body)) {
+	case "1":
+		return true, nil
+	case "0":
+		return false, nil
 	}
+	return false, fmt.Errorf("unexpected speed limits mode %q", body)
+}
 
-	endpoint := fmt.Sprintf("%s/api/v2/app/setPreferences", qb.config.BaseURL)
+// SetSpeedLimitsMode enables or disables the alternative speed limits. The
+// mode is only toggled when it differs, and is checked again afterwards.
+func (qb *Client) SetSpeedLimitsMode(alternative bool) error {
+	qb.speedModeMu.Lock()
+	defer qb.speedModeMu.Unlock()
 
-	resp, err := qb.doWithRetry(http.MethodPost, endpoint, []byte(data.Encode()), headers)
+	current, err := qb.GetSpeedLimitsMode()
 	if err != nil {
-		return fmt.Errorf("failed to set alternative rate limits: %w", err)
+		return err
+	}
+	if current == alternative {
+		return nil
+	}
+	if err := qb.ToggleSpeedLimits(); err != nil {
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to set alternative rate limits. Status: %d, Response: %s", resp.StatusCode, body)
+	// Another client may have toggled at the same time
+	current, err = qb.GetSpeedLimitsMode()
+	if err != nil {
+		return err
 	}
+	if current != alternative {
+		return fmt.Errorf("failed to set speed limits mode: alternative is %v after toggling", current)
+	}
+	return nil
+}
 
+// SetAlternativeRateLimits sets alternative global download and upload speed
+// limits. Limits that already match are not written again, and the limits
+// in effect afterwards are checked; GetAlternativeRateLimits reads them.
+// Limits are compared as qBittorrent stores them, see NormalizeSpeedLimit.
+func (qb *Client) SetAlternativeRateLimits(downloadLimit, uploadLimit int) error {
+	download, upload, err := qb.GetAlternativeRateLimits()
+	if err != nil {
+		return err
+	}
+	if NormalizeSpeedLimit(download) == NormalizeSpeedLimit(downloadLimit) && NormalizeSpeedLimit(upload) == NormalizeSpeedLimit(uploadLimit) {
+		return nil
+	}
+
+	updates := map[string]interface{}{
+		"alt_dl_limit": downloadLimit,
+		"alt_up_limit": uploadLimit,
+	}
+	if err := qb.SetPreferences(updates); err != nil {
+		return fmt.Errorf("failed to set alternative rate limits: %w", err)
+	}
+
+	download, upload, err = qb.GetAlternativeRateLimits()
+	if err != nil {
+		return err
+	}
+	if NormalizeSpeedLimit(download) != NormalizeSpeedLimit(downloadLimit) || NormalizeSpeedLimit(upload) != NormalizeSpeedLimit(uploadLimit) {
+		return fmt.Errorf("failed to set alternative rate limits: effective limits are %d/%d", download, upload)
+	}
 	return nil
 }
 
+// GetAlternativeRateLimits returns the alternative download and upload speed
+// limits in effect, in bytes/s (0 means unlimited)
+func (qb *Client) GetAlternativeRateLimits() (int, int, error) {
+	prefs, err := qb.GetPreferences()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get alternative rate limits: %w", err)
+	}
+	download, _ := prefs["alt_dl_limit"].(float64)
+	upload, _ := prefs["alt_up_limit"].(float64)
+	return int(download), int(upload), nil
+}
+
+// NormalizeSpeedLimit returns the global or alternative speed limit
+// qBittorrent stores for limit. It keeps these limits in whole KiB/s:
+// negative values become 0 (unlimited), 1 to 1024 become 1024 and larger
+// values are truncated to a multiple of 1024.
+func NormalizeSpeedLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return 0
+	case limit <= 1024:
+		return 1024
+	default:
+		return limit / 1024 * 1024
+	}
+}
+
 // SetTorrentDownloadLimit sets download speed limit for a specific torrent
 func (qb *Client) SetTorrentDownloadLimit(hash string, limit int) error {
 	data := url.Values{
@@ -1179,12 +1683,22 @@ func (qb *Client) GetTorrentUploadLimit(hash string) (int, error) {
 // ratioLimit: -2 means use global limit, -1 means no limit
 // seedingTimeLimit: -2 means use global limit, -1 means no limit (in minutes)
 // inactiveSeedingTimeLimit: -2 means use global limit, -1 means no limit (in minutes)
+// Servers older than qBittorrent 4.6 only accept the global default (-2) for inactiveSeedingTimeLimit
 func (qb *Client) SetTorrentShareLimit(hash string, ratioLimit float64, seedingTimeLimit int, inactiveSeedingTimeLimit int) error {
 	data := url.Values{
-		"hashes":                   {hash},
-		"ratioLimit":               {fmt.Sprintf("%.2f", ratioLimit)},
-		"seedingTimeLimit":         {fmt.Sprintf("%d", seedingTimeLimit)},
-		"inactiveSeedingTimeLimit": {fmt.Sprintf("%d", inactiveSeedingTimeLimit)},
+		"hashes":           {hash},
+		"ratioLimit":       {fmt.Sprintf("%.2f", ratioLimit)},
+		"seedingTimeLimit": {fmt.Sprintf("%d", seedingTimeLimit)},
+	}
+
+	caps, err := qb.Capabilities()
+	if err != nil {
+		return err
+	}
+	if caps.Supports(FeatureInactiveSeedingLimit) {
+		data.Set("inactiveSeedingTimeLimit", fmt.Sprintf("%d", inactiveSeedingTimeLimit))
+	} else if inactiveSeedingTimeLimit != -2 {
+		return qb.requireFeature(FeatureInactiveSeedingLimit)
 	}
 
 	headers := map[string]string{
@@ -1209,6 +1723,68 @@ func (qb *Client) SetTorrentShareLimit(hash string, ratioLimit float64, seedingT
 
 // GetRSSFeeds gets configured RSS feeds
 func (qb *Client) GetRSSFeeds(withData bool) (map[string]RSSFeed, error) {
+	body, err := qb.rssItems(withData)
+	if err != nil {
+		return nil, err
+	}
+
+	var feeds map[string]RSSFeed
+	if err := json.Unmarshal(body, &feeds); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return feeds, nil
+}
+
+// ListRSSItems gets every RSS feed and folder, walking folders recursively.
+// Feeds are keyed by their full item path and folders are returned as paths;
+// path elements are separated by RSSPathSeparator.
+func (qb *Client) ListRSSItems() (map[string]RSSFeed, []string, error) {
+	body, err := qb.rssItems(false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	feeds := make(map[string]RSSFeed)
+	var folders []string
+	var walk func(prefix string, data []byte) error
+	walk = func(prefix string, data []byte) error {
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		for name, raw := range items {
+			var item map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &item); err != nil {
+				return err
+			}
+			path := prefix + name
+			// Feeds have a URL; anything else is a folder of further items
+			if _, ok := item["url"]; ok {
+				var feed RSSFeed
+				if err := json.Unmarshal(raw, &feed); err != nil {
+					return err
+				}
+				feeds[path] = feed
+				continue
+			}
+			folders = append(folders, path)
+			if err := walk(path+RSSPathSeparator, raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk("", body); err != nil {
+		return nil, nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	sort.Strings(folders)
+	return feeds, folders, nil
+}
+
+// rssItems returns the raw RSS item tree.
+func (qb *Client) rssItems(withData bool) ([]byte, error) {
 	params := url.Values{}
 	params.Add("withData", fmt.Sprintf("%v", withData))
 
@@ -1229,12 +1805,33 @@ func (qb *Client) GetRSSFeeds(withData bool) (map[string]RSSFeed, error) {
 		return nil, fmt.Errorf("failed to get RSS feeds. Status: %d, Response: %s", resp.StatusCode, string(body))
 	}
 
-	var feeds map[string]RSSFeed
-	if err := json.Unmarshal(body, &feeds); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+	return body, nil
+}
+
+// AddRSSFolder adds an RSS folder. Its parent folder must exist.
+func (qb *Client) AddRSSFolder(path string) error {
+	data := url.Values{
+		"path": {path},
 	}
 
-	return feeds, nil
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v2/rss/addFolder", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodPost, endpoint, []byte(data.Encode()), headers)
+	if err != nil {
+		return fmt.Errorf("failed to add RSS folder: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to add RSS folder. Status: %d, Response: %s", resp.StatusCode, body)
+	}
+
+	return nil
 }
 
 // AddRSSFeed adds a new RSS feed
@@ -1289,6 +1886,91 @@ func (qb *Client) RemoveRSSFeed(path string) error {
 	return nil
 }
 
+// GetRSSRules gets all RSS auto-downloading rules
+func (qb *Client) GetRSSRules() (map[string]RSSRule, error) {
+	endpoint := fmt.Sprintf("%s/api/v2/rss/rules", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodGet, endpoint, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RSS rules: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get RSS rules. Status: %d, Response: %s", resp.StatusCode, string(body))
+	}
+
+	var rules map[string]RSSRule
+	if err := json.Unmarshal(body, &rules); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return rules, nil
+}
+
+// SetRSSRule creates or replaces an RSS auto-downloading rule
+func (qb *Client) SetRSSRule(name string, rule RSSRule) error {
+	ruleDef, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal RSS rule: %w", err)
+	}
+
+	data := url.Values{
+		"ruleName": {name},
+		"ruleDef":  {string(ruleDef)},
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v2/rss/setRule", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodPost, endpoint, []byte(data.Encode()), headers)
+	if err != nil {
+		return fmt.Errorf("failed to set RSS rule: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to set RSS rule. Status: %d, Response: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+// RemoveRSSRule removes an RSS auto-downloading rule
+func (qb *Client) RemoveRSSRule(name string) error {
+	data := url.Values{
+		"ruleName": {name},
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v2/rss/removeRule", qb.config.BaseURL)
+
+	resp, err := qb.doWithRetry(http.MethodPost, endpoint, []byte(data.Encode()), headers)
+	if err != nil {
+		return fmt.Errorf("failed to remove RSS rule: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to remove RSS rule. Status: %d, Response: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
 // SetTorrentLocation sets the location for torrent files
 func (qb *Client) SetTorrentLocation(hash string, location string) error {
 	data := url.Values{
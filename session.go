@@ -0,0 +1,251 @@
+package qbt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Session is a persisted login session.
+type Session struct {
+	Cookies   []*SessionCookie `json:"cookies"`
+	LoginTime time.Time        `json:"login_time"`
+}
+
+// SessionCookie is the persisted form of a session cookie.
+type SessionCookie struct {
+	Name    string    `json:"name"`
+	Value   string    `json:"value"`
+	Path    string    `json:"path,omitempty"`
+	Domain  string    `json:"domain,omitempty"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// SessionStore persists login sessions so that a restarted process can reuse
+// its SID cookie instead of logging in again. Keys identify an instance and user.
+type SessionStore interface {
+	// Load returns the session stored under key, or nil if there is none.
+	Load(key string) (*Session, error)
+	Save(key string, session *Session) error
+	Delete(key string) error
+}
+
+// MemorySessionStore keeps sessions in memory, e.g. to share them between
+// clients of the same process.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewMemorySessionStore creates an empty in-memory store.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*Session)}
+}
+
+func (s *MemorySessionStore) Load(key string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[key], nil
+}
+
+func (s *MemorySessionStore) Save(key string, session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[key] = session
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, key)
+	return nil
+}
+
+// FileSessionStore keeps sessions in a JSON file readable only by its owner
+// (0600). Several instances can share one file.
+type FileSessionStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// NewFileSessionStore creates a store backed by the file at path.
+func NewFileSessionStore(path string) *FileSessionStore {
+	return &FileSessionStore{Path: path}
+}
+
+func (s *FileSessionStore) Load(key string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return sessions[key], nil
+}
+
+func (s *FileSessionStore) Save(key string, session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.read()
+	if err != nil {
+		return err
+	}
+	sessions[key] = session
+	return s.write(sessions)
+}
+
+func (s *FileSessionStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := sessions[key]; !ok {
+		return nil
+	}
+	delete(sessions, key)
+	return s.write(sessions)
+}
+
+func (s *FileSessionStore) read() (map[string]*Session, error) {
+	sessions := make(map[string]*Session)
+
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return sessions, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to parse session file %s: %w", s.Path, err)
+	}
+	return sessions, nil
+}
+
+// write replaces the file atomically so a crash never leaves it truncated.
+func (s *FileSessionStore) write(sessions map[string]*Session) error {
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sessions: %w", err)
+	}
+
+	dir := filepath.Dir(s.Path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".sessions-*")
+	if err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp already uses 0600; Chmod guards against a permissive umask
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.Path); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	return nil
+}
+
+// sessionKey identifies the instance and user a session belongs to.
+func sessionKey(config Config) string {
+	instance := config.BaseURL
+	if config.socketPath != "" {
+		instance = unixScheme + config.socketPath
+	}
+	return instance + "|" + config.Username
+}
+
+// restoreSession loads a stored session into the cookie jar and cache. The
+// session is not trusted yet: ensureLogin validates it with isCookieValid
+// before the first request.
+func (qb *Client) restoreSession() {
+	store := qb.config.SessionStore
+	if store == nil {
+		return
+	}
+
+	key := sessionKey(qb.config)
+	session, err := store.Load(key)
+	if err != nil {
+		qb.log().Warn("failed to load stored session", LogKeyError, err)
+		return
+	}
+	if session == nil || len(session.Cookies) == 0 || time.Since(session.LoginTime) > CookieExpiryDuration {
+		return
+	}
+
+	baseURL, err := url.Parse(qb.config.BaseURL)
+	if err != nil {
+		return
+	}
+
+	cookies := make([]*http.Cookie, 0, len(session.Cookies))
+	for _, c := range session.Cookies {
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, Expires: c.Expires})
+	}
+	qb.config.jar.SetCookies(baseURL, cookies)
+	qb.updateCookieCache(cookies)
+	qb.setLastLoginTime(session.LoginTime)
+	qb.sessionRestored.Store(true)
+
+	qb.log().Debug("restored stored session", "login_time", session.LoginTime)
+}
+
+// saveSession persists the cookies of a successful login.
+func (qb *Client) saveSession(loginTime time.Time) {
+	store := qb.config.SessionStore
+	if store == nil {
+		return
+	}
+
+	cookies := qb.cookieCache.snapshot()
+	if len(cookies) == 0 {
+		return
+	}
+
+	session := &Session{LoginTime: loginTime}
+	for _, c := range cookies {
+		session.Cookies = append(session.Cookies, &SessionCookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, Expires: c.Expires})
+	}
+	if err := store.Save(sessionKey(qb.config), session); err != nil {
+		qb.log().Warn("failed to save session", LogKeyError, err)
+	}
+}
+
+// deleteSession removes the stored session once it is known to be invalid.
+func (qb *Client) deleteSession() {
+	store := qb.config.SessionStore
+	if store == nil {
+		return
+	}
+	if err := store.Delete(sessionKey(qb.config)); err != nil {
+		qb.log().Warn("failed to delete stored session", LogKeyError, err)
+	}
+}
@@ -0,0 +1,114 @@
+package qbt
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileSessionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qbt", "sessions.json")
+	store := NewFileSessionStore(path)
+
+	session, err := store.Load("missing")
+	if err != nil || session != nil {
+		t.Fatalf("Expected no session and no error, got %v, %v", session, err)
+	}
+
+	saved := &Session{
+		Cookies:   []*SessionCookie{{Name: "SID", Value: "abc", Path: "/"}},
+		LoginTime: time.Now().Truncate(time.Second),
+	}
+	if err := store.Save("http://a|admin", saved); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+	if err := store.Save("http://b|admin", saved); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Session file not written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected 0600 permissions, got %o", perm)
+	}
+
+	loaded, err := NewFileSessionStore(path).Load("http://a|admin")
+	if err != nil || loaded == nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	if loaded.Cookies[0].Value != "abc" || !loaded.LoginTime.Equal(saved.LoginTime) {
+		t.Errorf("Unexpected session: %+v", loaded)
+	}
+
+	if err := store.Delete("http://a|admin"); err != nil {
+		t.Fatalf("Failed to delete session: %v", err)
+	}
+	if session, _ := store.Load("http://a|admin"); session != nil {
+		t.Error("Session should be deleted")
+	}
+	if session, _ := store.Load("http://b|admin"); session == nil {
+		t.Error("Other sessions should be kept")
+	}
+}
+
+func TestSessionReusedAcrossClients(t *testing.T) {
+	var logins atomic.Int32
+	server := newAuthServer(t, nil, &logins)
+	store := NewFileSessionStore(filepath.Join(t.TempDir(), "sessions.json"))
+
+	first := newFakeClient(t, server, Config{SessionStore: store})
+	if _, err := first.GetTransferInfo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A new process with the same store skips the login
+	second := newFakeClient(t, server, Config{SessionStore: store})
+	if _, err := second.GetTransferInfo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if logins.Load() != 1 {
+		t.Errorf("Expected the stored session to be reused, got %d logins", logins.Load())
+	}
+	if second.GetStatus() != StatusConnected {
+		t.Errorf("Expected connected status, got %s", second.GetStatus())
+	}
+}
+
+func TestStaleSessionFallsBackToLogin(t *testing.T) {
+	var logins atomic.Int32
+	server := newAuthServer(t, nil, &logins)
+
+	store := NewMemorySessionStore()
+	config := Config{BaseURL: server.URL, Username: "admin"}
+	store.Save(sessionKey(config), &Session{
+		Cookies:   []*SessionCookie{{Name: "SID", Value: "stale", Path: "/"}},
+		LoginTime: time.Now().Add(-time.Hour),
+	})
+
+	client := newFakeClient(t, server, Config{SessionStore: store})
+	if _, err := client.GetTransferInfo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if logins.Load() != 1 {
+		t.Errorf("Expected a fresh login for a stale session, got %d", logins.Load())
+	}
+
+	session, _ := store.Load(sessionKey(config))
+	if session == nil || session.Cookies[0].Value != "test-session" {
+		t.Errorf("Expected the new session to be stored, got %+v", session)
+	}
+
+	// Closing keeps the stored session for the next client; logging out removes it
+	client.Close()
+	if session, _ := store.Load(sessionKey(config)); session == nil {
+		t.Error("Expected the session to be kept on Close")
+	}
+	client.Logout()
+	if session, _ := store.Load(sessionKey(config)); session != nil {
+		t.Error("Expected the session to be deleted on Logout")
+	}
+}
@@ -0,0 +1,148 @@
+package qbt
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// TLSConfig configures how the client verifies the server and authenticates
+// itself over HTTPS. It applies to every request, including login and the
+// accessibility check.
+type TLSConfig struct {
+	CAFile             string   // PEM bundle of CAs trusted in addition to the system pool
+	CAPEM              []byte   // Inline PEM CA bundle, merged with CAFile
+	CertFile           string   // PEM client certificate for mutual TLS
+	KeyFile            string   // PEM private key matching CertFile
+	CertPEM            []byte   // Inline client certificate, used when CertFile is empty
+	KeyPEM             []byte   // Inline private key, used when KeyFile is empty
+	ServerName         string   // Overrides the name used to verify the server certificate
+	InsecureSkipVerify bool     // Disable certificate verification (lab setups only)
+	PinnedSPKI         []string // Base64 SHA-256 hashes of accepted SubjectPublicKeyInfo; any certificate in the chain may match
+}
+
+// PinMismatchError is returned when no certificate presented by the server
+// matches the configured SPKI pins.
+type PinMismatchError struct {
+	Presented []string // SPKI hashes of the presented chain
+}
+
+func (e *PinMismatchError) Error() string {
+	return fmt.Sprintf("tls: no presented certificate matches the pinned public keys (presented: %s)", strings.Join(e.Presented, ", "))
+}
+
+// SPKIHash returns the pin for cert: the base64 SHA-256 of its SubjectPublicKeyInfo.
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// build converts the configuration into a *tls.Config.
+func (c *TLSConfig) build() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" || len(c.CAPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if c.CAFile != "" {
+			data, err := os.ReadFile(c.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+			}
+		}
+		if len(c.CAPEM) > 0 && !pool.AppendCertsFromPEM(c.CAPEM) {
+			return nil, errors.New("no certificates found in CAPEM")
+		}
+		config.RootCAs = pool
+	}
+
+	cert, err := c.clientCertificate()
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+
+	if len(c.PinnedSPKI) > 0 {
+		pins := make(map[string]bool, len(c.PinnedSPKI))
+		for _, pin := range c.PinnedSPKI {
+			decoded, err := base64.StdEncoding.DecodeString(pin)
+			if err != nil || len(decoded) != sha256.Size {
+				return nil, fmt.Errorf("invalid SPKI pin %q: want base64 SHA-256", pin)
+			}
+			pins[pin] = true
+		}
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyPins(state.PeerCertificates, pins)
+		}
+	}
+
+	return config, nil
+}
+
+// clientCertificate loads the mutual TLS key pair, or returns nil when none is configured.
+func (c *TLSConfig) clientCertificate() (*tls.Certificate, error) {
+	switch {
+	case c.CertFile != "" || c.KeyFile != "":
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("both CertFile and KeyFile are required for a client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		return &cert, nil
+	case len(c.CertPEM) > 0 || len(c.KeyPEM) > 0:
+		cert, err := tls.X509KeyPair(c.CertPEM, c.KeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+		return &cert, nil
+	default:
+		return nil, nil
+	}
+}
+
+// verifyPins accepts the connection when any presented certificate matches a pin.
+func verifyPins(chain []*x509.Certificate, pins map[string]bool) error {
+	presented := make([]string, 0, len(chain))
+	for _, cert := range chain {
+		hash := SPKIHash(cert)
+		if pins[hash] {
+			return nil
+		}
+		presented = append(presented, hash)
+	}
+	return &PinMismatchError{Presented: presented}
+}
+
+// roundTripper returns the transport requests are sent through.
+func (qb *Client) roundTripper() http.RoundTripper {
+	qb.mu.RLock()
+	defer qb.mu.RUnlock()
+	return roundTripperOrDefault(qb.transport)
+}
+
+// roundTripperOrDefault avoids wrapping a nil *http.Transport in a non-nil interface.
+func roundTripperOrDefault(transport *http.Transport) http.RoundTripper {
+	if transport == nil {
+		return nil
+	}
+	return transport
+}
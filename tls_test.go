@@ -0,0 +1,159 @@
+package qbt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newFakeTLSServer is newFakeServer over HTTPS; configure may adjust the server TLS settings.
+func newFakeTLSServer(t *testing.T, mux *http.ServeMux, configure func(*tls.Config)) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(fakeHandler(mux))
+	server.TLS = &tls.Config{}
+	if configure != nil {
+		configure(server.TLS)
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func serverCAPEM(server *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
+
+// newClientCertificate creates a self-signed client certificate and returns it as PEM.
+func newClientCertificate(t *testing.T) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "qbt-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func transferInfoMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/transfer/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"dl_info_speed":1}`))
+	})
+	return mux
+}
+
+func TestTLSCustomCA(t *testing.T) {
+	server := newFakeTLSServer(t, transferInfoMux(), nil)
+
+	untrusted := newFakeClient(t, server, Config{MaxRetries: 1})
+	if _, err := untrusted.GetTransferInfo(); GetErrorCode(err) != ErrorCodeSSLError {
+		t.Errorf("Expected %s without the CA, got %v", ErrorCodeSSLError, err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, serverCAPEM(server), 0o600); err != nil {
+		t.Fatalf("Failed to write CA: %v", err)
+	}
+	trusted := newFakeClient(t, server, Config{TLS: &TLSConfig{CAFile: caFile}})
+	if _, err := trusted.GetTransferInfo(); err != nil {
+		t.Errorf("Expected success with the CA bundle, got %v", err)
+	}
+
+	insecure := newFakeClient(t, server, Config{TLS: &TLSConfig{InsecureSkipVerify: true}})
+	if _, err := insecure.GetTransferInfo(); err != nil {
+		t.Errorf("Expected success with InsecureSkipVerify, got %v", err)
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	certPEM, keyPEM := newClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(certPEM)
+
+	server := newFakeTLSServer(t, transferInfoMux(), func(config *tls.Config) {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = clientCAs
+	})
+
+	anonymous := newFakeClient(t, server, Config{MaxRetries: 1, TLS: &TLSConfig{CAPEM: serverCAPEM(server)}})
+	if _, err := anonymous.GetTransferInfo(); err == nil {
+		t.Error("Expected the handshake to fail without a client certificate")
+	}
+
+	client := newFakeClient(t, server, Config{TLS: &TLSConfig{
+		CAPEM:   serverCAPEM(server),
+		CertPEM: certPEM,
+		KeyPEM:  keyPEM,
+	}})
+	if _, err := client.GetTransferInfo(); err != nil {
+		t.Errorf("Expected success with the client certificate, got %v", err)
+	}
+}
+
+func TestTLSPinning(t *testing.T) {
+	server := newFakeTLSServer(t, transferInfoMux(), nil)
+	pin := SPKIHash(server.Certificate())
+
+	pinned := newFakeClient(t, server, Config{TLS: &TLSConfig{CAPEM: serverCAPEM(server), PinnedSPKI: []string{pin}}})
+	if _, err := pinned.GetTransferInfo(); err != nil {
+		t.Errorf("Expected success with a matching pin, got %v", err)
+	}
+
+	wrongPin := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	mismatched := newFakeClient(t, server, Config{MaxRetries: 1, TLS: &TLSConfig{InsecureSkipVerify: true, PinnedSPKI: []string{wrongPin}}})
+	_, err := mismatched.GetTransferInfo()
+	if GetErrorCode(err) != ErrorCodeCertificatePinMismatch {
+		t.Fatalf("Expected %s, got %v", ErrorCodeCertificatePinMismatch, err)
+	}
+	if !IsPermanentError(err) {
+		t.Error("A pin mismatch should be permanent")
+	}
+}
+
+func TestTLSInvalidConfig(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config *TLSConfig
+	}{
+		{"missing CA file", &TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		{"empty CA bundle", &TLSConfig{CAPEM: []byte("not a certificate")}},
+		{"cert without key", &TLSConfig{CertFile: "client.pem"}},
+		{"malformed pin", &TLSConfig{PinnedSPKI: []string{"not-base64!"}}},
+	}
+
+	for _, tc := range testCases {
+		if _, err := New(Config{BaseURL: "https://localhost", TLS: tc.config}); err == nil {
+			t.Errorf("%s: expected New to fail", tc.name)
+		}
+	}
+}
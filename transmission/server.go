@@ -0,0 +1,227 @@
+/*
+Package transmission serves the Transmission RPC protocol on top of a
+qBittorrent instance, so tools that only speak Transmission can drive
+qBittorrent unchanged.
+
+The server implements the X-Transmission-Session-Id handshake and the
+session-get, session-set, session-stats, torrent-get, torrent-add,
+torrent-start, torrent-start-now, torrent-stop, torrent-remove, torrent-set,
+torrent-set-location, torrent-verify and torrent-reannounce methods:
+
+	server := transmission.New(client, transmission.Config{
+		Username: "rpc",
+		Password: "secret",
+	})
+	http.Handle("/transmission/rpc", server)
+	log.Fatal(http.ListenAndServe(":9091", nil))
+
+Torrent states, speeds (KB/s in Transmission, bytes/s in qBittorrent),
+ratios, labels (qBittorrent tags) and preferences are translated between the
+two models. Transmission's numeric torrent IDs are assigned by the server in
+the order torrents are first seen and are stable for its lifetime; info
+hashes work as IDs too.
+*/
+package transmission
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// SessionIDHeader carries the CSRF token of the Transmission handshake.
+const SessionIDHeader = "X-Transmission-Session-Id"
+
+// RPCVersion is the Transmission RPC version the server reports.
+const RPCVersion = 17
+
+// API is the part of *qbt.Client used by the server.
+type API interface {
+	ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error)
+	ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error)
+	GetTorrentTrackers(hash string) ([]*qbt.TorrentTracker, error)
+	AddTorrentLink(opts qbt.TorrentConfig) error
+	AddTorrentFile(filename string, torrent []byte, opts qbt.TorrentConfig) error
+	StartTorrents(hash string) error
+	StopTorrents(hash string) error
+	ForceStart(hash string) error
+	DeleteTorrents(hash string, deleteFiles bool) error
+	ForceRecheck(hash string) error
+	ForceReannounce(hash string) error
+	SetTorrentLocation(hash string, location string) error
+	SetTorrentDownloadLimit(hash string, limit int) error
+	SetTorrentUploadLimit(hash string, limit int) error
+	SetTorrentShareLimit(hash string, ratioLimit float64, seedingTimeLimit int, inactiveSeedingTimeLimit int) error
+	AddTorrentTags(hash string, tags []string) error
+	DeleteTorrentTags(hash string, tags []string) error
+	SetFilePriority(hash string, fileIDs []int, priority int) error
+	GetPreferences() (map[string]interface{}, error)
+	SetPreferences(prefs map[string]interface{}) error
+	GetAppVersion() (string, error)
+	GetTransferInfo() (*qbt.TransferInfoResponse, error)
+	GetMainData() (*qbt.MainDataResponse, error)
+}
+
+// Config configures a Server.
+type Config struct {
+	Username string       // Basic auth user; empty disables authentication (default: "")
+	Password string       // Basic auth password
+	Logger   *slog.Logger // Receives failed calls (default: discard)
+}
+
+// Server is an http.Handler speaking Transmission RPC.
+type Server struct {
+	api       API
+	config    Config
+	logger    *slog.Logger
+	sessionID string
+
+	mu     sync.Mutex
+	ids    map[string]int // Info hash to Transmission ID
+	nextID int
+}
+
+// New creates a server for the instance behind api.
+func New(api API, config Config) *Server {
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	token := make([]byte, 24)
+	rand.Read(token)
+
+	return &Server{
+		api:       api,
+		config:    config,
+		logger:    logger,
+		sessionID: base64.RawURLEncoding.EncodeToString(token),
+		ids:       make(map[string]int),
+		nextID:    1,
+	}
+}
+
+type request struct {
+	Method    string          `json:"method"`
+	Arguments json.RawMessage `json:"arguments"`
+	Tag       json.RawMessage `json:"tag,omitempty"`
+}
+
+type response struct {
+	Result    string          `json:"result"`
+	Arguments interface{}     `json:"arguments"`
+	Tag       json.RawMessage `json:"tag,omitempty"`
+}
+
+// ServeHTTP handles one RPC call.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.config.Username != "" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(s.config.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(s.config.Password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Transmission"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	// Clients retry with the session ID from the 409 response
+	w.Header().Set(SessionIDHeader, s.sessionID)
+	if r.Header.Get(SessionIDHeader) != s.sessionID {
+		http.Error(w, "Invalid session ID", http.StatusConflict)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	resp := response{Result: "success", Arguments: struct{}{}, Tag: req.Tag}
+	args, err := s.call(req.Method, req.Arguments)
+	if err != nil {
+		s.logger.Warn("transmission rpc call failed", "method", req.Method, qbt.LogKeyError, err)
+		resp.Result = err.Error()
+	} else if args != nil {
+		resp.Arguments = args
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) call(method string, raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = json.RawMessage("{}")
+	}
+
+	switch method {
+	case "session-get":
+		return s.sessionGet()
+	case "session-set":
+		return nil, s.sessionSet(raw)
+	case "session-stats":
+		return s.sessionStats()
+	case "torrent-get":
+		return s.torrentGet(raw)
+	case "torrent-add":
+		return s.torrentAdd(raw)
+	case "torrent-start":
+		return nil, s.each(raw, s.api.StartTorrents)
+	case "torrent-start-now":
+		return nil, s.each(raw, s.api.ForceStart)
+	case "torrent-stop":
+		return nil, s.each(raw, s.api.StopTorrents)
+	case "torrent-verify":
+		return nil, s.each(raw, s.api.ForceRecheck)
+	case "torrent-reannounce":
+		return nil, s.each(raw, s.api.ForceReannounce)
+	case "torrent-remove":
+		return nil, s.torrentRemove(raw)
+	case "torrent-set":
+		return nil, s.torrentSet(raw)
+	case "torrent-set-location":
+		return nil, s.torrentSetLocation(raw)
+	default:
+		return nil, fmt.Errorf("method name not recognized")
+	}
+}
+
+// assignIDs gives torrents seen for the first time the next IDs, oldest
+// first, and returns the IDs of all torrents.
+func (s *Server) assignIDs(torrents []*qbt.TorrentResponse) map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var unseen []*qbt.TorrentResponse
+	for _, t := range torrents {
+		if _, ok := s.ids[t.Hash]; !ok {
+			unseen = append(unseen, t)
+		}
+	}
+	sort.SliceStable(unseen, func(i, j int) bool { return unseen[i].AddedOn < unseen[j].AddedOn })
+	for _, t := range unseen {
+		s.ids[t.Hash] = s.nextID
+		s.nextID++
+	}
+
+	ids := make(map[string]int, len(torrents))
+	for _, t := range torrents {
+		ids[t.Hash] = s.ids[t.Hash]
+	}
+	return ids
+}
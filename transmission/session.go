@@ -0,0 +1,196 @@
+package transmission
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// encryptionModes maps qBittorrent's encryption preference (0 prefer,
+// 1 force, 2 disable) to Transmission's.
+var encryptionModes = []string{"preferred", "required", "tolerated"}
+
+func (s *Server) sessionGet() (interface{}, error) {
+	prefs, err := s.api.GetPreferences()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	version, err := s.api.GetAppVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	p := preferences(prefs)
+	session := map[string]interface{}{
+		"rpc-version":              RPCVersion,
+		"rpc-version-minimum":      14,
+		"version":                  fmt.Sprintf("4.0.0 (qBittorrent %s)", version),
+		"session-id":               s.sessionID,
+		"download-dir":             p.str("save_path"),
+		"incomplete-dir":           p.str("temp_path"),
+		"incomplete-dir-enabled":   p.boolean("temp_path_enabled"),
+		"rename-partial-files":     p.boolean("incomplete_files_ext"),
+		"speed-limit-down":         int(p.number("dl_limit")) / 1024,
+		"speed-limit-down-enabled": p.number("dl_limit") > 0,
+		"speed-limit-up":           int(p.number("up_limit")) / 1024,
+		"speed-limit-up-enabled":   p.number("up_limit") > 0,
+		"alt-speed-down":           int(p.number("alt_dl_limit")) / 1024,
+		"alt-speed-up":             int(p.number("alt_up_limit")) / 1024,
+		"peer-port":                p.number("listen_port"),
+		"peer-limit-global":        p.number("max_connec"),
+		"peer-limit-per-torrent":   p.number("max_connec_per_torrent"),
+		"dht-enabled":              p.boolean("dht"),
+		"pex-enabled":              p.boolean("pex"),
+		"lpd-enabled":              p.boolean("lsd"),
+		"seedRatioLimit":           p.number("max_ratio"),
+		"seedRatioLimited":         p.boolean("max_ratio_enabled"),
+		"download-queue-enabled":   p.boolean("queueing_enabled"),
+		"download-queue-size":      p.number("max_active_downloads"),
+		"seed-queue-enabled":       p.boolean("queueing_enabled"),
+		"seed-queue-size":          p.number("max_active_uploads"),
+		// qBittorrent 5 renamed start_paused_enabled to add_stopped_enabled
+		"start-added-torrents": !p.boolean("add_stopped_enabled") && !p.boolean("start_paused_enabled"),
+		"units": map[string]interface{}{
+			"speed-units":  []string{"kB/s", "MB/s", "GB/s", "TB/s"},
+			"speed-bytes":  1024,
+			"size-units":   []string{"kB", "MB", "GB", "TB"},
+			"size-bytes":   1024,
+			"memory-units": []string{"KiB", "MiB", "GiB", "TiB"},
+			"memory-bytes": 1024,
+		},
+	}
+	if mode := int(p.number("encryption")); mode >= 0 && mode < len(encryptionModes) {
+		session["encryption"] = encryptionModes[mode]
+	}
+	return session, nil
+}
+
+func (s *Server) sessionSet(raw json.RawMessage) error {
+	var args map[string]interface{}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	a := preferences(args)
+
+	prefs := make(map[string]interface{})
+	direct := map[string]string{
+		"download-dir":           "save_path",
+		"incomplete-dir":         "temp_path",
+		"incomplete-dir-enabled": "temp_path_enabled",
+		"rename-partial-files":   "incomplete_files_ext",
+		"peer-port":              "listen_port",
+		"peer-limit-global":      "max_connec",
+		"peer-limit-per-torrent": "max_connec_per_torrent",
+		"dht-enabled":            "dht",
+		"pex-enabled":            "pex",
+		"lpd-enabled":            "lsd",
+		"seedRatioLimit":         "max_ratio",
+		"seedRatioLimited":       "max_ratio_enabled",
+		"download-queue-enabled": "queueing_enabled",
+		"download-queue-size":    "max_active_downloads",
+		"seed-queue-size":        "max_active_uploads",
+	}
+	for key, pref := range direct {
+		if v, ok := args[key]; ok {
+			prefs[pref] = v
+		}
+	}
+
+	for _, limit := range []struct{ value, enabled, pref string }{
+		{"speed-limit-down", "speed-limit-down-enabled", "dl_limit"},
+		{"speed-limit-up", "speed-limit-up-enabled", "up_limit"},
+	} {
+		if enabled, ok := args[limit.enabled].(bool); ok && !enabled {
+			prefs[limit.pref] = 0
+		} else if _, ok := args[limit.value]; ok {
+			prefs[limit.pref] = int(a.number(limit.value)) * 1024
+		}
+	}
+	for key, pref := range map[string]string{"alt-speed-down": "alt_dl_limit", "alt-speed-up": "alt_up_limit"} {
+		if _, ok := args[key]; ok {
+			prefs[pref] = int(a.number(key)) * 1024
+		}
+	}
+
+	if v, ok := args["start-added-torrents"].(bool); ok {
+		prefs["add_stopped_enabled"] = !v
+		prefs["start_paused_enabled"] = !v
+	}
+	if v, ok := args["encryption"].(string); ok {
+		for mode, name := range encryptionModes {
+			if name == v {
+				prefs["encryption"] = mode
+			}
+		}
+	}
+
+	if len(prefs) == 0 {
+		return nil
+	}
+	if err := s.api.SetPreferences(prefs); err != nil {
+		return fmt.Errorf("failed to set preferences: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) sessionStats() (interface{}, error) {
+	info, err := s.api.GetTransferInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer info: %w", err)
+	}
+	torrents, _, err := s.torrents(json.RawMessage("{}"))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.api.GetMainData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get main data: %w", err)
+	}
+
+	active := 0
+	for _, t := range torrents {
+		if Status(t) != StatusStopped {
+			active++
+		}
+	}
+	current := map[string]interface{}{
+		"downloadedBytes": info.DlInfoData,
+		"uploadedBytes":   info.UpInfoData,
+	}
+	cumulative := map[string]interface{}{
+		"downloadedBytes": data.ServerState.AllTimeDownloaded,
+		"uploadedBytes":   data.ServerState.AllTimeUploaded,
+	}
+	return map[string]interface{}{
+		"activeTorrentCount": active,
+		"pausedTorrentCount": len(torrents) - active,
+		"torrentCount":       len(torrents),
+		"downloadSpeed":      info.DlInfoSpeed,
+		"uploadSpeed":        info.UpInfoSpeed,
+		"current-stats":      current,
+		"cumulative-stats":   cumulative,
+	}, nil
+}
+
+// preferences reads JSON-decoded values leniently.
+type preferences map[string]interface{}
+
+func (p preferences) number(key string) float64 {
+	switch v := p[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return 0
+}
+
+func (p preferences) boolean(key string) bool {
+	v, _ := p[key].(bool)
+	return v
+}
+
+func (p preferences) str(key string) string {
+	v, _ := p[key].(string)
+	return v
+}
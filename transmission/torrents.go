@@ -0,0 +1,718 @@
+package transmission
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	qbt "github.com/jfxdev/go-qbt"
+	"github.com/jfxdev/go-qbt/metainfo"
+)
+
+// Torrent status values of Transmission
+const (
+	StatusStopped      = 0
+	StatusCheckWait    = 1
+	StatusCheck        = 2
+	StatusDownloadWait = 3
+	StatusDownload     = 4
+	StatusSeedWait     = 5
+	StatusSeed         = 6
+)
+
+// etaInfinity is the ETA qBittorrent reports when it cannot estimate one.
+const etaInfinity = 8640000
+
+// Status maps a qBittorrent state to a Transmission status.
+func Status(t *qbt.TorrentResponse) int {
+	switch t.State {
+	case "queuedDL":
+		return StatusDownloadWait
+	case "queuedUP":
+		return StatusSeedWait
+	case "checkingDL", "checkingUP", "checkingResumeData":
+		return StatusCheck
+	case "downloading", "stalledDL", "metaDL", "forcedMetaDL", "forcedDL", "allocating":
+		return StatusDownload
+	case "uploading", "stalledUP", "forcedUP":
+		return StatusSeed
+	case "moving":
+		if t.Progress >= 1 {
+			return StatusSeed
+		}
+		return StatusDownload
+	default:
+		// stopped*, paused*, error, missingFiles, unknown
+		return StatusStopped
+	}
+}
+
+// selection is the "ids" argument: absent (all torrents), "recently-active",
+// or a number, hash or list of numbers and hashes.
+type selection struct {
+	all            bool
+	recentlyActive bool
+	ids            map[int]bool
+	hashes         map[string]bool
+}
+
+func parseSelection(raw json.RawMessage) (*selection, error) {
+	var args struct {
+		IDs json.RawMessage `json:"ids"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	sel := &selection{ids: make(map[int]bool), hashes: make(map[string]bool)}
+	if len(args.IDs) == 0 || string(args.IDs) == "null" {
+		sel.all = true
+		return sel, nil
+	}
+
+	var values []interface{}
+	var single interface{}
+	if err := json.Unmarshal(args.IDs, &values); err != nil {
+		if err := json.Unmarshal(args.IDs, &single); err != nil {
+			return nil, fmt.Errorf("invalid ids: %w", err)
+		}
+		if single == "recently-active" {
+			sel.recentlyActive = true
+			return sel, nil
+		}
+		values = []interface{}{single}
+	}
+
+	for _, v := range values {
+		switch v := v.(type) {
+		case float64:
+			sel.ids[int(v)] = true
+		case string:
+			sel.hashes[strings.ToLower(v)] = true
+		default:
+			return nil, fmt.Errorf("invalid id %v", v)
+		}
+	}
+	return sel, nil
+}
+
+// torrents lists the selected torrents with their Transmission IDs.
+func (s *Server) torrents(raw json.RawMessage) ([]*qbt.TorrentResponse, map[string]int, error) {
+	sel, err := parseSelection(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := qbt.ListOptions{}
+	if sel.recentlyActive {
+		opts.Filter = "active"
+	}
+	all, err := s.api.ListTorrents(opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list torrents: %w", err)
+	}
+	ids := s.assignIDs(all)
+	if sel.all || sel.recentlyActive {
+		return all, ids, nil
+	}
+
+	var selected []*qbt.TorrentResponse
+	for _, t := range all {
+		if sel.ids[ids[t.Hash]] || sel.hashes[strings.ToLower(t.Hash)] {
+			selected = append(selected, t)
+		}
+	}
+	return selected, ids, nil
+}
+
+// each calls fn once with the hashes of all selected torrents.
+func (s *Server) each(raw json.RawMessage, fn func(hashes string) error) error {
+	torrents, _, err := s.torrents(raw)
+	if err != nil || len(torrents) == 0 {
+		return err
+	}
+	return fn(joinHashes(torrents))
+}
+
+func joinHashes(torrents []*qbt.TorrentResponse) string {
+	hashes := make([]string, len(torrents))
+	for i, t := range torrents {
+		hashes[i] = t.Hash
+	}
+	return strings.Join(hashes, "|")
+}
+
+func (s *Server) torrentGet(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Fields []string `json:"fields"`
+		Format string   `json:"format"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if len(args.Fields) == 0 {
+		return nil, errors.New("no fields specified")
+	}
+
+	torrents, ids, err := s.torrents(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, 0, len(torrents)+1)
+	if args.Format == "table" {
+		header := make([]interface{}, len(args.Fields))
+		for i, f := range args.Fields {
+			header[i] = f
+		}
+		result = append(result, header)
+	}
+
+	for _, t := range torrents {
+		d := &details{api: s.api, torrent: t}
+		if args.Format == "table" {
+			row := make([]interface{}, len(args.Fields))
+			for i, f := range args.Fields {
+				if row[i], err = d.field(f, ids[t.Hash]); err != nil {
+					return nil, err
+				}
+			}
+			result = append(result, row)
+			continue
+		}
+
+		object := make(map[string]interface{}, len(args.Fields))
+		for _, f := range args.Fields {
+			v, err := d.field(f, ids[t.Hash])
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				object[f] = v
+			}
+		}
+		result = append(result, object)
+	}
+
+	response := map[string]interface{}{"torrents": result}
+	if sel, _ := parseSelection(raw); sel.recentlyActive {
+		// Removed torrents are not tracked
+		response["removed"] = []int{}
+	}
+	return response, nil
+}
+
+// details computes torrent-get fields, fetching files and trackers only when
+// a field needs them.
+type details struct {
+	api      API
+	torrent  *qbt.TorrentResponse
+	files    []*qbt.TorrentFile
+	trackers []*qbt.TorrentTracker
+}
+
+func (d *details) loadFiles() ([]*qbt.TorrentFile, error) {
+	if d.files == nil {
+		files, err := d.api.ListTorrentFiles(d.torrent.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files of %s: %w", d.torrent.Hash, err)
+		}
+		d.files = files
+	}
+	return d.files, nil
+}
+
+func (d *details) loadTrackers() ([]*qbt.TorrentTracker, error) {
+	if d.trackers == nil {
+		trackers, err := d.api.GetTorrentTrackers(d.torrent.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get trackers of %s: %w", d.torrent.Hash, err)
+		}
+		// Skip the DHT, PeX and LSD pseudo-trackers
+		d.trackers = []*qbt.TorrentTracker{}
+		for _, tr := range trackers {
+			if !strings.HasPrefix(tr.URL, "** [") {
+				d.trackers = append(d.trackers, tr)
+			}
+		}
+	}
+	return d.trackers, nil
+}
+
+// field returns the value of a Transmission torrent field, or nil for fields
+// that have no qBittorrent equivalent.
+func (d *details) field(name string, id int) (interface{}, error) {
+	t := d.torrent
+	stopped := Status(t) == StatusStopped
+
+	switch name {
+	case "id":
+		return id, nil
+	case "hashString":
+		return t.Hash, nil
+	case "name":
+		return t.Name, nil
+	case "status":
+		return Status(t), nil
+	case "error":
+		if t.State == "error" || t.State == "missingFiles" {
+			return 3, nil // Local error
+		}
+		return 0, nil
+	case "errorString":
+		switch t.State {
+		case "error":
+			return "qBittorrent reported an error", nil
+		case "missingFiles":
+			return "No data found! Ensure your drives are connected or use \"Set Location\".", nil
+		}
+		return "", nil
+	case "totalSize", "sizeWhenDone":
+		return t.Size, nil
+	case "leftUntilDone":
+		return t.AmountLeft, nil
+	case "haveValid":
+		return int64(t.Size) - t.AmountLeft, nil
+	case "percentDone":
+		return t.Progress, nil
+	case "metadataPercentComplete":
+		if t.State == "metaDL" || t.State == "forcedMetaDL" {
+			return 0, nil
+		}
+		return 1, nil
+	case "isFinished":
+		return t.Progress >= 1 && stopped, nil
+	case "isStalled":
+		return t.State == "stalledDL" || t.State == "stalledUP", nil
+	case "rateDownload":
+		return t.Dlspeed, nil
+	case "rateUpload":
+		return t.Upspeed, nil
+	case "downloadedEver":
+		return t.Downloaded, nil
+	case "uploadedEver":
+		return t.Uploaded, nil
+	case "uploadRatio":
+		return t.Ratio, nil
+	case "eta":
+		if t.Eta >= etaInfinity || t.Eta < 0 {
+			return -1, nil
+		}
+		return t.Eta, nil
+	case "addedDate":
+		return t.AddedOn, nil
+	case "doneDate":
+		return max(t.CompletionOn, 0), nil
+	case "activityDate":
+		return t.LastActivity, nil
+	case "secondsSeeding":
+		return t.SeedingTime, nil
+	case "downloadDir":
+		return t.SavePath, nil
+	case "labels":
+		return splitTags(t.Tags), nil
+	case "queuePosition":
+		return max(t.Priority-1, 0), nil
+	case "magnetLink":
+		return t.MagnetURI, nil
+	case "peersConnected":
+		return t.NumSeeds + t.NumLeechs, nil
+	case "peersSendingToUs":
+		return t.NumSeeds, nil
+	case "peersGettingFromUs":
+		return t.NumLeechs, nil
+	case "downloadLimit":
+		return max(t.DlLimit, 0) / 1024, nil
+	case "downloadLimited":
+		return t.DlLimit > 0, nil
+	case "uploadLimit":
+		return max(t.UpLimit, 0) / 1024, nil
+	case "uploadLimited":
+		return t.UpLimit > 0, nil
+	case "seedRatioLimit":
+		return max(t.RatioLimit, 0), nil
+	case "seedRatioMode":
+		switch {
+		case t.RatioLimit == -1:
+			return 2, nil // Unlimited
+		case t.RatioLimit >= 0:
+			return 1, nil // Per torrent
+		}
+		return 0, nil // Global
+	case "files", "fileStats", "priorities", "wanted":
+		files, err := d.loadFiles()
+		if err != nil {
+			return nil, err
+		}
+		return fileField(name, files), nil
+	case "trackers", "trackerStats":
+		trackers, err := d.loadTrackers()
+		if err != nil {
+			return nil, err
+		}
+		return trackerField(name, trackers), nil
+	default:
+		return nil, nil
+	}
+}
+
+func fileField(name string, files []*qbt.TorrentFile) interface{} {
+	values := make([]interface{}, len(files))
+	for i, f := range files {
+		completed := int64(float64(f.Size) * f.Progress)
+		switch name {
+		case "files":
+			values[i] = map[string]interface{}{"name": f.Name, "length": f.Size, "bytesCompleted": completed}
+		case "fileStats":
+			values[i] = map[string]interface{}{"bytesCompleted": completed, "wanted": f.Priority != 0, "priority": filePriority(f.Priority)}
+		case "priorities":
+			values[i] = filePriority(f.Priority)
+		case "wanted":
+			values[i] = f.Priority != 0
+		}
+	}
+	return values
+}
+
+// filePriority maps qBittorrent file priorities (0 skip, 1 normal, 6 high,
+// 7 maximum) to Transmission priorities (-1 low, 0 normal, 1 high).
+func filePriority(priority int) int {
+	if priority >= 6 {
+		return 1
+	}
+	return 0
+}
+
+func trackerField(name string, trackers []*qbt.TorrentTracker) interface{} {
+	values := make([]interface{}, len(trackers))
+	for i, tr := range trackers {
+		tracker := map[string]interface{}{"id": i, "announce": tr.URL, "tier": tr.Tier}
+		if name == "trackerStats" {
+			tracker["host"] = hostOf(tr.URL)
+			tracker["lastAnnounceResult"] = tr.Msg
+			tracker["lastAnnounceSucceeded"] = tr.Status == 2
+			tracker["seederCount"] = tr.NumSeeds
+			tracker["leecherCount"] = tr.NumLeeches
+			tracker["downloadCount"] = tr.NumDownloaded
+		}
+		values[i] = tracker
+	}
+	return values
+}
+
+func hostOf(tracker string) string {
+	if i := strings.Index(tracker, "://"); i >= 0 {
+		tracker = tracker[i+3:]
+	}
+	if i := strings.Index(tracker, "/"); i >= 0 {
+		tracker = tracker[:i]
+	}
+	return tracker
+}
+
+func splitTags(tags string) []string {
+	labels := []string{}
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			labels = append(labels, tag)
+		}
+	}
+	return labels
+}
+
+func (s *Server) torrentAdd(raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		Filename    string   `json:"filename"`
+		Metainfo    string   `json:"metainfo"`
+		DownloadDir string   `json:"download-dir"`
+		Paused      bool     `json:"paused"`
+		Labels      []string `json:"labels"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	config := qbt.TorrentConfig{Directory: args.DownloadDir, Paused: args.Paused, Tags: args.Labels}
+
+	var hash, name string
+	var add func() error
+	switch {
+	case args.Metainfo != "":
+		data, err := base64.StdEncoding.DecodeString(args.Metainfo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metainfo: %w", err)
+		}
+		info, err := metainfo.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		hash, name = info.InfoHash, info.Name
+		add = func() error { return s.api.AddTorrentFile(info.Name+".torrent", data, config) }
+	case strings.HasPrefix(args.Filename, "magnet:"):
+		magnet, err := qbt.ParseMagnetLink(args.Filename)
+		if err != nil {
+			return nil, err
+		}
+		hash, name = magnetHash(magnet.Hash), magnet.DisplayName
+		config.MagnetURI = args.Filename
+		add = func() error { return s.api.AddTorrentLink(config) }
+	case strings.HasPrefix(args.Filename, "http://"), strings.HasPrefix(args.Filename, "https://"):
+		// The info hash is unknown until qBittorrent has fetched the file
+		config.MagnetURI = args.Filename
+		if err := s.api.AddTorrentLink(config); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"torrent-added": map[string]interface{}{"name": args.Filename}}, nil
+	case args.Filename != "":
+		return nil, errors.New("filename must be a magnet link or an HTTP(S) URL")
+	default:
+		return nil, errors.New("no filename or metainfo specified")
+	}
+
+	if hash != "" {
+		existing, err := s.api.ListTorrents(qbt.ListOptions{Hashes: []string{hash}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list torrents: %w", err)
+		}
+		if len(existing) > 0 {
+			ids := s.assignIDs(existing)
+			t := existing[0]
+			return map[string]interface{}{"torrent-duplicate": map[string]interface{}{"id": ids[t.Hash], "name": t.Name, "hashString": t.Hash}}, nil
+		}
+	}
+
+	if err := add(); err != nil {
+		return nil, err
+	}
+
+	added := map[string]interface{}{"name": name}
+	if hash != "" {
+		// Reserve the ID now; the torrent may not be listed yet
+		ids := s.assignIDs([]*qbt.TorrentResponse{{Hash: hash}})
+		added["id"] = ids[hash]
+		added["hashString"] = hash
+	}
+	return map[string]interface{}{"torrent-added": added}, nil
+}
+
+// magnetHash returns a btih hash as lowercase hex, decoding base32 hashes.
+func magnetHash(hash string) string {
+	if len(hash) == 32 {
+		if decoded, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash)); err == nil {
+			return hex.EncodeToString(decoded)
+		}
+	}
+	return strings.ToLower(hash)
+}
+
+func (s *Server) torrentRemove(raw json.RawMessage) error {
+	var args struct {
+		DeleteLocalData bool `json:"delete-local-data"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return s.each(raw, func(hashes string) error { return s.api.DeleteTorrents(hashes, args.DeleteLocalData) })
+}
+
+func (s *Server) torrentSetLocation(raw json.RawMessage) error {
+	var args struct {
+		Location string `json:"location"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.Location == "" {
+		return errors.New("no location specified")
+	}
+	// qBittorrent always moves the data; "move": false is not supported
+	return s.each(raw, func(hashes string) error { return s.api.SetTorrentLocation(hashes, args.Location) })
+}
+
+type torrentSetArgs struct {
+	DownloadLimit   *int      `json:"downloadLimit"` // KB/s
+	DownloadLimited *bool     `json:"downloadLimited"`
+	UploadLimit     *int      `json:"uploadLimit"` // KB/s
+	UploadLimited   *bool     `json:"uploadLimited"`
+	SeedRatioLimit  *float64  `json:"seedRatioLimit"`
+	SeedRatioMode   *int      `json:"seedRatioMode"`
+	Labels          *[]string `json:"labels"`
+	Location        string    `json:"location"`
+	FilesWanted     []int     `json:"files-wanted"`
+	FilesUnwanted   []int     `json:"files-unwanted"`
+	PriorityHigh    []int     `json:"priority-high"`
+	PriorityNormal  []int     `json:"priority-normal"`
+	PriorityLow     []int     `json:"priority-low"`
+}
+
+func (s *Server) torrentSet(raw json.RawMessage) error {
+	var args torrentSetArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	torrents, _, err := s.torrents(raw)
+	if err != nil || len(torrents) == 0 {
+		return err
+	}
+	hashes := joinHashes(torrents)
+
+	if limit, ok := speedLimit(args.DownloadLimit, args.DownloadLimited); ok {
+		if err := s.api.SetTorrentDownloadLimit(hashes, limit); err != nil {
+			return err
+		}
+	}
+	if limit, ok := speedLimit(args.UploadLimit, args.UploadLimited); ok {
+		if err := s.api.SetTorrentUploadLimit(hashes, limit); err != nil {
+			return err
+		}
+	}
+	if args.Location != "" {
+		if err := s.api.SetTorrentLocation(hashes, args.Location); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range torrents {
+		if ratio, ok := ratioLimit(t, args.SeedRatioLimit, args.SeedRatioMode); ok {
+			// Keep the seeding time limits; Transmission has no equivalent
+			if err := s.api.SetTorrentShareLimit(t.Hash, ratio, t.SeedingTimeLimit, t.InactiveSeedingTimeLimit); err != nil {
+				return err
+			}
+		}
+		if args.Labels != nil {
+			if err := s.setLabels(t, *args.Labels); err != nil {
+				return err
+			}
+		}
+
+		if err := s.setFiles(t, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFiles applies files-wanted, files-unwanted and the priority lists.
+// qBittorrent folds both into one priority, 0 meaning unwanted, while
+// Transmission keeps them apart: wanting a file keeps its priority, and a
+// priority does not make an unwanted file wanted.
+func (s *Server) setFiles(t *qbt.TorrentResponse, args torrentSetArgs) error {
+	if len(args.FilesWanted)+len(args.FilesUnwanted)+len(args.PriorityHigh)+len(args.PriorityNormal)+len(args.PriorityLow) == 0 {
+		return nil
+	}
+	files, err := s.api.ListTorrentFiles(t.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	wanted := make(map[int]bool, len(files))
+	for i, f := range files {
+		wanted[i] = f.Priority > 0
+	}
+
+	// Only files that were unwanted get a priority back
+	var rewanted []int
+	for _, id := range args.FilesWanted {
+		if !wanted[id] {
+			rewanted = append(rewanted, id)
+		}
+		wanted[id] = true
+	}
+	onlyWanted := func(ids []int) []int {
+		var result []int
+		for _, id := range ids {
+			if wanted[id] {
+				result = append(result, id)
+			}
+		}
+		return result
+	}
+
+	// Priorities after wanted so they are kept, unwanted last so it wins
+	for _, change := range []struct {
+		files    []int
+		priority int
+	}{
+		{rewanted, 1},
+		{onlyWanted(args.PriorityNormal), 1},
+		{onlyWanted(args.PriorityLow), 1},
+		{onlyWanted(args.PriorityHigh), 6},
+		{args.FilesUnwanted, 0},
+	} {
+		if len(change.files) == 0 {
+			continue
+		}
+		if err := s.api.SetFilePriority(t.Hash, change.files, change.priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// speedLimit converts a Transmission limit in KB/s to bytes/s. A limit turned
+// off is 0 (unlimited); turning a limit on without a value changes nothing.
+func speedLimit(limit *int, limited *bool) (int, bool) {
+	if limited != nil && !*limited {
+		return 0, true
+	}
+	if limit == nil {
+		return 0, false
+	}
+	return *limit * 1024, true
+}
+
+// ratioLimit converts seedRatioMode (0 global, 1 per torrent, 2 unlimited)
+// and seedRatioLimit to a qBittorrent ratio limit (-2 global, -1 unlimited).
+func ratioLimit(t *qbt.TorrentResponse, limit *float64, mode *int) (float64, bool) {
+	if mode == nil {
+		// A new limit only applies if the torrent already uses its own
+		if limit != nil && t.RatioLimit >= 0 {
+			return *limit, true
+		}
+		return 0, false
+	}
+	switch *mode {
+	case 0:
+		return -2, true
+	case 2:
+		return -1, true
+	default:
+		if limit != nil {
+			return *limit, true
+		}
+		if t.RatioLimit >= 0 {
+			return t.RatioLimit, true
+		}
+		return 0, false
+	}
+}
+
+// setLabels replaces the tags of t with labels.
+func (s *Server) setLabels(t *qbt.TorrentResponse, labels []string) error {
+	current := splitTags(t.Tags)
+	var remove, add []string
+	for _, tag := range current {
+		if !slices.Contains(labels, tag) {
+			remove = append(remove, tag)
+		}
+	}
+	for _, label := range labels {
+		if !slices.Contains(current, label) {
+			add = append(add, label)
+		}
+	}
+
+	if len(remove) > 0 {
+		if err := s.api.DeleteTorrentTags(t.Hash, remove); err != nil {
+			return err
+		}
+	}
+	if len(add) > 0 {
+		return s.api.AddTorrentTags(t.Hash, add)
+	}
+	return nil
+}
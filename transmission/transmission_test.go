@@ -0,0 +1,359 @@
+package transmission
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	qbt "github.com/jfxdev/go-qbt"
+)
+
+// fakeAPI records the calls made by the server.
+type fakeAPI struct {
+	torrents []*qbt.TorrentResponse
+	prefs    map[string]interface{}
+	calls    []string
+}
+
+func (f *fakeAPI) record(format string, args ...interface{}) error {
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+	return nil
+}
+
+func (f *fakeAPI) ListTorrents(opts qbt.ListOptions) ([]*qbt.TorrentResponse, error) {
+	if len(opts.Hashes) == 0 {
+		return f.torrents, nil
+	}
+	var result []*qbt.TorrentResponse
+	for _, t := range f.torrents {
+		for _, h := range opts.Hashes {
+			if t.Hash == h {
+				result = append(result, t)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeAPI) ListTorrentFiles(hash string) ([]*qbt.TorrentFile, error) {
+	return []*qbt.TorrentFile{{Name: "a.mkv", Size: 100, Progress: 0.5, Priority: 6}, {Name: "b.nfo", Size: 10, Priority: 0}}, nil
+}
+
+func (f *fakeAPI) GetTorrentTrackers(hash string) ([]*qbt.TorrentTracker, error) {
+	return []*qbt.TorrentTracker{
+		{URL: "** [DHT] **"},
+		{URL: "https://tracker.example.org/announce", Status: 2, NumSeeds: 4, Msg: "ok"},
+	}, nil
+}
+
+func (f *fakeAPI) AddTorrentLink(opts qbt.TorrentConfig) error {
+	return f.record("add-link %s dir=%s paused=%v tags=%v", opts.MagnetURI, opts.Directory, opts.Paused, opts.Tags)
+}
+
+func (f *fakeAPI) AddTorrentFile(filename string, torrent []byte, opts qbt.TorrentConfig) error {
+	return f.record("add-file %s dir=%s", filename, opts.Directory)
+}
+
+func (f *fakeAPI) StartTorrents(hash string) error { return f.record("start %s", hash) }
+func (f *fakeAPI) StopTorrents(hash string) error  { return f.record("stop %s", hash) }
+func (f *fakeAPI) ForceStart(hash string) error    { return f.record("force-start %s", hash) }
+func (f *fakeAPI) ForceRecheck(hash string) error  { return f.record("recheck %s", hash) }
+func (f *fakeAPI) ForceReannounce(hash string) error {
+	return f.record("reannounce %s", hash)
+}
+
+func (f *fakeAPI) DeleteTorrents(hash string, deleteFiles bool) error {
+	return f.record("delete %s files=%v", hash, deleteFiles)
+}
+
+func (f *fakeAPI) SetTorrentLocation(hash string, location string) error {
+	return f.record("location %s %s", hash, location)
+}
+
+func (f *fakeAPI) SetTorrentDownloadLimit(hash string, limit int) error {
+	return f.record("dl-limit %s %d", hash, limit)
+}
+
+func (f *fakeAPI) SetTorrentUploadLimit(hash string, limit int) error {
+	return f.record("up-limit %s %d", hash, limit)
+}
+
+func (f *fakeAPI) SetTorrentShareLimit(hash string, ratioLimit float64, seedingTimeLimit int, inactiveSeedingTimeLimit int) error {
+	return f.record("share-limit %s %v %d %d", hash, ratioLimit, seedingTimeLimit, inactiveSeedingTimeLimit)
+}
+
+func (f *fakeAPI) AddTorrentTags(hash string, tags []string) error {
+	return f.record("tag %s %v", hash, tags)
+}
+
+func (f *fakeAPI) DeleteTorrentTags(hash string, tags []string) error {
+	return f.record("untag %s %v", hash, tags)
+}
+
+func (f *fakeAPI) SetFilePriority(hash string, fileIDs []int, priority int) error {
+	return f.record("file-prio %s %v %d", hash, fileIDs, priority)
+}
+
+func (f *fakeAPI) GetPreferences() (map[string]interface{}, error) { return f.prefs, nil }
+
+func (f *fakeAPI) SetPreferences(prefs map[string]interface{}) error {
+	data, _ := json.Marshal(prefs)
+	return f.record("prefs %s", data)
+}
+
+func (f *fakeAPI) GetAppVersion() (string, error) { return "v5.0.0", nil }
+
+func (f *fakeAPI) GetTransferInfo() (*qbt.TransferInfoResponse, error) {
+	return &qbt.TransferInfoResponse{DlInfoSpeed: 2048, DlInfoData: 1 << 20}, nil
+}
+
+func (f *fakeAPI) GetMainData() (*qbt.MainDataResponse, error) {
+	return &qbt.MainDataResponse{ServerState: qbt.MainDataServerStateResponse{AllTimeDownloaded: 1 << 30, AllTimeUploaded: 2 << 30}}, nil
+}
+
+func newAPI() *fakeAPI {
+	return &fakeAPI{torrents: []*qbt.TorrentResponse{
+		{Hash: "bbbb", Name: "Second", AddedOn: 200, State: "stoppedUP", Progress: 1, Size: 110, Tags: "tv, hd", RatioLimit: -2, SeedingTimeLimit: 60, InactiveSeedingTimeLimit: -2},
+		{Hash: "aaaa", Name: "First", AddedOn: 100, State: "stalledDL", Progress: 0.25, Size: 400, AmountLeft: 300, DlLimit: 2048, Eta: 8640000, RatioLimit: 1.5},
+	}}
+}
+
+// rpc performs the handshake and one call, returning the decoded response.
+func rpc(t *testing.T, server *httptest.Server, method string, args interface{}) map[string]interface{} {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"method": method, "arguments": args, "tag": 7})
+
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected 409 without a session ID, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(body))
+	req.Header.Set(SessionIDHeader, resp.Header.Get(SessionIDHeader))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if result["tag"] != float64(7) {
+		t.Errorf("Expected the tag to be echoed, got %v", result["tag"])
+	}
+	return result
+}
+
+func TestAuthentication(t *testing.T) {
+	server := httptest.NewServer(New(newAPI(), Config{Username: "rpc", Password: "secret"}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get(SessionIDHeader) != "" {
+		t.Errorf("Expected 401 before the session ID is revealed, got %d", resp.StatusCode)
+	}
+}
+
+func TestTorrentGet(t *testing.T) {
+	server := httptest.NewServer(New(newAPI(), Config{}))
+	defer server.Close()
+
+	result := rpc(t, server, "torrent-get", map[string]interface{}{
+		"fields": []string{"id", "hashString", "status", "percentDone", "eta", "labels", "downloadLimit", "downloadLimited", "seedRatioMode", "fileStats", "trackerStats", "unknownField"},
+	})
+	if result["result"] != "success" {
+		t.Fatalf("Unexpected result: %v", result)
+	}
+	torrents := result["arguments"].(map[string]interface{})["torrents"].([]interface{})
+	if len(torrents) != 2 {
+		t.Fatalf("Expected 2 torrents, got %v", torrents)
+	}
+
+	// IDs follow the order torrents were added
+	first := torrents[1].(map[string]interface{})
+	second := torrents[0].(map[string]interface{})
+	if first["id"] != float64(1) || second["id"] != float64(2) {
+		t.Errorf("Expected IDs by age, got %v and %v", first["id"], second["id"])
+	}
+	if first["status"] != float64(StatusDownload) || second["status"] != float64(StatusStopped) {
+		t.Errorf("Unexpected statuses %v %v", first["status"], second["status"])
+	}
+	if first["eta"] != float64(-1) || first["downloadLimit"] != float64(2) || first["downloadLimited"] != true || first["seedRatioMode"] != float64(1) {
+		t.Errorf("Unexpected fields %v", first)
+	}
+	if fmt.Sprint(second["labels"]) != "[tv hd]" || second["seedRatioMode"] != float64(0) {
+		t.Errorf("Unexpected fields %v", second)
+	}
+	if _, ok := first["unknownField"]; ok {
+		t.Error("Unknown fields should be omitted")
+	}
+
+	stats := first["fileStats"].([]interface{})
+	if fmt.Sprint(stats[0]) != "map[bytesCompleted:50 priority:1 wanted:true]" || stats[1].(map[string]interface{})["wanted"] != false {
+		t.Errorf("Unexpected file stats %v", stats)
+	}
+	trackers := first["trackerStats"].([]interface{})
+	if len(trackers) != 1 || trackers[0].(map[string]interface{})["host"] != "tracker.example.org" {
+		t.Errorf("Unexpected tracker stats %v", trackers)
+	}
+
+	// Select by ID and hash; table format
+	result = rpc(t, server, "torrent-get", map[string]interface{}{"ids": []interface{}{2, "AAAA"}, "fields": []string{"id", "name"}, "format": "table"})
+	table := result["arguments"].(map[string]interface{})["torrents"]
+	if fmt.Sprint(table) != "[[id name] [2 Second] [1 First]]" {
+		t.Errorf("Unexpected table %v", table)
+	}
+}
+
+func TestTorrentActions(t *testing.T) {
+	api := newAPI()
+	server := httptest.NewServer(New(api, Config{}))
+	defer server.Close()
+
+	rpc(t, server, "torrent-get", map[string]interface{}{"fields": []string{"id"}}) // Assign IDs
+	rpc(t, server, "torrent-stop", map[string]interface{}{"ids": []int{1, 2}})
+	rpc(t, server, "torrent-start-now", map[string]interface{}{"ids": 1})
+	rpc(t, server, "torrent-remove", map[string]interface{}{"ids": []string{"bbbb"}, "delete-local-data": true})
+	rpc(t, server, "torrent-set", map[string]interface{}{
+		"ids":             []int{2},
+		"downloadLimit":   100,
+		"uploadLimited":   false,
+		"seedRatioLimit":  2.0,
+		"seedRatioMode":   1,
+		"labels":          []string{"tv", "done"},
+		"files-unwanted":  []int{1},
+		"priority-high":   []int{0},
+		"unknownArgument": true,
+	})
+
+	expected := []string{
+		"stop bbbb|aaaa",
+		"force-start aaaa",
+		"delete bbbb files=true",
+		"dl-limit bbbb 102400",
+		"up-limit bbbb 0",
+		"share-limit bbbb 2 60 -2",
+		"untag bbbb [hd]",
+		"tag bbbb [done]",
+		"file-prio bbbb [0] 6",
+		"file-prio bbbb [1] 0",
+	}
+	if strings.Join(api.calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(api.calls, "\n"))
+	}
+
+	result := rpc(t, server, "torrent-frobnicate", nil)
+	if result["result"] == "success" {
+		t.Error("Expected an error for an unknown method")
+	}
+}
+
+func TestTorrentSetFiles(t *testing.T) {
+	api := newAPI()
+	server := httptest.NewServer(New(api, Config{}))
+	defer server.Close()
+
+	// a.mkv is wanted at high priority, b.nfo is unwanted
+	rpc(t, server, "torrent-set", map[string]interface{}{"ids": []string{"bbbb"}, "files-wanted": []int{0, 1}})
+	rpc(t, server, "torrent-set", map[string]interface{}{"ids": []string{"bbbb"}, "priority-normal": []int{1}})
+	rpc(t, server, "torrent-set", map[string]interface{}{"ids": []string{"bbbb"}, "files-wanted": []int{1}, "priority-high": []int{1}})
+
+	expected := []string{
+		"file-prio bbbb [1] 1",
+		"file-prio bbbb [1] 1",
+		"file-prio bbbb [1] 6",
+	}
+	if strings.Join(api.calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(api.calls, "\n"))
+	}
+}
+
+func TestTorrentAdd(t *testing.T) {
+	api := newAPI()
+	server := httptest.NewServer(New(api, Config{}))
+	defer server.Close()
+
+	// Magnet with a base32 hash of an existing torrent
+	existing := "magnet:?xt=urn:btih:VKVKVKVKVKVKVKVKVKVKVKVKVKVKVKVK&dn=First"
+	api.torrents[1].Hash = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	result := rpc(t, server, "torrent-add", map[string]interface{}{"filename": existing})
+	if _, ok := result["arguments"].(map[string]interface{})["torrent-duplicate"]; !ok {
+		t.Errorf("Expected a duplicate, got %v", result)
+	}
+
+	pieces := sha1.Sum([]byte("hello"))
+	info := fmt.Sprintf("d6:lengthi5e4:name5:hello12:piece lengthi16e6:pieces20:%se", pieces[:])
+	metainfo := base64.StdEncoding.EncodeToString([]byte("d4:info" + info + "e"))
+	result = rpc(t, server, "torrent-add", map[string]interface{}{"metainfo": metainfo, "download-dir": "/downloads/tv"})
+	added := result["arguments"].(map[string]interface{})["torrent-added"].(map[string]interface{})
+	hash := sha1.Sum([]byte(info))
+	if added["hashString"] != fmt.Sprintf("%x", hash) || added["name"] != "hello" || added["id"] != float64(2) {
+		t.Errorf("Unexpected torrent-added %v", added)
+	}
+
+	result = rpc(t, server, "torrent-add", map[string]interface{}{"filename": "/etc/passwd"})
+	if result["result"] == "success" {
+		t.Error("Expected local paths to be rejected")
+	}
+
+	expected := []string{"add-file hello.torrent dir=/downloads/tv"}
+	if fmt.Sprint(api.calls) != fmt.Sprint(expected) {
+		t.Errorf("Expected calls %v, got %v", expected, api.calls)
+	}
+}
+
+func TestSession(t *testing.T) {
+	api := newAPI()
+	api.prefs = map[string]interface{}{
+		"save_path":           "/downloads",
+		"dl_limit":            float64(10240),
+		"up_limit":            float64(0),
+		"listen_port":         float64(6881),
+		"encryption":          float64(1),
+		"add_stopped_enabled": true,
+	}
+	server := httptest.NewServer(New(api, Config{}))
+	defer server.Close()
+
+	session := rpc(t, server, "session-get", nil)["arguments"].(map[string]interface{})
+	if session["download-dir"] != "/downloads" || session["speed-limit-down"] != float64(10) || session["speed-limit-down-enabled"] != true ||
+		session["speed-limit-up-enabled"] != false || session["peer-port"] != float64(6881) || session["encryption"] != "required" ||
+		session["start-added-torrents"] != false || session["rpc-version"] != float64(RPCVersion) {
+		t.Errorf("Unexpected session %v", session)
+	}
+	if !strings.Contains(session["version"].(string), "qBittorrent v5.0.0") {
+		t.Errorf("Unexpected version %v", session["version"])
+	}
+
+	rpc(t, server, "session-set", map[string]interface{}{"speed-limit-down-enabled": false, "speed-limit-up": 50, "encryption": "tolerated"})
+	expected := `prefs {"dl_limit":0,"encryption":2,"up_limit":51200}`
+	if len(api.calls) != 1 || api.calls[0] != expected {
+		t.Errorf("Expected %s, got %v", expected, api.calls)
+	}
+
+	stats := rpc(t, server, "session-stats", nil)["arguments"].(map[string]interface{})
+	if stats["torrentCount"] != float64(2) || stats["activeTorrentCount"] != float64(1) || stats["downloadSpeed"] != float64(2048) {
+		t.Errorf("Unexpected stats %v", stats)
+	}
+	current := stats["current-stats"].(map[string]interface{})
+	cumulative := stats["cumulative-stats"].(map[string]interface{})
+	if current["downloadedBytes"] != float64(1<<20) || cumulative["downloadedBytes"] != float64(1<<30) || cumulative["uploadedBytes"] != float64(2<<30) {
+		t.Errorf("Expected session and all-time totals, got %v and %v", current, cumulative)
+	}
+}